@@ -2,13 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"math"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/chriscorrea/sift/internal/app"
 	"github.com/chriscorrea/sift/internal/counter"
+	"github.com/chriscorrea/sift/internal/crawl"
+	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/feed"
+	"github.com/chriscorrea/sift/internal/fetch"
+	"github.com/chriscorrea/sift/internal/rules"
 
 	"github.com/spf13/cobra"
 )
@@ -17,6 +29,7 @@ import (
 func buildConfig(cmd *cobra.Command, args []string) (app.Config, error) {
 	// get flag values
 	selector, _ := cmd.Flags().GetString("selector")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
 	tokenLimit, _ := cmd.Flags().GetInt("token-limit")
 	wordLimit, _ := cmd.Flags().GetInt("word-limit")
 	charLimit, _ := cmd.Flags().GetInt("character-limit")
@@ -30,8 +43,123 @@ func buildConfig(cmd *cobra.Command, args []string) (app.Config, error) {
 	quiet, _ := cmd.Flags().GetBool("quiet")
 	debug, _ := cmd.Flags().GetBool("debug")
 	includeAll, _ := cmd.Flags().GetBool("include-all")
+	footnotes, _ := cmd.Flags().GetBool("footnotes")
+	linksFlag, _ := cmd.Flags().GetString("links")
+	frontMatter, _ := cmd.Flags().GetBool("front-matter")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	stream, _ := cmd.Flags().GetBool("stream")
+	inputFormatFlag, _ := cmd.Flags().GetString("input-format")
+
+	encodingFlag, _ := cmd.Flags().GetString("encoding")
+
+	inputFormat, err := extract.ParseFormat(inputFormatFlag)
+	if err != nil {
+		return app.Config{}, err
+	}
+	if encodingFlag != "" {
+		if _, err := fetch.LookupEncoding(encodingFlag); err != nil {
+			return app.Config{}, err
+		}
+	}
+	if err := extract.CheckSelectors(exclude); err != nil {
+		return app.Config{}, fmt.Errorf("invalid --exclude: %w", err)
+	}
+	links, err := extract.ParseLinkMode(linksFlag)
+	if err != nil {
+		return app.Config{}, fmt.Errorf("invalid --links: %w", err)
+	}
+
+	rulesFlag, _ := cmd.Flags().GetString("rules")
+	siteRules, err := loadRules(rulesFlag)
+	if err != nil {
+		return app.Config{}, err
+	}
 
-	//TODO: configurable http timeout, ...
+	// size limit flags
+	maxBytesFlag, _ := cmd.Flags().GetString("max-bytes")
+	maxTotalBytesFlag, _ := cmd.Flags().GetString("max-total-bytes")
+	maxBytes, err := parseByteSize(maxBytesFlag)
+	if err != nil {
+		return app.Config{}, fmt.Errorf("invalid --max-bytes: %w", err)
+	}
+	maxTotalBytes, err := parseByteSize(maxTotalBytesFlag)
+	if err != nil {
+		return app.Config{}, fmt.Errorf("invalid --max-total-bytes: %w", err)
+	}
+	// streaming is meant for inputs larger than the default limit, so it only applies when set
+	if stream && !cmd.Flags().Changed("max-bytes") {
+		maxBytes = math.MaxInt64
+	}
+
+	// directory and glob expansion flags
+	includeGlobs, _ := cmd.Flags().GetStringArray("include-glob")
+	excludeGlobs, _ := cmd.Flags().GetStringArray("exclude-glob")
+	maxDepth, _ := cmd.Flags().GetInt("max-depth")
+	maxFiles, _ := cmd.Flags().GetInt("max-files")
+	followSymlinks, _ := cmd.Flags().GetBool("follow-symlinks")
+	noGitignore, _ := cmd.Flags().GetBool("no-gitignore")
+
+	// feed flags
+	feedLimit, _ := cmd.Flags().GetInt("feed-limit")
+	feedSinceFlag, _ := cmd.Flags().GetString("feed-since")
+	feedUntilFlag, _ := cmd.Flags().GetString("feed-until")
+
+	now := time.Now()
+	feedSince, err := parseFeedDate(feedSinceFlag, now)
+	if err != nil {
+		return app.Config{}, fmt.Errorf("invalid --feed-since: %w", err)
+	}
+	feedUntil, err := parseFeedDate(feedUntilFlag, now)
+	if err != nil {
+		return app.Config{}, fmt.Errorf("invalid --feed-until: %w", err)
+	}
+
+	// crawl flags
+	crawlFlag, _ := cmd.Flags().GetBool("crawl")
+	crawlDepth, _ := cmd.Flags().GetInt("crawl-depth")
+	crawlMaxPages, _ := cmd.Flags().GetInt("crawl-max-pages")
+	crawlDelay, _ := cmd.Flags().GetDuration("crawl-delay")
+
+	// HTTP fetching flags
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+	userAgent, _ := cmd.Flags().GetString("user-agent")
+	cookieFile, _ := cmd.Flags().GetString("cookie-file")
+	maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+	retryDeadline, _ := cmd.Flags().GetDuration("retry-deadline")
+	cacheDir, _ := cmd.Flags().GetString("cache-dir")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	cacheMaxAge, _ := cmd.Flags().GetDuration("cache-max-age")
+	ignoreRobots, _ := cmd.Flags().GetBool("ignore-robots")
+	hostDelay, _ := cmd.Flags().GetDuration("host-delay")
+	hostConcurrency, _ := cmd.Flags().GetInt("host-concurrency")
+
+	// crawling is at least as polite as the crawl delay
+	if crawlFlag {
+		hostDelay = max(hostDelay, crawlDelay)
+	}
+
+	// parse "Name: value" headers
+	headers := http.Header{}
+	for _, header := range headerFlags {
+		name, value, found := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return app.Config{}, fmt.Errorf("invalid header %q: expected \"Name: value\"", header)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+
+	// resolve the cache directory; caching is skipped if no user cache directory exists
+	if noCache {
+		cacheDir = ""
+	} else if cacheDir == "" {
+		dir, err := fetch.DefaultCacheDir()
+		if err != nil {
+			slog.Debug("HTTP cache disabled", "error", err)
+		}
+		cacheDir = dir
+	}
 
 	// determine counting method and max units
 	var countingMethod counter.CountingMethod
@@ -111,6 +239,8 @@ func buildConfig(cmd *cobra.Command, args []string) (app.Config, error) {
 	return app.Config{
 		Sources:         sources,
 		Selector:        selector,
+		Exclude:         exclude,
+		Rules:           siteRules,
 		MaxUnits:        maxUnits,
 		CountingMethod:  countingMethod,
 		SizingStrategy:  sizingStrategy,
@@ -123,9 +253,129 @@ func buildConfig(cmd *cobra.Command, args []string) (app.Config, error) {
 		Quiet:           quiet,
 		Debug:           debug,
 		IncludeAll:      includeAll,
+		LinkFootnotes:   footnotes,
+		Links:           links,
+		FrontMatter:     frontMatter,
+		Concurrency:     concurrency,
+		Stream:          stream,
+		InputFormat:     inputFormat,
+		Expand: fetch.ExpandOptions{
+			Include:        includeGlobs,
+			Exclude:        excludeGlobs,
+			MaxDepth:       maxDepth,
+			MaxFiles:       maxFiles,
+			FollowSymlinks: followSymlinks,
+			NoGitignore:    noGitignore,
+		},
+		Crawl: crawlFlag,
+		CrawlOptions: crawl.Options{
+			MaxDepth: crawlDepth,
+			MaxPages: crawlMaxPages,
+		},
+		Feed: feed.Options{
+			Limit: feedLimit,
+			Since: feedSince,
+			Until: feedUntil,
+		},
+		Fetch: fetch.Options{
+			Timeout:         timeout,
+			UserAgent:       userAgent,
+			Headers:         headers,
+			CookieFile:      cookieFile,
+			MaxAttempts:     maxAttempts,
+			RetryDeadline:   retryDeadline,
+			CacheDir:        cacheDir,
+			CacheMaxAge:     cacheMaxAge,
+			IgnoreRobots:    ignoreRobots,
+			HostDelay:       hostDelay,
+			HostConcurrency: hostConcurrency,
+			Encoding:        encodingFlag,
+			MaxBytes:        maxBytes,
+			MaxTotalBytes:   maxTotalBytes,
+		},
 	}, nil
 }
 
+// loadRules reads the rules file given with --rules or, without one, the default rules file if
+// it exists
+func loadRules(path string) (*rules.Set, error) {
+	if path != "" {
+		return rules.Load(path)
+	}
+
+	path, err := rules.DefaultPath()
+	if err != nil {
+		slog.Debug("Default rules file skipped", "error", err)
+		return nil, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return rules.Load(path)
+}
+
+// parseFeedDate parses a feed date bound: a date (2006-01-02), an RFC 3339 timestamp, or an age
+// relative to now such as "36h" or "7d". An empty value means no bound.
+func parseFeedDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	// ages: Go durations, plus days
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if age, err := time.ParseDuration(value); err == nil && age >= 0 {
+		return now.Add(-age), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02), timestamp (RFC 3339), or age (e.g. 36h, 7d)", value)
+}
+
+// byteUnits are the suffixes accepted by parseByteSize, longest first so that "MB" is not read as "B"
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseByteSize parses a size such as "512", "500KB", or "1.5GB" (units are powers of 1024).
+// An empty value means no limit and returns 0.
+func parseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, multiplier := value, int64(1)
+	for _, unit := range byteUnits {
+		if trimmed, found := strings.CutSuffix(strings.ToUpper(value), unit.suffix); found {
+			number, multiplier = strings.TrimSpace(trimmed), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a positive size (e.g. 500KB, 100MB, 1GB)", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
 // setupLogger configures the default slog logger based on debug mode
 func setupLogger(debug bool) {
 	var level slog.Level
@@ -144,11 +394,14 @@ func setupLogger(debug bool) {
 var rootCmd = &cobra.Command{
 	Use:   "sift [sources...]",
 	Short: "A CLI tool for text content extraction",
-	Long: `Sift is a command-line tool that extracts clean, structured text from messy sources. Sources may include URLs, local files, or standard input.
+	Long: `Sift is a command-line tool that extracts clean, structured text from messy sources. Sources may include URLs, local files, directories, glob patterns, RSS and Atom feeds, or standard input.
 
 Examples:
   sift https://example.com
   sift file.txt document.html
+  sift ./docs 'notes/**/*.md'
+  sift https://example.com/feed.xml --feed-since 7d
+  sift --crawl https://example.com/docs/ --search "rate limit"
   cat content.txt | sift`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// build config from flags and arguments
@@ -179,6 +432,12 @@ Examples:
 
 func init() {
 	rootCmd.Flags().StringP("selector", "s", "", "CSS selector or extraction pattern")
+	rootCmd.Flags().StringArray("exclude", nil, "Remove HTML elements matching this CSS selector before extraction, e.g. \".ad, nav\" (repeatable)")
+	rootCmd.Flags().String("rules", "", "YAML or JSON file of per-site extraction rules (default: sift/rules.yaml under the user config directory, if present)")
+	rootCmd.Flags().String("input-format", "auto", "Input format: auto, html, markdown, text, pdf, docx, odt, epub, or feed (auto detects from content type, extension, and content)")
+	rootCmd.Flags().String("encoding", "", "Character encoding of text sources, e.g. shift_jis or windows-1252 (default detects it per source)")
+	rootCmd.Flags().String("max-bytes", "100MB", "Maximum size of each source, after decompression, e.g. 500KB or 1GB")
+	rootCmd.Flags().String("max-total-bytes", "", "Maximum size of all sources together (default: no limit)")
 
 	// limit flags
 	rootCmd.Flags().IntP("token-limit", "t", 0, "Limit output to number of tokens (default: 1000)")
@@ -195,10 +454,14 @@ func init() {
 	// output format flags (see also 'configure mutually exclusive flag groups' below)
 	rootCmd.Flags().Bool("md", false, "Output in Markdown format (default)")
 	rootCmd.Flags().Bool("text", false, "Output in plain text format")
-	rootCmd.Flags().Bool("json", false, "Output in JSON format")
+	rootCmd.Flags().Bool("json", false, "Output in JSON format, with source, score, and unit count per chunk")
+	rootCmd.Flags().Bool("footnotes", false, "In plain text output, list link URLs as numbered footnotes")
+	rootCmd.Flags().String("links", "inline", "Link handling: inline, reference (numbered URL list at the end), text (anchor text only), or strip")
+	rootCmd.Flags().Bool("front-matter", false, "In Markdown output, precede each article with YAML front matter holding its title, byline, date, site, language, and excerpt")
 
 	// output format flags are mutually exclusive
 	rootCmd.MarkFlagsMutuallyExclusive("md", "text", "json")
+	rootCmd.MarkFlagsMutuallyExclusive("front-matter", "text", "json")
 
 	// sizing strategy flags (see also 'configure mutually exclusive flag groups' below)
 	rootCmd.Flags().Bool("beginning", false, "Apply size constraints from the beginning of the document (default)")
@@ -208,7 +471,42 @@ func init() {
 	// sizing strategy flags are mutually exclusive
 	rootCmd.MarkFlagsMutuallyExclusive("beginning", "middle", "end")
 
+	// HTTP fetching flags (proxies are taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY)
+	rootCmd.Flags().Duration("timeout", fetch.DefaultHTTPRequestTimeout, "Timeout for each HTTP request")
+	rootCmd.Flags().StringArrayP("header", "H", nil, "Add an HTTP request header, e.g. -H \"Authorization: Bearer token\" (repeatable)")
+	rootCmd.Flags().String("user-agent", fetch.DefaultUserAgent, "User-Agent header for HTTP requests")
+	rootCmd.Flags().String("cookie-file", "", "Load cookies from a Netscape-format cookie file")
+	rootCmd.Flags().Int("max-attempts", fetch.DefaultMaxAttempts, "Maximum attempts per URL when retrying transient failures (1 disables retries)")
+	rootCmd.Flags().Duration("retry-deadline", 0, "Overall time limit for fetching a URL, including retries (default: no limit)")
+	rootCmd.Flags().String("cache-dir", "", "Directory for cached HTTP responses (default: sift/http under the user cache directory)")
+	rootCmd.Flags().Bool("no-cache", false, "Disable the HTTP response cache")
+	rootCmd.Flags().Duration("cache-max-age", fetch.DefaultCacheMaxAge, "Serve cached HTTP responses without revalidation for this long (default: 0, always revalidate)")
+	rootCmd.Flags().Bool("ignore-robots", false, "Fetch URLs even when the site's robots.txt disallows them")
+	rootCmd.Flags().Duration("host-delay", 0, "Minimum delay between requests to the same host (a longer robots.txt Crawl-delay takes precedence)")
+	rootCmd.Flags().Int("host-concurrency", fetch.DefaultHostConcurrency, "Maximum simultaneous requests to the same host")
+
+	// directory and glob source flags
+	rootCmd.Flags().StringArray("include-glob", nil, "Only read files matching this glob when expanding directories, e.g. \"*.md\" (repeatable)")
+	rootCmd.Flags().StringArray("exclude-glob", nil, "Skip files and directories matching this glob when expanding directories (repeatable)")
+	rootCmd.Flags().Int("max-depth", 0, "Directory levels to descend, where 1 reads only top-level files (default: unlimited)")
+	rootCmd.Flags().Int("max-files", fetch.DefaultMaxFiles, "Maximum number of files expanded from directories and globs")
+	rootCmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links when expanding directories")
+	rootCmd.Flags().Bool("no-gitignore", false, "Include files ignored by .gitignore when expanding directories")
+
+	// crawl flags
+	rootCmd.Flags().Bool("crawl", false, "Crawl same-site links (or the pages of a sitemap) from URL sources")
+	rootCmd.Flags().Int("crawl-depth", crawl.DefaultMaxDepth, "Link hops followed from each crawled URL (0 reads only the URL itself)")
+	rootCmd.Flags().Int("crawl-max-pages", crawl.DefaultMaxPages, "Maximum number of pages fetched per crawled URL")
+	rootCmd.Flags().Duration("crawl-delay", crawl.DefaultDelay, "Minimum delay between requests to the same host while crawling (raises --host-delay)")
+
+	// feed flags
+	rootCmd.Flags().Int("feed-limit", feed.DefaultLimit, "Maximum number of entries read from each RSS or Atom feed (0 for all)")
+	rootCmd.Flags().String("feed-since", "", "Only read feed entries published on or after this date (2006-01-02), timestamp, or age (e.g. 7d)")
+	rootCmd.Flags().String("feed-until", "", "Only read feed entries published before this date (2006-01-02), timestamp, or age (e.g. 7d)")
+
 	// other flags
+	rootCmd.Flags().Int("concurrency", app.DefaultConcurrency, "Maximum number of sources fetched and extracted in parallel")
+	rootCmd.Flags().Bool("stream", false, "Read sources one at a time, chunking plain text and Markdown as it is read, for inputs too large for memory")
 	rootCmd.Flags().BoolP("quiet", "q", false, "Suppress output messages")
 	rootCmd.Flags().BoolP("debug", "D", false, "Enable debug logging")
 	_ = rootCmd.Flags().MarkHidden("debug")
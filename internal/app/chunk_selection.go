@@ -103,7 +103,7 @@ func NewChunkSelector(countingMethod counter.CountingMethod, maxUnits int, strat
 }
 
 // PrepareChunks breaks text into manageable chunks w/ unit-aware sizing
-// TODO: Implement streaming chunking for large documents
+// The whole text is held in memory; streaming mode chunks text incrementally (see runStream)
 func (cs *ChunkSelector) PrepareChunks(text string) []string {
 	// always chunk content for better searchability
 	// even when there's no size limit, chunking enables effective search
@@ -113,9 +113,36 @@ func (cs *ChunkSelector) PrepareChunks(text string) []string {
 	return chunk.SplitText(text, chunkSize)
 }
 
+// PrepareDocumentChunks chunks each document separately so that no chunk spans two documents.
+// The chunk size is derived from the combined length, matching PrepareChunks on the joined text.
+// Returns the chunks along with the index of the document each chunk came from.
+func (cs *ChunkSelector) PrepareDocumentChunks(documents []string) ([]string, []int) {
+	totalLength := 0
+	for _, doc := range documents {
+		totalLength += len(doc)
+	}
+	chunkSize := cs.calculateChunkSizeForLength(totalLength)
+	slog.Debug("Preparing document chunks", "countingMethod", cs.counter.Name(), "chunkSize", chunkSize, "documents", len(documents))
+
+	var chunks []string
+	var origins []int
+	for docIndex, doc := range documents {
+		for _, c := range chunk.SplitText(doc, chunkSize) {
+			chunks = append(chunks, c)
+			origins = append(origins, docIndex)
+		}
+	}
+
+	return chunks, origins
+}
+
 // calculateChunkSize determines appropriate chunk size based on counting method and text length
 func (cs *ChunkSelector) calculateChunkSize(text string) int {
-	textLen := len(text)
+	return cs.calculateChunkSizeForLength(len(text))
+}
+
+// calculateChunkSizeForLength determines appropriate chunk size for a text of the given length
+func (cs *ChunkSelector) calculateChunkSizeForLength(textLen int) int {
 	var baseSize, threshold int
 
 	switch cs.counter.Name() {
@@ -502,9 +529,20 @@ func (cs *ChunkSelector) Select(orderedChunks []ChunkWithIndex, allChunks []stri
 
 // SelectWithContextConfig provides chunk selection with optional smart context calculation
 func (cs *ChunkSelector) SelectWithContextConfig(orderedChunks []ChunkWithIndex, allChunks []string, contextBefore, contextAfter, contextUnits int, useSmartContext bool) (string, error) {
+	selected, err := cs.SelectChunks(orderedChunks, allChunks, contextBefore, contextAfter, contextUnits, useSmartContext)
+	if err != nil {
+		return "", err
+	}
+
+	return cs.formatSelectedChunks(selected), nil
+}
+
+// SelectChunks performs the same selection as SelectWithContextConfig but returns the selected
+// chunks (in selection order) instead of formatted output, so callers can render them differently
+func (cs *ChunkSelector) SelectChunks(orderedChunks []ChunkWithIndex, allChunks []string, contextBefore, contextAfter, contextUnits int, useSmartContext bool) ([]ChunkWithIndex, error) {
 	// handle empty input
 	if len(orderedChunks) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
 	slog.Debug("Starting unified chunk selection", "orderedChunks", len(orderedChunks), "maxUnits", cs.maxUnits, "contextBefore", contextBefore, "contextAfter", contextAfter, "useSmartContext", useSmartContext)
@@ -519,12 +557,12 @@ func (cs *ChunkSelector) SelectWithContextConfig(orderedChunks []ChunkWithIndex,
 }
 
 // selectWithSmartContext uses the ContextCalculator for intelligent context selection
-func (cs *ChunkSelector) selectWithSmartContext(orderedChunks []ChunkWithIndex, allChunks []string, contextUnits int) (string, error) {
+func (cs *ChunkSelector) selectWithSmartContext(orderedChunks []ChunkWithIndex, allChunks []string, contextUnits int) ([]ChunkWithIndex, error) {
 	// lazily create and cache the context calculator
 	if cs.contextCalculator == nil || cs.contextCalculator.maxContextUnits != contextUnits {
 		calculator, err := NewContextCalculator(cs.counter, contextUnits)
 		if err != nil {
-			return "", fmt.Errorf("failed to create context calculator: %w", err)
+			return nil, fmt.Errorf("failed to create context calculator: %w", err)
 		}
 		cs.contextCalculator = calculator
 	}
@@ -582,11 +620,11 @@ func (cs *ChunkSelector) selectWithSmartContext(orderedChunks []ChunkWithIndex,
 		}
 	}
 
-	return cs.formatSelectedChunks(allSelectedChunks), nil
+	return allSelectedChunks, nil
 }
 
 // selectWithFixedContext uses the original fixed-count context selection logic
-func (cs *ChunkSelector) selectWithFixedContext(orderedChunks []ChunkWithIndex, allChunks []string, contextBefore, contextAfter int) (string, error) {
+func (cs *ChunkSelector) selectWithFixedContext(orderedChunks []ChunkWithIndex, allChunks []string, contextBefore, contextAfter int) ([]ChunkWithIndex, error) {
 	slog.Debug("Using fixed context selection", "contextBefore", contextBefore, "contextAfter", contextAfter)
 
 	// handle no size limit case (maxUnits <= 0)
@@ -646,7 +684,7 @@ func (cs *ChunkSelector) selectWithFixedContext(orderedChunks []ChunkWithIndex,
 				}
 			}
 
-			return cs.formatSelectedChunks(selectedChunks), nil
+			return selectedChunks, nil
 		} else {
 			slog.Debug("No size limit specified, selecting all chunks")
 
@@ -666,7 +704,7 @@ func (cs *ChunkSelector) selectWithFixedContext(orderedChunks []ChunkWithIndex,
 				}
 			}
 
-			return cs.formatSelectedChunks(selectedChunks), nil
+			return selectedChunks, nil
 		}
 	}
 
@@ -710,7 +748,7 @@ func (cs *ChunkSelector) selectWithFixedContext(orderedChunks []ChunkWithIndex,
 	}
 
 	slog.Debug("Fixed context selection complete", "selectedChunks", len(selectedChunks), "finalUnits", currentUnits)
-	return cs.formatSelectedChunks(selectedChunks), nil
+	return selectedChunks, nil
 }
 
 // SetSearchMode enables or disables search mode for gap detection
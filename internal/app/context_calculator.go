@@ -153,6 +153,7 @@ func (cc *ContextCalculator) CalculateSmartContextWithBudget(req ContextRequest,
 type ChunkFieldType struct {
 	Primary bm25md.Field
 	IsList  bool // special flag for list items that need different context strategy
+	IsTable bool // special flag for table chunks, which are usually introduced by preceding text
 }
 
 // detectPrimaryFieldType analyzes chunk content to determine its primary markdown field type
@@ -162,6 +163,11 @@ func (cc *ContextCalculator) detectPrimaryFieldType(chunk string) ChunkFieldType
 		return ChunkFieldType{Primary: bm25md.FieldBody, IsList: false}
 	}
 
+	// check for tables first, since their cells may hold emphasis, code, or list markers
+	if cc.patterns.tableRegex.MatchString(trimmed) {
+		return ChunkFieldType{Primary: bm25md.FieldBody, IsTable: true}
+	}
+
 	// check for headers (most specific first)
 	if cc.patterns.headerRegex.MatchString(trimmed) {
 		// count #'s to determine header level
@@ -241,6 +247,15 @@ func (cc *ContextCalculator) getContextStrategy(fieldType ChunkFieldType) Contex
 		}
 	}
 
+	// tables are usually introduced (and their columns explained) by the text before them
+	if fieldType.IsTable {
+		return ContextStrategy{
+			BeforeRatio: 0.7,
+			AfterRatio:  0.3,
+			Name:        "table-preceding",
+		}
+	}
+
 	// handle primary field types
 	switch fieldType.Primary {
 	case bm25md.FieldH1, bm25md.FieldH2, bm25md.FieldH3, bm25md.FieldH4, bm25md.FieldH5, bm25md.FieldH6:
@@ -329,6 +344,11 @@ func (cc *ContextCalculator) createPartialChunk(chunkText string, remainingUnits
 		return ""
 	}
 
+	// tables are cut between rows, keeping their header
+	if partial, ok := cc.createPartialTable(chunkText, remainingUnits); ok {
+		return partial
+	}
+
 	// for word counting, we can do precise partial chunks
 	if cc.counter.Name() == "words" {
 		words := strings.Fields(chunkText)
@@ -387,6 +407,25 @@ func (cc *ContextCalculator) createPartialChunk(chunkText string, remainingUnits
 	return ""
 }
 
+// createPartialTable keeps the header and as many leading rows of a table chunk as fit in the
+// unit limit. ok is false for chunks that do not start with a table, or when not even one row
+// fits, leaving the chunk to be cut like any other text.
+func (cc *ContextCalculator) createPartialTable(chunkText string, remainingUnits int) (string, bool) {
+	lines := strings.Split(strings.TrimSpace(chunkText), "\n")
+	if len(lines) < 3 || !cc.patterns.tableRegex.MatchString(lines[0]+"\n"+lines[1]) {
+		return "", false
+	}
+
+	kept := 2
+	for kept < len(lines) && cc.counter.Count(strings.Join(lines[:kept+1], "\n")) <= remainingUnits {
+		kept++
+	}
+	if kept == 2 {
+		return "", false
+	}
+	return strings.Join(lines[:kept], "\n"), true
+}
+
 // getChunkIndices extracts the indices from a slice of chunks for debug logging
 func (cc *ContextCalculator) getChunkIndices(chunks []ChunkWithIndex) []int {
 	indices := make([]int, len(chunks))
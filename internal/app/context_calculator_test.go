@@ -176,6 +176,14 @@ func TestContextCalculator_ContextStrategies(t *testing.T) {
 			expectedName:   "list-preceding",
 			description:    "Lists should include more context before",
 		},
+		{
+			name:           "Table strategy - preceding emphasis",
+			fieldType:      ChunkFieldType{Primary: bm25md.FieldBody, IsTable: true},
+			expectedBefore: 0.7,
+			expectedAfter:  0.3,
+			expectedName:   "table-preceding",
+			description:    "Tables should include more of the text introducing them",
+		},
 		{
 			name:           "Code strategy - following emphasis",
 			fieldType:      ChunkFieldType{Primary: bm25md.FieldCode, IsList: false},
@@ -391,6 +399,57 @@ func TestContextCalculator_BudgetParameter(t *testing.T) {
 	}
 }
 
+func TestContextCalculator_Tables(t *testing.T) {
+	textCounter, err := counter.NewCounter(counter.Words)
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+
+	calculator, err := NewContextCalculator(textCounter, 100)
+	if err != nil {
+		t.Fatalf("Failed to create context calculator: %v", err)
+	}
+
+	table := "| Flour | Grams |\n| --- | :-: |\n| **Bread** flour | 500 |\n| `Rye` | 100 |\n| Whole wheat | 50 |"
+
+	detection := []struct {
+		name    string
+		input   string
+		isTable bool
+	}{
+		{"table with emphasis and code cells", table, true},
+		{"table under an introducing line", "Weigh the flours:\n" + table, true},
+		{"table without outer pipes", "Flour | Grams\n--- | ---\nRye | 100", true},
+		{"pipes in text", "Choose rye | spelt for the levain.", false},
+		{"thematic break under text", "Flour\n---", false},
+	}
+	for _, tt := range detection {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculator.detectPrimaryFieldType(tt.input); got.IsTable != tt.isTable {
+				t.Errorf("detectPrimaryFieldType(%q).IsTable = %v, want %v", tt.input, got.IsTable, tt.isTable)
+			}
+		})
+	}
+
+	truncation := []struct {
+		name           string
+		remainingUnits int
+		expected       string
+	}{
+		{"whole rows that fit", 18, "| Flour | Grams |\n| --- | :-: |\n| **Bread** flour | 500 |"},
+		{"whole table", 100, table},
+		// without room for a row, the table is cut like any other text
+		{"no row fits", 3, "| Flour |"},
+	}
+	for _, tt := range truncation {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculator.createPartialChunk(table, tt.remainingUnits); got != tt.expected {
+				t.Errorf("createPartialChunk(%d) = %q, want %q", tt.remainingUnits, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestContextCalculator_PartialChunkTruncation(t *testing.T) {
 	textCounter, err := counter.NewCounter(counter.Words)
 	if err != nil {
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/chriscorrea/sift/internal/crawl"
+	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// processCrawl crawls a site from source and extracts each page as its own document, with the
+// page URL as its source and base URL, using the extraction rule matching the page. Pages in
+// formats that expand into several documents (feeds and EPUB books) and pages without
+// extractable content are skipped.
+func processCrawl(ctx context.Context, client *fetch.Client, source string, cfg Config) ([]Document, error) {
+	var documents []Document
+
+	err := crawl.Crawl(ctx, client, source, cfg.CrawlOptions, func(page crawl.Page) error {
+		format, reader := extract.DetectFormat(bytes.NewReader(page.Content), page.ContentType, page.URL)
+		if format == extract.FormatFeed || format == extract.FormatEPUB {
+			slog.Debug("Skipping crawled page", "url", page.URL, "format", format)
+			return nil
+		}
+
+		doc, err := convertDocument(reader, format, sourceURL(page.URL), sourceConfig(page.URL, cfg))
+		if err != nil {
+			slog.Debug("Skipping crawled page without content", "url", page.URL, "error", err)
+			return nil
+		}
+
+		doc.Source = page.URL
+		documents = append(documents, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no content extracted from crawled pages")
+	}
+
+	return documents, nil
+}
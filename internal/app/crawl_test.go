@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/counter"
+	"github.com/chriscorrea/sift/internal/crawl"
+)
+
+func TestRun_CrawlSearchesWholeSite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs/":
+			fmt.Fprint(w, `<html><body><main><h1>Docs</h1><p>Start with the <a href="install">installation guide</a> or the <a href="limits">limits page</a>.</p></main></body></html>`)
+		case "/docs/install":
+			fmt.Fprint(w, `<html><body><main><h1>Installation</h1><p>Download the binary and put it on your path.</p></main></body></html>`)
+		case "/docs/limits":
+			fmt.Fprint(w, `<html><body><main><h1>Limits</h1><p>The API enforces a rate limit of sixty requests per minute.</p></main></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	result, err := Run(context.Background(), Config{
+		Sources:        []string{server.URL + "/docs/"},
+		CountingMethod: counter.Words,
+		SearchQuery:    "rate limit",
+		OutputFormat:   JSON,
+		Selector:       "main",
+		Quiet:          true,
+		Crawl:          true,
+		CrawlOptions:   crawl.Options{MaxDepth: 1},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var output jsonOutput
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("Run() produced invalid JSON: %v\n%s", err, result)
+	}
+
+	if len(output.Sources) != 3 {
+		t.Errorf("Sources = %v, want all three crawled pages", output.Sources)
+	}
+	if len(output.Chunks) == 0 {
+		t.Fatalf("expected search results, got none")
+	}
+	best := output.Chunks[0]
+	for _, chunk := range output.Chunks[1:] {
+		if chunk.Score > best.Score {
+			best = chunk
+		}
+	}
+	if best.Source != server.URL+"/docs/limits" {
+		t.Errorf("best match came from %q, want the limits page", best.Source)
+	}
+}
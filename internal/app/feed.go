@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/feed"
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// processFeed expands an RSS or Atom feed into one document per entry selected by cfg.Feed.
+// Each document takes the entry's link as its source and the entry's title as its section,
+// and its content starts with the title as a heading. Entries are extracted with the rule
+// matching their link.
+func processFeed(ctx context.Context, client *fetch.Client, source string, reader io.Reader, baseURL *url.URL, cfg Config) ([]Document, error) {
+	parsed, err := feed.Parse(reader, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract content: %w", err)
+	}
+
+	entries := feed.Filter(parsed.Entries, cfg.Feed)
+	slog.Debug("Expanding feed", "source", source, "title", parsed.Title, "entries", len(parsed.Entries), "selected", len(entries))
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no feed entries selected")
+	}
+
+	var documents []Document
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entrySource := entry.Link
+		if entrySource == "" {
+			entrySource = source
+		}
+
+		markdown, err := processFeedEntry(ctx, client, entry, sourceConfig(entry.Link, cfg))
+		if err != nil {
+			if !cfg.Quiet {
+				fmt.Fprintf(os.Stderr, "Warning: failed to process feed entry %q: %v\n", entrySource, err)
+			}
+			continue
+		}
+
+		documents = append(documents, Document{
+			Source:    entrySource,
+			Section:   entry.Title,
+			Published: entry.Published,
+			Content:   extract.WithTitleHeading(markdown, entry.Title),
+		})
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no content extracted from feed entries")
+	}
+
+	return documents, nil
+}
+
+// processFeedEntry converts a feed entry to Markdown, with the entry's link as base URL.
+// Full content embedded in the feed is used when present (it is already the article body, so the
+// selector does not apply); otherwise the linked article is fetched and extracted like any other
+// source, falling back to the entry's summary if that fails.
+func processFeedEntry(ctx context.Context, client *fetch.Client, entry feed.Entry, cfg Config) (string, error) {
+	entryURL := sourceURL(entry.Link)
+
+	embedded := cfg
+	embedded.Selector = ""
+
+	if entry.Content != "" {
+		doc, err := convertDocument(strings.NewReader(entry.Content), extract.FormatHTML, entryURL, embedded)
+		return doc.Content, err
+	}
+
+	if entry.Link != "" {
+		markdown, err := fetchArticle(ctx, client, entry.Link, cfg)
+		if err == nil || entry.Summary == "" || ctx.Err() != nil {
+			return markdown, err
+		}
+		slog.Debug("Falling back to feed entry summary", "link", entry.Link, "error", err)
+	}
+
+	if entry.Summary != "" {
+		doc, err := convertDocument(strings.NewReader(entry.Summary), extract.FormatHTML, entryURL, embedded)
+		return doc.Content, err
+	}
+
+	return "", fmt.Errorf("entry has no content or link")
+}
+
+// fetchArticle fetches the article linked from a feed entry and converts it to Markdown,
+// detecting its format independently of the feed
+func fetchArticle(ctx context.Context, client *fetch.Client, link string, cfg Config) (string, error) {
+	content, err := client.GetContent(ctx, link)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch content: %w", err)
+	}
+	defer content.Close()
+
+	format, reader := extract.DetectFormat(content, content.ContentType, link)
+	doc, err := convertDocument(reader, format, sourceURL(link), cfg)
+	return doc.Content, err
+}
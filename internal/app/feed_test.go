@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chriscorrea/sift/internal/feed"
+	"github.com/chriscorrea/sift/internal/fetch"
+	"github.com/chriscorrea/sift/internal/rules"
+)
+
+func TestProcessSource_FeedEntries(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog/feed.xml":
+			// served as generic XML, so the feed is recognized by sniffing
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel>
+  <title>Baking Notes</title>
+  <item>
+    <title>Carrot Cake</title>
+    <link>posts/carrot-cake</link>
+    <pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate>
+    <content:encoded><![CDATA[<p>Sift the flour twice, then fold in the grated carrots. See the <a href="../pantry">pantry</a> list.</p>]]></content:encoded>
+  </item>
+  <item>
+    <title>Sourdough</title>
+    <link>%s/blog/posts/sourdough</link>
+    <pubDate>Mon, 01 Jan 2024 08:00:00 +0000</pubDate>
+    <description>Only a summary.</description>
+  </item>
+  <item>
+    <title>Last Year</title>
+    <link>posts/old</link>
+    <pubDate>Fri, 01 Dec 2023 08:00:00 +0000</pubDate>
+    <description>Too old to read.</description>
+  </item>
+</channel></rss>`, server.URL)
+		case "/blog/posts/sourdough":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><nav>Home</nav><article><p>Feed the starter every morning before baking the loaf.</p></article></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := fetch.NewClient(fetch.Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	cfg := Config{
+		Selector: "article",
+		Quiet:    true,
+		Feed:     feed.Options{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	documents, err := processSource(context.Background(), client, server.URL+"/blog/feed.xml", cfg)
+	if err != nil {
+		t.Fatalf("processSource() error = %v", err)
+	}
+
+	expected := []Document{
+		{
+			Source:    server.URL + "/blog/posts/carrot-cake",
+			Section:   "Carrot Cake",
+			Published: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+			// embedded content ignores the selector, and its links resolve against the entry URL
+			Content: "# Carrot Cake\n\nSift the flour twice, then fold in the grated carrots. See the [pantry](" + server.URL + "/blog/pantry) list.",
+		},
+		{
+			Source:    server.URL + "/blog/posts/sourdough",
+			Section:   "Sourdough",
+			Published: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+			// summary-only entries are fetched and extracted with the selector
+			Content: "# Sourdough\n\nFeed the starter every morning before baking the loaf.",
+		},
+	}
+
+	if len(documents) != len(expected) {
+		t.Fatalf("processSource() returned %d documents, want %d: %+v", len(documents), len(expected), documents)
+	}
+	for i, doc := range documents {
+		want := expected[i]
+		if doc.Source != want.Source || doc.Section != want.Section || !doc.Published.Equal(want.Published) {
+			t.Errorf("document %d = {%q %q %v}, want {%q %q %v}", i, doc.Source, doc.Section, doc.Published, want.Source, want.Section, want.Published)
+		}
+		if strings.TrimSpace(doc.Content) != want.Content {
+			t.Errorf("document %d content = %q, want %q", i, doc.Content, want.Content)
+		}
+	}
+}
+
+func TestProcessSource_FeedEntryRules(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+  <title>Baking Notes</title>
+  <item>
+    <title>Focaccia</title>
+    <link>%s/recipes/focaccia</link>
+    <description>Only a summary.</description>
+  </item>
+</channel></rss>`, server.URL)
+		case "/recipes/focaccia":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><nav>Home</nav><div class="recipe"><p>Dimple the dough with oiled fingers.</p>`+
+				`<p class="ad">Buy our flour.</p></div></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := fetch.NewClient(fetch.Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// the rule matches the linked article, not the feed
+	set, err := rules.Parse(strings.NewReader("rules:\n  - match: " + server.URL + "/recipes\n    selector: .recipe\n    exclude: [.ad]\n    include_all: true\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	documents, err := processSource(context.Background(), client, server.URL+"/feed.xml", Config{Rules: set, Quiet: true})
+	if err != nil {
+		t.Fatalf("processSource() error = %v", err)
+	}
+	if len(documents) != 1 {
+		t.Fatalf("processSource() returned %d documents, want 1: %+v", len(documents), documents)
+	}
+	if want := "# Focaccia\n\nDimple the dough with oiled fingers."; strings.TrimSpace(documents[0].Content) != want {
+		t.Errorf("document content = %q, want %q", documents[0].Content, want)
+	}
+}
@@ -0,0 +1,183 @@
+// Package app contains output rendering for structured (JSON) results
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonOutput is the top-level document produced for JSON output
+type jsonOutput struct {
+	Sources        []string    `json:"sources"`                // sources that produced content, in argument order
+	ExtractionMode string      `json:"extraction_mode"`        // readability, selector, or include-all
+	CountingMethod string      `json:"counting_method"`        // tokens, words, or characters
+	SearchQuery    string      `json:"search_query,omitempty"` // search query, if any
+	TotalUnits     int         `json:"total_units"`            // sum of units across all chunks
+	Chunks         []jsonChunk `json:"chunks"`
+}
+
+// jsonChunk is a single selected chunk along with its provenance
+type jsonChunk struct {
+	Index     int     `json:"index"`               // chunk index across all sources
+	Score     float64 `json:"score"`               // BM25md score (0 for non-search output)
+	Units     int     `json:"units"`               // size of the chunk in the configured counting method
+	Source    string  `json:"source"`              // source the chunk was extracted from
+	Section   string  `json:"section,omitempty"`   // section within the source, such as an EPUB chapter or feed entry title
+	Published string  `json:"published,omitempty"` // publication time of a feed entry or article (RFC 3339)
+	Title     string  `json:"title,omitempty"`     // article metadata found by readability in HTML sources
+	Byline    string  `json:"byline,omitempty"`
+	SiteName  string  `json:"site_name,omitempty"`
+	Language  string  `json:"language,omitempty"`
+	Excerpt   string  `json:"excerpt,omitempty"`
+	Text      string  `json:"text"`
+}
+
+// extractionMode describes how content was extracted from HTML sources
+func extractionMode(cfg Config) string {
+	switch {
+	case cfg.Selector != "":
+		return "selector"
+	case cfg.IncludeAll:
+		return "include-all"
+	default:
+		return "readability"
+	}
+}
+
+// renderJSON selects chunks from the extracted documents and renders them as a JSON document.
+// Search runs the same BM25md pathway as Markdown output; without a search query the sizing
+// strategy is applied to unfiltered chunks, mirroring the plain size limit.
+func renderJSON(ctx context.Context, documents []Document, cfg Config) (string, error) {
+	selector, selected, origins, err := selectDocumentChunks(ctx, documents, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeJSON(selected, selector, documents, func(index int) Document { return documents[origins[index]] }, cfg)
+}
+
+// selectDocumentChunks chunks each document separately and selects chunks by search or sizing
+// strategy, returning the index of the originating document for every chunk
+func selectDocumentChunks(ctx context.Context, documents []Document, cfg Config) (*ChunkSelector, []ChunkWithIndex, []int, error) {
+	searchQuery := strings.TrimSpace(cfg.SearchQuery)
+
+	// classification filtering only applies to search, as with Markdown output
+	skipFiltering := cfg.IncludeAll || searchQuery == ""
+	selector, chunks, origins, err := prepareDocumentChunksForProcessing(documents, cfg.CountingMethod, cfg.MaxUnits, cfg.SizingStrategy, skipFiltering)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	titles := make([]string, len(chunks))
+	for i, origin := range origins {
+		titles[i] = documents[origin].Title
+	}
+
+	var selected []ChunkWithIndex
+	if len(chunks) > 0 {
+		selected, err = selectChunks(ctx, chunks, titles, selector, searchQuery, cfg.Quiet, cfg.ContextBefore, cfg.ContextAfter, cfg.ContextUnits, cfg.UseSmartContext)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return selector, selected, origins, nil
+}
+
+// encodeJSON renders selected chunks as a JSON document. documents lists every document content
+// was read from, in order, and documentOf returns the document of a chunk by its index.
+func encodeJSON(selected []ChunkWithIndex, selector *ChunkSelector, documents []Document, documentOf func(index int) Document, cfg Config) (string, error) {
+	// present chunks in document order
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Index < selected[j].Index
+	})
+
+	output := jsonOutput{
+		Sources:        make([]string, 0, len(documents)),
+		ExtractionMode: extractionMode(cfg),
+		CountingMethod: cfg.CountingMethod.String(),
+		SearchQuery:    strings.TrimSpace(cfg.SearchQuery),
+		Chunks:         make([]jsonChunk, 0, len(selected)),
+	}
+
+	// multi-part sources contribute several documents but are listed once
+	for i, doc := range documents {
+		if i == 0 || doc.Source != documents[i-1].Source {
+			output.Sources = append(output.Sources, doc.Source)
+		}
+	}
+
+	for i, chunk := range selected {
+		text := chunk.Text
+
+		// remove overlapping prefix from subsequent chunks, as formatSelectedChunks does
+		if i > 0 {
+			text = selector.removeOverlapPrefix(text, selected[i-1].Text)
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// units are those sized; reference definitions of the links the chunk keeps come on top
+		units := selector.counter.Count(text)
+		output.TotalUnits += units
+		text = withReferences(text, cfg)
+		document := documentOf(chunk.Index)
+		var published string
+		if !document.Published.IsZero() {
+			published = document.Published.Format(time.RFC3339)
+		}
+		output.Chunks = append(output.Chunks, jsonChunk{
+			Index:     chunk.Index,
+			Score:     chunk.Score,
+			Units:     units,
+			Source:    document.Source,
+			Section:   document.Section,
+			Published: published,
+			Title:     document.Title,
+			Byline:    document.Byline,
+			SiteName:  document.SiteName,
+			Language:  document.Language,
+			Excerpt:   document.Excerpt,
+			Text:      text,
+		})
+	}
+
+	encoded, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+
+	return string(encoded) + "\n", nil
+}
+
+// frontMatter renders the article metadata of a document as a YAML front matter block, followed
+// by a blank line, or returns an empty string if the document has none
+func frontMatter(doc Document) string {
+	var b strings.Builder
+	field := func(key, value string) {
+		if value != "" {
+			// Go's quoted strings use only escapes that YAML double-quoted scalars also accept
+			fmt.Fprintf(&b, "%s: %s\n", key, strconv.Quote(value))
+		}
+	}
+
+	field("title", doc.Title)
+	field("byline", doc.Byline)
+	if !doc.Published.IsZero() {
+		fmt.Fprintf(&b, "published: %s\n", doc.Published.Format(time.RFC3339))
+	}
+	field("site_name", doc.SiteName)
+	field("language", doc.Language)
+	field("excerpt", doc.Excerpt)
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return "---\n" + "source: " + strconv.Quote(doc.Source) + "\n" + b.String() + "---\n\n"
+}
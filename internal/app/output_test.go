@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chriscorrea/sift/internal/counter"
+)
+
+func TestRenderJSON(t *testing.T) {
+	documents := []Document{
+		{
+			Source:  "cake.md",
+			Content: "# Carrot Cake\n\nSift the flour twice before folding in the grated carrots.\n\nBake until a skewer comes out clean.",
+		},
+		{
+			Source:  "https://example.com/icing",
+			Content: "# Cream Cheese Icing\n\nBeat the cream cheese with sifted icing sugar until smooth.",
+		},
+		{
+			Source:  "-",
+			Content: "Serve the cake at room temperature with a strong cup of coffee.",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		cfg            Config
+		expectSources  []string
+		expectMode     string
+		expectScored   bool
+		expectMaxUnits int
+	}{
+		{
+			name: "search keeps per-chunk provenance and scores",
+			cfg: Config{
+				CountingMethod: counter.Words,
+				SearchQuery:    "icing",
+				ContextBefore:  0,
+				ContextAfter:   0,
+				Quiet:          true,
+				IncludeAll:     true,
+			},
+			expectSources: []string{"cake.md", "https://example.com/icing", "-"},
+			expectMode:    "include-all",
+			expectScored:  true,
+		},
+		{
+			name: "size limit without search",
+			cfg: Config{
+				CountingMethod: counter.Words,
+				MaxUnits:       5,
+				SizingStrategy: Beginning,
+				Selector:       "article",
+				Quiet:          true,
+			},
+			expectSources:  []string{"cake.md", "https://example.com/icing", "-"},
+			expectMode:     "selector",
+			expectMaxUnits: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderJSON(context.Background(), documents, tt.cfg)
+			if err != nil {
+				t.Fatalf("renderJSON() error = %v", err)
+			}
+
+			var output jsonOutput
+			if err := json.Unmarshal([]byte(result), &output); err != nil {
+				t.Fatalf("renderJSON() produced invalid JSON: %v\n%s", err, result)
+			}
+
+			if strings.Join(output.Sources, ",") != strings.Join(tt.expectSources, ",") {
+				t.Errorf("Sources = %v, want %v", output.Sources, tt.expectSources)
+			}
+			if output.ExtractionMode != tt.expectMode {
+				t.Errorf("ExtractionMode = %q, want %q", output.ExtractionMode, tt.expectMode)
+			}
+			if output.CountingMethod != "words" {
+				t.Errorf("CountingMethod = %q, want %q", output.CountingMethod, "words")
+			}
+			if len(output.Chunks) == 0 {
+				t.Fatalf("expected at least one chunk, got none")
+			}
+
+			total := 0
+			for _, chunk := range output.Chunks {
+				total += chunk.Units
+				if chunk.Units != len(strings.Fields(chunk.Text)) {
+					t.Errorf("chunk %d units = %d, want %d", chunk.Index, chunk.Units, len(strings.Fields(chunk.Text)))
+				}
+				if strings.Contains(chunk.Text, "Icing") && chunk.Source != "https://example.com/icing" {
+					t.Errorf("chunk %d attributed to %q, want icing source", chunk.Index, chunk.Source)
+				}
+				if strings.Contains(chunk.Text, "Carrot") && chunk.Source != "cake.md" {
+					t.Errorf("chunk %d attributed to %q, want cake.md", chunk.Index, chunk.Source)
+				}
+			}
+			if total != output.TotalUnits {
+				t.Errorf("TotalUnits = %d, want sum of chunk units %d", output.TotalUnits, total)
+			}
+
+			if tt.expectScored && output.Chunks[0].Score <= 0 {
+				t.Errorf("expected matching chunk to carry a positive score, got %v", output.Chunks[0].Score)
+			}
+			if tt.expectMaxUnits > 0 && output.TotalUnits > tt.expectMaxUnits {
+				t.Errorf("TotalUnits = %d, want <= %d", output.TotalUnits, tt.expectMaxUnits)
+			}
+		})
+	}
+}
+
+func TestPrepareDocumentChunks_NoChunkSpansDocuments(t *testing.T) {
+	selector, err := NewChunkSelector(counter.Words, 0, Beginning)
+	if err != nil {
+		t.Fatalf("NewChunkSelector() error = %v", err)
+	}
+
+	chunks, origins := selector.PrepareDocumentChunks([]string{"first source", "second source"})
+	if len(chunks) != 2 || len(origins) != 2 {
+		t.Fatalf("expected 2 chunks with origins, got %d chunks and %d origins", len(chunks), len(origins))
+	}
+	if origins[0] != 0 || origins[1] != 1 {
+		t.Errorf("origins = %v, want [0 1]", origins)
+	}
+}
+
+func TestRenderJSON_Sections(t *testing.T) {
+	// an EPUB source yields one document per chapter, sharing the source
+	documents := []Document{
+		{Source: "moby-dick.epub", Section: "Loomings", Content: "# Loomings\n\nCall me Ishmael."},
+		{Source: "moby-dick.epub", Section: "The Carpet-Bag", Content: "# The Carpet-Bag\n\nI stuffed a shirt or two into my old carpet-bag."},
+		{Source: "notes.md", Content: "Whales are mammals."},
+	}
+
+	result, err := renderJSON(context.Background(), documents, Config{CountingMethod: counter.Words, Quiet: true})
+	if err != nil {
+		t.Fatalf("renderJSON() error = %v", err)
+	}
+
+	var output jsonOutput
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("renderJSON() produced invalid JSON: %v\n%s", err, result)
+	}
+
+	if got := strings.Join(output.Sources, ","); got != "moby-dick.epub,notes.md" {
+		t.Errorf("Sources = %v, want each source once", output.Sources)
+	}
+
+	for _, chunk := range output.Chunks {
+		var want string
+		switch {
+		case strings.Contains(chunk.Text, "Ishmael"):
+			want = "Loomings"
+		case strings.Contains(chunk.Text, "carpet-bag"):
+			want = "The Carpet-Bag"
+		}
+		if chunk.Section != want {
+			t.Errorf("chunk %d section = %q, want %q", chunk.Index, chunk.Section, want)
+		}
+	}
+}
+
+func TestRenderJSON_ArticleMetadata(t *testing.T) {
+	published := time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC)
+	documents := []Document{
+		{
+			Source:    "https://example.com/proofing",
+			Content:   "Watch the dough rather than the clock.",
+			Published: published,
+			Title:     "Proofing Sourdough",
+			Byline:    "Ada Crumb",
+			SiteName:  "The Bakehouse",
+			Language:  "en",
+			Excerpt:   "How long to proof a sourdough loaf.",
+		},
+		{Source: "notes.md", Content: "Feed the starter daily."},
+	}
+
+	result, err := renderJSON(context.Background(), documents, Config{CountingMethod: counter.Words, Quiet: true})
+	if err != nil {
+		t.Fatalf("renderJSON() error = %v", err)
+	}
+
+	var output jsonOutput
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("renderJSON() produced invalid JSON: %v\n%s", err, result)
+	}
+	if len(output.Chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(output.Chunks))
+	}
+
+	article := output.Chunks[0]
+	if article.Title != "Proofing Sourdough" || article.Byline != "Ada Crumb" || article.SiteName != "The Bakehouse" ||
+		article.Language != "en" || article.Excerpt != "How long to proof a sourdough loaf." || article.Published != "2024-03-05T08:30:00Z" {
+		t.Errorf("article chunk metadata = %+v", article)
+	}
+
+	// documents without metadata omit the fields
+	var raw struct {
+		Chunks []map[string]any `json:"chunks"`
+	}
+	if err := json.Unmarshal([]byte(result), &raw); err != nil {
+		t.Fatalf("renderJSON() produced invalid JSON: %v", err)
+	}
+	for _, field := range []string{"published", "title", "byline", "site_name", "language", "excerpt"} {
+		if _, ok := raw.Chunks[1][field]; ok {
+			t.Errorf("chunk without metadata has a %q field", field)
+		}
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      Document
+		expected string
+	}{
+		{
+			name:     "no metadata",
+			doc:      Document{Source: "notes.md"},
+			expected: "",
+		},
+		{
+			name: "article metadata",
+			doc: Document{
+				Source:    "https://example.com/proofing",
+				Published: time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC),
+				Title:     "Proofing: a \"slow\" guide",
+				Byline:    "Ada Crumb",
+				Language:  "en",
+			},
+			expected: "---\nsource: \"https://example.com/proofing\"\ntitle: \"Proofing: a \\\"slow\\\" guide\"\nbyline: \"Ada Crumb\"\npublished: 2024-03-05T08:30:00Z\nlanguage: \"en\"\n---\n\n",
+		},
+		{
+			name:     "multi-line excerpt",
+			doc:      Document{Source: "-", Excerpt: "First line.\nSecond line."},
+			expected: "---\nsource: \"-\"\nexcerpt: \"First line.\\nSecond line.\"\n---\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frontMatter(tt.doc); got != tt.expected {
+				t.Errorf("frontMatter() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chriscorrea/sift/internal/counter"
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+// readsPrefix reports whether the output only depends on the beginning of the content, so that
+// Markdown and plain text sources can stop being read once the size limit is met. Search and
+// JSON output chunk the whole content, End and Middle sizing select from later in it, and
+// reference links are only numbered once it is read in full.
+func readsPrefix(cfg Config) bool {
+	return cfg.MaxUnits > 0 &&
+		cfg.SizingStrategy == Beginning &&
+		cfg.OutputFormat != JSON &&
+		cfg.Links != extract.LinksReference &&
+		strings.TrimSpace(cfg.SearchQuery) == ""
+}
+
+// readTextPrefix normalizes Markdown or plain text content like extract.NormalizeText, reading
+// only as much of it as the size limit of cfg can use
+func readTextPrefix(content io.Reader, format extract.Format, cfg Config) (string, error) {
+	prefix, err := newTextPrefix(cfg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := prefix.read(extract.NewTextNormalizer(content, format)); err != nil {
+		return "", err
+	}
+	return prefix.String(), nil
+}
+
+// textPrefix collects the beginning of Markdown or plain text content, a line at a time, until
+// applySimpleSizeLimit would truncate it, after which more content cannot change the output.
+// Without a size limit, content is read in full.
+type textPrefix struct {
+	counter counter.Counter
+	cfg     Config
+	text    []byte
+	units   int // units of the lines read, each counted on its own
+	target  int // units of lines to read before checking the text against the limit again
+}
+
+func newTextPrefix(cfg Config) (*textPrefix, error) {
+	textCounter, err := counter.NewCounter(cfg.CountingMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create counter: %w", err)
+	}
+	return &textPrefix{counter: textCounter, cfg: cfg, target: cfg.MaxUnits}, nil
+}
+
+// read appends content, separated from earlier content by a blank line as combineDocuments does,
+// and returns false once the size limit is met
+func (p *textPrefix) read(content io.Reader) (bool, error) {
+	if len(p.text) > 0 {
+		p.text = append(bytes.TrimRight(p.text, "\n"), "\n\n"...)
+	}
+
+	reader := bufio.NewReader(content)
+	for {
+		// an overlong line is read, and counted, in pieces
+		line, err := reader.ReadSlice('\n')
+		p.text = append(p.text, line...)
+		p.units += p.counter.Count(string(line))
+		if p.cfg.MaxUnits > 0 && p.units >= p.target && p.full() {
+			return false, nil
+		}
+
+		switch {
+		case err == io.EOF:
+			return true, nil
+		case err != nil && err != bufio.ErrBufferFull:
+			return true, fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+}
+
+// full reports whether the text read so far, rendered for output, is truncated by the size
+// limit. Units counted line by line only approximate those of the rendered text, so otherwise
+// the units still missing are read before checking again.
+func (p *textPrefix) full() bool {
+	rendered := extract.RewriteLinks(p.String(), documentLinks(p.cfg), nil)
+	if p.cfg.OutputFormat == Text {
+		rendered = extract.ToPlainText(rendered, p.cfg.LinkFootnotes)
+	}
+	rendered = strings.TrimRight(rendered, " \t\r\n")
+
+	limited := applySimpleSizeLimit(rendered, p.cfg.MaxUnits, p.cfg.CountingMethod)
+	if len(limited) < len(rendered) {
+		return true
+	}
+
+	p.target = p.units + max(1, p.cfg.MaxUnits-p.counter.Count(limited))
+	return false
+}
+
+// String returns the text read, without leading or trailing blank lines
+func (p *textPrefix) String() string {
+	return strings.Trim(string(p.text), "\n")
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/counter"
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader *strings.Reader
+	read   int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+func TestReadsPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected bool
+	}{
+		{"beginning with a limit", Config{MaxUnits: 500}, true},
+		{"plain text output", Config{MaxUnits: 500, OutputFormat: Text}, true},
+		{"no limit", Config{}, false},
+		{"end sizing", Config{MaxUnits: 500, SizingStrategy: End}, false},
+		{"json output", Config{MaxUnits: 500, OutputFormat: JSON}, false},
+		{"search", Config{MaxUnits: 500, SearchQuery: "starter"}, false},
+		{"blank search", Config{MaxUnits: 500, SearchQuery: "  "}, true},
+		{"reference links", Config{MaxUnits: 500, Links: extract.LinksReference}, false},
+		{"links as text", Config{MaxUnits: 500, Links: extract.LinksText}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readsPrefix(tt.cfg); got != tt.expected {
+				t.Errorf("readsPrefix() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadTextPrefix(t *testing.T) {
+	var b strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&b, "Step %d: fold the [dough](https://example.com/%d) and let it **rest**.\r\n\r\n\r\n", i, i)
+	}
+	content := "\ufeff\n\n" + b.String()
+
+	tests := []struct {
+		name   string
+		format extract.Format
+		cfg    Config
+	}{
+		{"markdown by words", extract.FormatMarkdown, Config{MaxUnits: 50, CountingMethod: counter.Words}},
+		{"markdown by characters", extract.FormatMarkdown, Config{MaxUnits: 300, CountingMethod: counter.Characters}},
+		{"plain text output", extract.FormatMarkdown, Config{MaxUnits: 50, CountingMethod: counter.Words, OutputFormat: Text}},
+		{"plain text output with footnotes", extract.FormatMarkdown, Config{MaxUnits: 50, CountingMethod: counter.Words, OutputFormat: Text, LinkFootnotes: true}},
+		{"text input", extract.FormatText, Config{MaxUnits: 120, CountingMethod: counter.Words}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &countingReader{reader: strings.NewReader(content)}
+			prefix, err := readTextPrefix(reader, tt.format, tt.cfg)
+			if err != nil {
+				t.Fatalf("readTextPrefix() error = %v", err)
+			}
+
+			full, err := extract.NormalizeText(strings.NewReader(content), tt.format)
+			if err != nil {
+				t.Fatalf("NormalizeText() error = %v", err)
+			}
+
+			// the output after rendering and sizing is the same as for the whole text
+			render := func(text string) string {
+				if tt.cfg.OutputFormat == Text {
+					text = extract.ToPlainText(text, tt.cfg.LinkFootnotes)
+				}
+				return applySimpleSizeLimit(text, tt.cfg.MaxUnits, tt.cfg.CountingMethod)
+			}
+			if got, want := render(prefix), render(full); got != want {
+				t.Errorf("sized prefix = %q, want %q", got, want)
+			}
+			if !strings.HasPrefix(full, prefix) {
+				t.Errorf("prefix %q is not the beginning of the normalized text", prefix)
+			}
+			if reader.read >= len(content)/10 {
+				t.Errorf("read %d of %d bytes, want reading to stop near the limit", reader.read, len(content))
+			}
+		})
+	}
+}
+
+func TestRun_StopsReadingAtSizeLimit(t *testing.T) {
+	const total = 64 << 20
+
+	// the server writes until the client stops reading and closes the connection
+	written := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/log.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+
+		n := 0
+		line := []byte(strings.Repeat("the oven held steady at two hundred degrees ", 20) + "\n")
+		for n < total {
+			m, err := w.Write(line)
+			n += m
+			if err != nil {
+				break
+			}
+		}
+		written <- n
+	}))
+	defer server.Close()
+
+	result, err := Run(context.Background(), Config{
+		Sources:        []string{server.URL + "/log.txt"},
+		MaxUnits:       20,
+		CountingMethod: counter.Words,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if words := len(strings.Fields(result)); words != 20 {
+		t.Errorf("Run() returned %d words, want 20", words)
+	}
+
+	if n := <-written; n >= total {
+		t.Errorf("server wrote the whole %d byte body, want the client to stop reading early", n)
+	}
+}
@@ -15,6 +15,7 @@ type regexPatterns struct {
 	inlineCodeRegex *regexp.Regexp
 	boldRegex       *regexp.Regexp
 	italicRegex     *regexp.Regexp
+	tableRegex      *regexp.Regexp
 }
 
 var (
@@ -33,6 +34,7 @@ func getRegexPatterns() *regexPatterns {
 			inlineCodeRegex: regexp.MustCompile(`\x60[^\x60]+\x60`),
 			boldRegex:       regexp.MustCompile(`\*\*[^*\s][^*]*[^*\s]\*\*|\*\*[^*\s]\*\*`),
 			italicRegex:     regexp.MustCompile(`(?:^|[^*])\*[^*\s][^*]*[^*\s]\*(?:[^*]|$)|(?:^|[^*])\*[^*\s]\*(?:[^*]|$)`),
+			tableRegex:      regexp.MustCompile(`(?m)^.*\|.*\n[ \t]*\|?(?:[ \t]*:?-+:?[ \t]*\|)+(?:[ \t]*:?-+:?[ \t]*)?$`),
 		}
 	})
 	return patterns
@@ -5,16 +5,24 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/url"
 	"os"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/chriscorrea/bm25md"
 	"github.com/chriscorrea/sift/internal/classify"
 	"github.com/chriscorrea/sift/internal/counter"
+	"github.com/chriscorrea/sift/internal/crawl"
 	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/feed"
 	"github.com/chriscorrea/sift/internal/fetch"
+	"github.com/chriscorrea/sift/internal/rules"
 	"github.com/chriscorrea/sift/internal/spinner"
 )
 
@@ -55,6 +63,8 @@ type ChunkScore struct {
 type Config struct {
 	Sources         []string               // URLs, file paths, or "-" for stdin
 	Selector        string                 // CSS selector for content extraction
+	Exclude         []string               // CSS selectors of HTML elements removed before extraction
+	Rules           *rules.Set             // per-site extraction settings, nil for none
 	MaxUnits        int                    // max output units (tokens/words/characters)
 	CountingMethod  counter.CountingMethod // method for counting text units
 	SizingStrategy  SizingStrategy
@@ -66,14 +76,47 @@ type Config struct {
 	UseSmartContext bool         // whether to use smart context calculation instead of fixed chunk counts
 	Quiet           bool         // suppress info messages
 	Debug           bool
-	IncludeAll      bool // include all content without readability or classification filtering
+	IncludeAll      bool                // include all content without readability or classification filtering
+	LinkFootnotes   bool                // in plain text output, keep link URLs as numbered footnotes
+	Links           extract.LinkMode    // how links are written: inline, as numbered references, as anchor text, or stripped
+	FrontMatter     bool                // in Markdown output, precede each document with YAML front matter holding its article metadata
+	Fetch           fetch.Options       // HTTP fetching options (timeout, headers, user agent, cookies)
+	Concurrency     int                 // max sources fetched and extracted in parallel (values below 1 mean 1)
+	Expand          fetch.ExpandOptions // directory and glob source expansion
+	InputFormat     extract.Format      // input format override (FormatAuto detects per source)
+	Feed            feed.Options        // which entries of RSS and Atom feed sources are read
+	Crawl           bool                // crawl same-site links (or sitemaps) from URL sources
+	CrawlOptions    crawl.Options       // crawl depth, page budget, and per-host delay
+	Stream          bool                // read sources one at a time, chunking text incrementally with bounded memory
+
+	references *extract.References // numbers reference links across documents; set by Run in reference mode
+}
+
+// DefaultConcurrency is the default number of sources fetched and extracted in parallel
+const DefaultConcurrency = 4
+
+// Document holds the extracted Markdown content of a single source, or of one section of a
+// multi-part source such as an EPUB chapter or feed entry
+type Document struct {
+	Source    string    // source the content was extracted from
+	Section   string    // section within the source (e.g. chapter or entry title), empty for single-part sources
+	Published time.Time // publication time of a feed entry or article, zero if unknown
+	Content   string    // extracted Markdown content
+
+	// article metadata found by readability in HTML sources, empty for other formats
+	Title    string
+	Byline   string
+	SiteName string
+	Language string
+	Excerpt  string
 }
 
 // Run executes the main sift application logic with the given configuration.
 //
 // Processing Pipeline:
-// 1. Extract and combine content from all sources (extractAndCombineContent)
+// 1. Expand directory and glob sources, then extract content from all sources (extractDocuments)
 // 2. Apply transformations based on search vs non-search scenarios
+// 3. Render the result in the configured output format
 //
 // ctx allows for cancellation and timeout control of long-running operations.
 func Run(ctx context.Context, cfg Config) (string, error) {
@@ -81,11 +124,61 @@ func Run(ctx context.Context, cfg Config) (string, error) {
 		return "", fmt.Errorf("no sources provided")
 	}
 
-	// step 1: extract and combine content from all sources
-	combinedContent, err := extractAndCombineContent(ctx, cfg.Sources, cfg.Selector, cfg.IncludeAll, cfg.Quiet)
+	client, err := fetch.NewClient(cfg.Fetch)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure fetching: %w", err)
+	}
+	defer client.Close()
+
+	// reference links are numbered across all documents as they are read in order, and the URLs
+	// of those the output keeps are listed once it is sized
+	if cfg.Links == extract.LinksReference {
+		cfg.references = extract.NewReferences()
+	}
+
+	// directories and globs become one source per file
+	sources, err := fetch.ExpandSources(cfg.Sources, cfg.Expand)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand sources: %w", err)
+	}
+	// tar archives spool only the members expanded from them
+	client.SelectArchiveMembers(sources)
+
+	if cfg.Stream {
+		return runStream(ctx, client, sources, cfg)
+	}
+
+	// step 1: extract content from all sources
+	documents, err := extractDocuments(ctx, client, sources, cfg)
 	if err != nil {
 		return "", err
 	}
+	if cfg.references != nil {
+		for i := range documents {
+			documents[i].Content = cfg.references.Rewrite(documents[i].Content)
+		}
+	}
+
+	// front matter is part of the content, so that it is sized along with the document it describes
+	if cfg.FrontMatter && cfg.OutputFormat == Markdown {
+		for i := range documents {
+			documents[i].Content = frontMatter(documents[i]) + documents[i].Content
+		}
+	}
+
+	// plain text is rendered before sizing so that unit counts reflect the text actually emitted
+	if cfg.OutputFormat == Text {
+		for i := range documents {
+			documents[i].Content = extract.ToPlainText(withReferences(documents[i].Content, cfg), cfg.LinkFootnotes)
+		}
+	}
+
+	// structured output keeps per-chunk provenance, so it takes the chunk-based pathway
+	if cfg.OutputFormat == JSON {
+		return renderJSON(ctx, documents, cfg)
+	}
+
+	combinedContent := combineDocuments(documents)
 
 	// step 2: apply transformations based on scenario
 	searchQuery := strings.TrimSpace(cfg.SearchQuery)
@@ -93,69 +186,284 @@ func Run(ctx context.Context, cfg Config) (string, error) {
 	// no search query = simple processing
 	if searchQuery == "" {
 		if cfg.MaxUnits <= 0 {
-			return combinedContent, nil // return full content
+			return listReferences(combinedContent, cfg), nil // return full content
 		}
-		return applySimpleSizeLimit(combinedContent, cfg.MaxUnits, cfg.CountingMethod), nil
+		return listReferences(applySimpleSizeLimit(combinedContent, cfg.MaxUnits, cfg.CountingMethod), cfg), nil
+	}
+
+	// differing article titles boost the chunks of their own documents, so documents are chunked separately
+	if slices.ContainsFunc(documents, func(doc Document) bool { return doc.Title != documents[0].Title }) {
+		selector, selected, _, err := selectDocumentChunks(ctx, documents, cfg)
+		if err != nil {
+			return "", err
+		}
+		return listReferences(selector.formatSelectedChunks(selected), cfg), nil
 	}
 
 	// search query = advanced chunking + BM25md
 	// note: maxUnits may be 0 for search-only (no size limit)
-	return applySearchTransformations(ctx, combinedContent, cfg)
+	output, err := applySearchTransformations(ctx, combinedContent, cfg)
+	if err != nil {
+		return "", err
+	}
+	return listReferences(output, cfg), nil
 }
 
-// extractAndCombineContent processes all sources and combines their content with appropriate separators.
-func extractAndCombineContent(ctx context.Context, sources []string, selector string, includeAll, quiet bool) (string, error) {
-	var combinedContent strings.Builder
+// documentLinks returns the link mode documents are extracted with: reference links are
+// numbered by cfg.references once documents are read, so they are extracted as inline links
+func documentLinks(cfg Config) extract.LinkMode {
+	if cfg.references != nil {
+		return extract.LinksInline
+	}
+	return cfg.Links
+}
 
-	for _, source := range sources {
-		content, err := processSource(ctx, source, selector, includeAll, quiet)
-		if err != nil {
-			if !quiet {
+// withReferences appends the definitions of the reference links used in Markdown, in reference mode
+func withReferences(markdown string, cfg Config) string {
+	if cfg.references == nil {
+		return markdown
+	}
+	return cfg.references.Append(markdown)
+}
+
+// listReferences appends the definitions of the reference links used in output, unless it is
+// plain text, whose documents hold their own
+func listReferences(output string, cfg Config) string {
+	if cfg.OutputFormat == Text {
+		return output
+	}
+	return withReferences(output, cfg)
+}
+
+// extractDocuments processes all sources and returns the content extracted from each one.
+// Up to cfg.Concurrency sources are fetched and extracted in parallel; documents are returned in
+// argument order regardless of completion order. Sources that fail are reported as warnings
+// (also in argument order) and skipped. Cancelling ctx stops all in-flight work.
+func extractDocuments(ctx context.Context, client *fetch.Client, sources []string, cfg Config) ([]Document, error) {
+	type result struct {
+		documents []Document
+		err       error
+	}
+
+	concurrency := max(1, min(cfg.Concurrency, len(sources)))
+	results := make([]result, len(sources))
+
+	// workers pull source indices until the queue is drained or ctx is cancelled
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				documents, err := processSource(ctx, client, sources[i], cfg)
+				results[i] = result{documents: documents, err: err}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range sources {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var documents []Document
+	for i, source := range sources {
+		if err := results[i].err; err != nil {
+			if !cfg.Quiet {
 				fmt.Fprintf(os.Stderr, "Warning: failed to process source %q: %v\n", source, err)
 			}
 			continue
 		}
 
+		documents = append(documents, results[i].documents...)
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no content extracted from any source")
+	}
+
+	return documents, nil
+}
+
+// combineDocuments joins the content of all documents with paragraph separators
+func combineDocuments(documents []Document) string {
+	var combinedContent strings.Builder
+
+	for _, doc := range documents {
 		if combinedContent.Len() > 0 {
 			combinedContent.WriteString("\n\n")
 		}
-		combinedContent.WriteString(content)
+		combinedContent.WriteString(doc.Content)
 	}
 
-	if combinedContent.Len() == 0 {
-		return "", fmt.Errorf("no content extracted from any source")
+	return combinedContent.String()
+}
+
+// processSource fetches content from a single source and converts it to markdown.
+// HTML is extracted with readability or the selector; PDF, DOCX, and ODT documents are converted to
+// Markdown; Markdown and plain text are passed through with light normalization.
+// EPUB books produce one document per chapter, feeds one document per entry, and crawled URLs
+// one document per page; other sources produce a single document. The format is detected per source unless cfg.InputFormat overrides it.
+// Content is loaded into memory in full; see runStream for reading large text incrementally.
+func processSource(ctx context.Context, client *fetch.Client, source string, cfg Config) ([]Document, error) {
+	// crawled URLs produce one document per page, each extracted with the rule matching the page
+	if cfg.Crawl && sourceURL(source) != nil {
+		return processCrawl(ctx, client, source, cfg)
+	}
+
+	content, reader, format, err := openSource(ctx, client, source, sourceConfig(source, cfg))
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return processContent(ctx, client, source, reader, format, cfg)
+}
+
+// sourceConfig applies the first extraction rule matching a source to cfg. Rules only fill in
+// what the command line leaves unset: the selector and input format apply if none was given,
+// exclusions are added to those given, and include-all can be enabled but not disabled.
+func sourceConfig(source string, cfg Config) Config {
+	rule, ok := cfg.Rules.Match(source)
+	if !ok {
+		return cfg
 	}
+	slog.Debug("Applying extraction rule", "source", source, "match", rule.Match)
 
-	return combinedContent.String(), nil
+	if cfg.Selector == "" {
+		cfg.Selector = rule.Selector
+	}
+	if cfg.InputFormat == extract.FormatAuto {
+		cfg.InputFormat = rule.InputFormat
+	}
+	cfg.Exclude = append(slices.Clip(cfg.Exclude), rule.Exclude...)
+	cfg.IncludeAll = cfg.IncludeAll || rule.IncludeAll
+	return cfg
 }
 
-// processSource fetches content from a single source and converts it to markdown
-// TODO: implement streaming; current approach loads full content into memory
-func processSource(ctx context.Context, source, selector string, includeAll, quiet bool) (string, error) {
-	// fetch content
-	reader, err := fetch.GetContent(ctx, source)
+// openSource fetches a source and determines its format, unless cfg.InputFormat overrides it.
+// The returned reader yields the content and must be used instead of content, which the caller closes.
+func openSource(ctx context.Context, client *fetch.Client, source string, cfg Config) (*fetch.Content, io.Reader, extract.Format, error) {
+	content, err := client.GetContent(ctx, source)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch content: %w", err)
+		return nil, nil, extract.FormatAuto, fmt.Errorf("failed to fetch content: %w", err)
 	}
-	defer reader.Close()
 
-	// parse source URL for context (if it's a URL)
-	var baseURL *url.URL
-	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
-		baseURL, _ = url.Parse(source) // ignore parse errors, will use nil
+	var reader io.Reader = content
+	format := cfg.InputFormat
+	if format == extract.FormatAuto {
+		format, reader = extract.DetectFormat(content, content.ContentType, source)
 	}
+	slog.Debug("Processing source", "source", source, "format", format, "contentType", content.ContentType)
 
-	// extract and convert to Markdown
-	markdown, err := extract.ToMarkdown(reader, selector, includeAll, baseURL)
+	return content, reader, format, nil
+}
+
+// processContent converts the fetched content of a source in the given format to documents.
+// The extraction rule matching the source is applied to cfg, except for feeds, whose entries are
+// extracted with the rules matching their own links.
+func processContent(ctx context.Context, client *fetch.Client, source string, reader io.Reader, format extract.Format, cfg Config) ([]Document, error) {
+	baseURL := sourceURL(source)
+	if format == extract.FormatFeed {
+		return processFeed(ctx, client, source, reader, baseURL, cfg)
+	}
+	cfg = sourceConfig(source, cfg)
+
+	if format == extract.FormatEPUB {
+		// books are split into chapters so that chunks keep their chapter as provenance
+		chapters, err := extract.EPUBToChapters(reader, cfg.Selector, maxMemberBytes(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract content: %w", err)
+		}
+		documents := make([]Document, 0, len(chapters))
+		for _, chapter := range chapters {
+			documents = append(documents, Document{Source: source, Section: chapter.Title, Content: extract.RewriteLinks(chapter.Markdown, documentLinks(cfg), nil)})
+		}
+		return documents, nil
+	}
+
+	doc, err := convertDocument(reader, format, baseURL, cfg)
+	if err != nil {
+		return nil, err
+	}
+	doc.Source = source
+
+	return []Document{doc}, nil
+}
+
+// convertDocument converts the content of a single-document format to a Document holding its
+// Markdown and, for HTML extracted with readability, its article metadata. Source is left unset.
+func convertDocument(reader io.Reader, format extract.Format, baseURL *url.URL, cfg Config) (Document, error) {
+	var markdown string
+	var article extract.Article
+	var err error
+	switch format {
+	case extract.FormatPDF:
+		markdown, err = extract.PDFToMarkdown(reader)
+	case extract.FormatDOCX:
+		markdown, err = extract.DOCXToMarkdown(reader, maxMemberBytes(cfg))
+	case extract.FormatODT:
+		markdown, err = extract.ODTToMarkdown(reader, maxMemberBytes(cfg))
+	case extract.FormatMarkdown, extract.FormatText:
+		// text past the size limit would be truncated anyway, so it is not read (or downloaded)
+		if readsPrefix(cfg) {
+			markdown, err = readTextPrefix(reader, format, cfg)
+		} else {
+			markdown, err = extract.NormalizeText(reader, format)
+		}
+	case extract.FormatEPUB, extract.FormatFeed:
+		return Document{}, fmt.Errorf("%s content is not supported here", format)
+	default:
+		article, err = extract.ToArticle(reader, cfg.Selector, cfg.Exclude, cfg.IncludeAll, baseURL)
+		markdown = article.Markdown
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to extract content: %w", err)
+		return Document{}, fmt.Errorf("failed to extract content: %w", err)
 	}
+	markdown = extract.RewriteLinks(markdown, documentLinks(cfg), baseURL)
 
 	if strings.TrimSpace(markdown) == "" {
-		return "", fmt.Errorf("no content extracted")
+		return Document{}, fmt.Errorf("no content extracted")
 	}
 
-	return markdown, nil
+	return Document{
+		Content:   markdown,
+		Published: article.Published,
+		Title:     article.Title,
+		Byline:    article.Byline,
+		SiteName:  article.SiteName,
+		Language:  article.Language,
+		Excerpt:   article.Excerpt,
+	}, nil
+}
+
+// maxMemberBytes returns the limit on the decompressed size of each file within DOCX, ODT, and
+// EPUB documents, which is the limit on the bytes read from a single source
+func maxMemberBytes(cfg Config) int64 {
+	if cfg.Fetch.MaxBytes > 0 {
+		return cfg.Fetch.MaxBytes
+	}
+	return fetch.DefaultMaxBytes
+}
+
+// sourceURL parses a source as a URL for context, returning nil for files and stdin
+func sourceURL(source string) *url.URL {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return nil
+	}
+	baseURL, _ := url.Parse(source) // ignore parse errors, will use nil
+	return baseURL
 }
 
 // applyContentTransformations coordinates the application of size constraints and transformations with smart context support.
@@ -197,13 +505,9 @@ func prepareChunksForProcessing(text string, countingMethod counter.CountingMeth
 
 	// apply classification filtering *unless includeAll is true*
 	if !includeAll && len(chunks) > 0 {
-		classifier := classify.NewClassifier()
 		filtered := make([]string, 0, len(chunks))
-
-		for i, chunk := range chunks {
-			if !classifier.IsExtraneous(chunk, i, len(chunks)) {
-				filtered = append(filtered, chunk)
-			}
+		for _, i := range keptChunkIndices(chunks) {
+			filtered = append(filtered, chunks[i])
 		}
 
 		chunks = filtered
@@ -212,15 +516,71 @@ func prepareChunksForProcessing(text string, countingMethod counter.CountingMeth
 	return selector, chunks, nil
 }
 
+// prepareDocumentChunksForProcessing works like prepareChunksForProcessing but chunks each
+// document separately, returning the index of the originating document for every chunk
+func prepareDocumentChunksForProcessing(documents []Document, countingMethod counter.CountingMethod, maxUnits int, sizingStrategy SizingStrategy, includeAll bool) (*ChunkSelector, []string, []int, error) {
+	selector, err := NewChunkSelector(countingMethod, maxUnits, sizingStrategy)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create chunk selector: %w", err)
+	}
+
+	contents := make([]string, len(documents))
+	for i, doc := range documents {
+		contents[i] = doc.Content
+	}
+	chunks, origins := selector.PrepareDocumentChunks(contents)
+
+	// apply classification filtering *unless includeAll is true*, keeping origins aligned
+	if !includeAll && len(chunks) > 0 {
+		kept := keptChunkIndices(chunks)
+		filteredChunks := make([]string, 0, len(kept))
+		filteredOrigins := make([]int, 0, len(kept))
+		for _, i := range kept {
+			filteredChunks = append(filteredChunks, chunks[i])
+			filteredOrigins = append(filteredOrigins, origins[i])
+		}
+
+		chunks, origins = filteredChunks, filteredOrigins
+	}
+
+	return selector, chunks, origins, nil
+}
+
+// keptChunkIndices returns the indices of chunks the classifier does not consider extraneous
+func keptChunkIndices(chunks []string) []int {
+	classifier := classify.NewClassifier()
+	kept := make([]int, 0, len(chunks))
+
+	for i, chunk := range chunks {
+		if !classifier.IsExtraneous(chunk, i, len(chunks)) {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
 // applyTransformations handles chunk selection with optional smart context support using a unified pathway
 func applyTransformations(ctx context.Context, chunks []string, selector *ChunkSelector, searchQuery string, quiet bool, contextBefore, contextAfter, contextUnits int, useSmartContext bool) (string, error) {
+	selected, err := selectChunks(ctx, chunks, nil, selector, searchQuery, quiet, contextBefore, contextAfter, contextUnits, useSmartContext)
+	if err != nil {
+		return "", err
+	}
+
+	return selector.formatSelectedChunks(selected), nil
+}
+
+// selectChunks orders chunks by relevance (search) or sizing strategy and selects them with context.
+// For search, each selected chunk carries its own BM25md score (context chunks included).
+func selectChunks(ctx context.Context, chunks []string, titles []string, selector *ChunkSelector, searchQuery string, quiet bool, contextBefore, contextAfter, contextUnits int, useSmartContext bool) ([]ChunkWithIndex, error) {
 	var orderedChunks []ChunkWithIndex
 	var finalContextBefore, finalContextAfter int
+	var scores map[int]float64
 
 	// determine chunk ordering and context based on whether search is configured
 	if strings.TrimSpace(searchQuery) != "" {
 		// search path: get scored chunks
-		scoredChunks, err := performLexicalSearch(ctx, chunks, searchQuery, quiet)
+		scoredChunks, err := performLexicalSearch(ctx, chunks, titles, searchQuery, quiet)
 		if err != nil {
 			if !quiet {
 				fmt.Fprintf(os.Stderr, "Warning: search failed: %v\n", err)
@@ -233,6 +593,11 @@ func applyTransformations(ctx context.Context, chunks []string, selector *ChunkS
 			orderedChunks = selector.PrepareForSearch(scoredChunks)
 			finalContextBefore = contextBefore
 			finalContextAfter = contextAfter
+
+			scores = make(map[int]float64, len(scoredChunks))
+			for _, scored := range scoredChunks {
+				scores[scored.Index] = scored.Score
+			}
 		}
 	} else {
 		// strategy path
@@ -242,17 +607,25 @@ func applyTransformations(ctx context.Context, chunks []string, selector *ChunkS
 	}
 
 	// single point of chunk selection (unified pathway)
-	result, err := selector.SelectWithContextConfig(orderedChunks, chunks, finalContextBefore, finalContextAfter, contextUnits, useSmartContext)
+	selected, err := selector.SelectChunks(orderedChunks, chunks, finalContextBefore, finalContextAfter, contextUnits, useSmartContext)
 	if err != nil {
-		return "", fmt.Errorf("failed to select chunks: %w", err)
+		return nil, fmt.Errorf("failed to select chunks: %w", err)
 	}
 
-	return result, nil
+	// context selection does not carry scores forward, so restore them by index
+	for i := range selected {
+		if score, ok := scores[selected[i].Index]; ok {
+			selected[i].Score = score
+		}
+	}
+
+	return selected, nil
 }
 
-// performLexicalSearch sorts chunks by relevance using BM25md field-weighted ranking
+// performLexicalSearch sorts chunks by relevance using BM25md field-weighted ranking.
+// titles holds the article title of each chunk's document, or is nil if there are none.
 // ctx allows for cancellation of search operations.
-func performLexicalSearch(ctx context.Context, chunks []string, searchQuery string, quiet bool) ([]ChunkScore, error) {
+func performLexicalSearch(ctx context.Context, chunks []string, titles []string, searchQuery string, quiet bool) ([]ChunkScore, error) {
 	if len(chunks) == 0 {
 		return []ChunkScore{}, nil
 	}
@@ -268,11 +641,20 @@ func performLexicalSearch(ctx context.Context, chunks []string, searchQuery stri
 	// create BM25md corpus with default field weights and parameters
 	corpus := bm25md.NewCorpus()
 
+	if !titlesDiffer(titles) {
+		titles = nil
+	}
+
 	// parse chunks as markdown documents and add to corpus
 	parser := bm25md.NewMarkdownFieldParser()
 	for i, chunk := range chunks {
+		var title string
+		if titles != nil {
+			title = titles[i]
+		}
+
 		// parse the chunk to extract field-specific content
-		fields := parser.ParseDocument(chunk)
+		fields := searchFields(parser, chunk, title)
 		doc := bm25md.Document{
 			ID:       i,
 			Fields:   fields,
@@ -300,6 +682,23 @@ func performLexicalSearch(ctx context.Context, chunks []string, searchQuery stri
 	return scoredChunks, nil
 }
 
+// searchFields parses a chunk into the fields BM25md scores, adding the article title of its
+// document to the h1 field so that chunks of articles whose title matches the query rank higher
+func searchFields(parser *bm25md.MarkdownFieldParser, chunk, title string) map[bm25md.Field]string {
+	fields := parser.ParseDocument(chunk)
+	if title != "" {
+		fields[bm25md.FieldH1] = strings.TrimSpace(fields[bm25md.FieldH1] + " " + title)
+	}
+	return fields
+}
+
+// titlesDiffer reports whether the article titles of chunks are not all the same. A title shared
+// by every chunk cannot rank one above another, and adding its words to all of them would zero
+// their IDF, and with it the score of every chunk matching them.
+func titlesDiffer(titles []string) bool {
+	return slices.ContainsFunc(titles, func(title string) bool { return title != titles[0] })
+}
+
 // applySimpleSizeLimit truncates content to fit within the specified unit limit
 // by iterating through the content while preserving line breaks and word boundaries.
 func applySimpleSizeLimit(content string, maxUnits int, countingMethod counter.CountingMethod) string {
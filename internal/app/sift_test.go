@@ -1,11 +1,24 @@
 package app
 
 import (
+	"archive/zip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/chriscorrea/sift/internal/counter"
+	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/fetch"
+	"github.com/chriscorrea/sift/internal/rules"
 )
 
 func TestConfig_IncludeAll(t *testing.T) {
@@ -206,3 +219,560 @@ References: Department of Education. 5th Edition. Foundation Publications, 2023.
 		})
 	}
 }
+
+func TestRun_TextOutputStripsMarkdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cake.html")
+	html := `<html><body><article><h1>Carrot Cake</h1><p>Always <strong>sift</strong> the <a href="https://example.com/flour">flour</a>.</p></article></body></html>`
+	if err := os.WriteFile(path, []byte(html), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	result, err := Run(context.Background(), Config{
+		Sources:        []string{path},
+		Selector:       "article",
+		CountingMethod: counter.Words,
+		OutputFormat:   Text,
+		LinkFootnotes:  true,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	expected := "Carrot Cake\n\nAlways sift the flour [1].\n\n[1] https://example.com/flour"
+	if result != expected {
+		t.Errorf("Run() text output =\n%q\nwant\n%q", result, expected)
+	}
+}
+
+func TestExtractDocuments_Concurrency(t *testing.T) {
+	var active, peak atomic.Int32
+	release := make(chan struct{})
+
+	// later sources respond first, so completion order is the reverse of argument order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		fmt.Fprintf(w, "<html><body><article><p>Recipe from %s</p></article></body></html>", r.URL.Path)
+	}))
+	defer server.Close()
+
+	client, err := fetch.NewClient(fetch.Options{MaxAttempts: 1})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	t.Run("preserves argument order and skips failures", func(t *testing.T) {
+		sources := []string{server.URL + "/slow", server.URL + "/missing", server.URL + "/fast"}
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+
+		documents, err := extractDocuments(context.Background(), client, sources, Config{Selector: "article", Quiet: true, Concurrency: 3})
+		if err != nil {
+			t.Fatalf("extractDocuments() error = %v", err)
+		}
+		if len(documents) != 2 {
+			t.Fatalf("expected 2 documents, got %d", len(documents))
+		}
+		if documents[0].Source != sources[0] || !strings.Contains(documents[0].Content, "/slow") {
+			t.Errorf("documents[0] = %+v, want content from %s", documents[0], sources[0])
+		}
+		if documents[1].Source != sources[2] || !strings.Contains(documents[1].Content, "/fast") {
+			t.Errorf("documents[1] = %+v, want content from %s", documents[1], sources[2])
+		}
+	})
+
+	t.Run("limits parallel fetches", func(t *testing.T) {
+		peak.Store(0)
+		var sources []string
+		for i := range 8 {
+			sources = append(sources, fmt.Sprintf("%s/page-%d", server.URL, i))
+		}
+
+		documents, err := extractDocuments(context.Background(), client, sources, Config{Selector: "article", Quiet: true, Concurrency: 2})
+		if err != nil {
+			t.Fatalf("extractDocuments() error = %v", err)
+		}
+		if len(documents) != len(sources) {
+			t.Fatalf("expected %d documents, got %d", len(sources), len(documents))
+		}
+		if got := peak.Load(); got > 2 {
+			t.Errorf("peak concurrent requests = %d, want <= 2", got)
+		}
+	})
+
+	t.Run("cancellation stops in-flight work", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer blocked.Close()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		_, err := extractDocuments(ctx, client, []string{blocked.URL, blocked.URL, blocked.URL}, Config{Quiet: true, Concurrency: 2})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("extractDocuments() error = %v, want context.Canceled", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("extractDocuments() returned after %v, expected prompt cancellation", elapsed)
+		}
+	})
+}
+
+func TestRun_MarkdownSourcesSkipHTMLExtraction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cake.md")
+	if err := os.WriteFile(path, []byte("# Carrot Cake\n\nAlways sift the **flour**.\n"), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		inputFormat extract.Format
+		expected    string
+	}{
+		{"detected from extension", extract.FormatAuto, "# Carrot Cake\n\nAlways sift the **flour**."},
+		{"forced HTML escapes Markdown syntax", extract.FormatHTML, "\\# Carrot Cake\n\nAlways sift the \\*\\*flour\\*\\*."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(context.Background(), Config{
+				Sources:        []string{path},
+				CountingMethod: counter.Words,
+				InputFormat:    tt.inputFormat,
+				IncludeAll:     true,
+				Quiet:          true,
+			})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Run() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRun_ArchiveMembersKeepProvenance(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "bundle.zip")
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	w := zip.NewWriter(file)
+	for name, content := range map[string]string{
+		"docs/cake.md":  "# Carrot Cake\n\nAlways sift the flour before folding it in.\n",
+		"docs/icing.md": "# Icing\n\nBeat the cream cheese frosting until smooth.\n",
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	file.Close()
+
+	result, err := Run(context.Background(), Config{
+		Sources:        []string{archive},
+		CountingMethod: counter.Words,
+		SearchQuery:    "frosting",
+		OutputFormat:   JSON,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var output jsonOutput
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("Run() produced invalid JSON: %v\n%s", err, result)
+	}
+	if len(output.Sources) != 2 {
+		t.Errorf("Sources = %v, want both archive members", output.Sources)
+	}
+	if len(output.Chunks) == 0 {
+		t.Fatalf("expected search results, got none")
+	}
+	for _, chunk := range output.Chunks {
+		if strings.Contains(chunk.Text, "frosting") {
+			if expected := archive + "!docs/icing.md"; chunk.Source != expected {
+				t.Errorf("chunk source = %q, want %q", chunk.Source, expected)
+			}
+			return
+		}
+	}
+	t.Errorf("no chunk contains the search term: %s", result)
+}
+
+func TestRun_ArticleMetadata(t *testing.T) {
+	dir := t.TempDir()
+	article := func(title, body string) string {
+		return `<html lang="en"><head><title>` + title + `</title><meta name="author" content="Ada Crumb"><meta name="description" content="A baking note."></head><body><article><p>` +
+			strings.Repeat(body+" ", 4) + `</p><p>` + strings.Repeat(body+" ", 3) + `</p></article></body></html>`
+	}
+	files := map[string]string{
+		"retarding.html": article("Retarding Dough Overnight", "Chill the shaped loaf in the refrigerator so the flavor develops while you sleep, then bake it straight from the cold."),
+		"scoring.html":   article("Scoring Loaves", "Hold the blade at a shallow angle and cut with one confident stroke so the loaf opens into a clean ear."),
+		"starter.md":     "Feed the starter twice a day with equal weights of flour and water.",
+		"oven.md":        "Preheat the oven with the pot inside for a full hour.",
+	}
+	var sources []string
+	for _, name := range []string{"scoring.html", "retarding.html", "starter.md", "oven.md"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0o644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+		sources = append(sources, path)
+	}
+
+	t.Run("front matter precedes articles", func(t *testing.T) {
+		result, err := Run(context.Background(), Config{
+			Sources:        sources[:1],
+			CountingMethod: counter.Words,
+			FrontMatter:    true,
+			Quiet:          true,
+		})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		expected := "---\nsource: \"" + sources[0] + "\"\ntitle: \"Scoring Loaves\"\nbyline: \"Ada Crumb\"\nlanguage: \"en\"\nexcerpt: \"A baking note.\"\n---\n\nHold the blade"
+		if !strings.HasPrefix(result, expected) {
+			t.Errorf("Run() = %q, want it to start with %q", result, expected)
+		}
+	})
+
+	t.Run("search matches article titles", func(t *testing.T) {
+		// "retarding" appears only in the title of one article
+		result, err := Run(context.Background(), Config{
+			Sources:        sources,
+			CountingMethod: counter.Words,
+			SearchQuery:    "retarding",
+			MaxUnits:       60,
+			Quiet:          true,
+		})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if !strings.HasPrefix(result, "Chill the shaped loaf") {
+			t.Errorf("Run() = %q, want the article titled by the query first", result)
+		}
+	})
+}
+
+func TestRun_SearchSharedArticleTitle(t *testing.T) {
+	// every chunk shares the title, so it must not zero the scores of chunks matching its words
+	page := `<html><head><title>Carrot Cake</title></head><body><article>` +
+		`<h1>Intro</h1><p>` + strings.Repeat("This cake is a family favorite for birthdays and long weekends. ", 6) + `</p>` +
+		`<h2>Preparing</h2><p>` + strings.Repeat("Grate the carrot finely and fold it into the batter with the walnuts. ", 6) + `</p>` +
+		`<h2>Frosting</h2><p>` + strings.Repeat("Beat cream cheese with butter and sugar until smooth and pale. ", 6) + `</p>` +
+		`</article></body></html>`
+	path := filepath.Join(t.TempDir(), "cake.html")
+	if err := os.WriteFile(path, []byte(page), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			result, err := Run(context.Background(), Config{
+				Sources:        []string{path},
+				CountingMethod: counter.Words,
+				SearchQuery:    "carrot",
+				MaxUnits:       70,
+				Stream:         stream,
+				Quiet:          true,
+			})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !strings.Contains(result, "Grate the carrot") {
+				t.Errorf("Run() = %q, want the chunk mentioning carrots", result)
+			}
+		})
+	}
+}
+
+func TestSourceConfig(t *testing.T) {
+	set, err := rules.Parse(strings.NewReader(`
+rules:
+  - match: example.com/recipes
+    selector: .recipe
+    exclude: [".ad"]
+    include_all: true
+    input_format: html
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		cfg      Config
+		expected Config
+	}{
+		{
+			name:     "rule fills unset settings",
+			source:   "https://example.com/recipes/focaccia",
+			cfg:      Config{Rules: set},
+			expected: Config{Rules: set, Selector: ".recipe", Exclude: []string{".ad"}, IncludeAll: true, InputFormat: extract.FormatHTML},
+		},
+		{
+			name:     "command line settings win",
+			source:   "https://example.com/recipes/focaccia",
+			cfg:      Config{Rules: set, Selector: "main", Exclude: []string{"nav"}, InputFormat: extract.FormatMarkdown},
+			expected: Config{Rules: set, Selector: "main", Exclude: []string{"nav", ".ad"}, IncludeAll: true, InputFormat: extract.FormatMarkdown},
+		},
+		{
+			name:     "no matching rule",
+			source:   "https://example.com/about",
+			cfg:      Config{Rules: set, Exclude: []string{"nav"}},
+			expected: Config{Rules: set, Exclude: []string{"nav"}},
+		},
+		{
+			name:     "no rules",
+			source:   "https://example.com/recipes/focaccia",
+			cfg:      Config{Selector: "main"},
+			expected: Config{Selector: "main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sourceConfig(tt.source, tt.cfg)
+			if got.Selector != tt.expected.Selector ||
+				strings.Join(got.Exclude, ",") != strings.Join(tt.expected.Exclude, ",") ||
+				got.IncludeAll != tt.expected.IncludeAll ||
+				got.InputFormat != tt.expected.InputFormat {
+				t.Errorf("sourceConfig() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+
+	// appending a rule's exclusions leaves the shared command line slice untouched
+	shared := make([]string, 1, 4)
+	shared[0] = "nav"
+	sourceConfig("https://example.com/recipes/a", Config{Rules: set, Exclude: shared})
+	if got := shared[:2][1]; got != "" {
+		t.Errorf("sourceConfig() wrote %q into the command line exclusions", got)
+	}
+}
+
+func TestRun_ExtractionRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><nav>Home</nav><div class="recipe"><p>Fold the dough every thirty minutes.</p>`+
+			`<p class="ad">Buy our flour.</p></div><footer>Copyright</footer></body></html>`)
+	}))
+	defer server.Close()
+
+	set, err := rules.Parse(strings.NewReader("rules:\n  - match: " + server.URL + "/recipes\n    selector: .recipe\n    exclude: [.ad]\n    include_all: true\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	result, err := Run(context.Background(), Config{
+		Sources:        []string{server.URL + "/recipes/focaccia", server.URL + "/about"},
+		CountingMethod: counter.Words,
+		Rules:          set,
+		IncludeAll:     true,
+		Quiet:          true,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// the recipe page is reduced to its recipe, the other page is extracted whole
+	parts := strings.SplitN(result, "\n\n", 2)
+	if len(parts) != 2 {
+		t.Fatalf("Run() = %q, want two documents", result)
+	}
+	if parts[0] != "Fold the dough every thirty minutes." {
+		t.Errorf("matched page = %q, want only the recipe without the ad", parts[0])
+	}
+	if !strings.Contains(parts[1], "Home") || !strings.Contains(parts[1], "Buy our flour.") {
+		t.Errorf("unmatched page = %q, want the whole page", parts[1])
+	}
+}
+
+func TestRun_LinkModes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".md") {
+			w.Header().Set("Content-Type", "text/markdown")
+			fmt.Fprint(w, "Proof the dough as the [baker's guide](guide) says.\n\n- [Home](/)\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Proof the dough as the <a href="guide">baker's guide</a> says.</p>`+
+			`<p>See <a href="/tips">tips</a> and the <a href="guide">guide</a> again.</p></body></html>`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		links    extract.LinkMode
+		stream   bool
+		expected string
+	}{
+		{
+			name:     "inline resolves relative links",
+			path:     "/bread/",
+			links:    extract.LinksInline,
+			expected: "Proof the dough as the [baker's guide](" + server.URL + "/bread/guide) says.\n\nSee [tips](" + server.URL + "/tips) and the [guide](" + server.URL + "/bread/guide) again.",
+		},
+		{
+			name:  "reference lists urls at the end",
+			path:  "/bread/",
+			links: extract.LinksReference,
+			expected: "Proof the dough as the [baker's guide][1] says.\n\nSee [tips][2] and the [guide][1] again.\n\n" +
+				"[1]: " + server.URL + "/bread/guide\n[2]: " + server.URL + "/tips",
+		},
+		{
+			name:     "text keeps anchor text",
+			path:     "/bread/",
+			links:    extract.LinksText,
+			expected: "Proof the dough as the baker's guide says.\n\nSee tips and the guide again.",
+		},
+		{
+			name:     "strip markdown source",
+			path:     "/bread/notes.md",
+			links:    extract.LinksStrip,
+			expected: "Proof the dough as the says.",
+		},
+		{
+			name:     "reference markdown source streamed",
+			path:     "/bread/notes.md",
+			links:    extract.LinksReference,
+			stream:   true,
+			expected: "Proof the dough as the [baker's guide][1] says.\n\n- [Home][2]\n\n[1]: " + server.URL + "/bread/guide\n[2]: " + server.URL + "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(context.Background(), Config{
+				Sources:        []string{server.URL + tt.path},
+				CountingMethod: counter.Words,
+				MaxUnits:       1000,
+				IncludeAll:     true,
+				Links:          tt.links,
+				Stream:         tt.stream,
+				Quiet:          true,
+			})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Run() =\n%q\nwant\n%q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRun_ReferenceLinksAfterSizing(t *testing.T) {
+	// the link to the oven is far from the top of the page and from the reference definitions
+	// that would end it
+	var page strings.Builder
+	page.WriteString("Knead the dough with [flour](/flour) for ten minutes.\n\n")
+	for i := range 300 {
+		fmt.Fprintf(&page, "Filler paragraph %d about folding and shaping loaves.\n\n", i)
+		if i == 150 {
+			page.WriteString("Bake the loaf in a hot [oven](/oven) with steam.\n\n")
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/markdown")
+		if r.URL.Path == "/rest.md" {
+			fmt.Fprint(w, "Rest the dough, dusted with [flour](/flour), overnight.\n")
+			return
+		}
+		fmt.Fprint(w, page.String())
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "size limit",
+			cfg:      Config{MaxUnits: 8},
+			contains: []string{"Knead the dough with [flour][1] for ten minutes.\n\n[1]: " + server.URL + "/flour"},
+			excludes: []string{"[2]:"},
+		},
+		{
+			name:     "search",
+			cfg:      Config{MaxUnits: 60, SearchQuery: "oven steam"},
+			contains: []string{"[oven][2]", "[2]: " + server.URL + "/oven"},
+		},
+		{
+			name:     "json chunks",
+			cfg:      Config{MaxUnits: 8, OutputFormat: JSON},
+			contains: []string{`for ten minutes.\n\n[1]: ` + server.URL + "/flour"},
+		},
+		{
+			name:     "numbers shared across sources",
+			cfg:      Config{Sources: []string{server.URL + "/bread.md", server.URL + "/rest.md"}, MaxUnits: 20, SearchQuery: "rest overnight"},
+			contains: []string{"Rest the dough, dusted with [flour][1], overnight.", "[1]: " + server.URL + "/flour"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s stream %v", tt.name, stream), func(t *testing.T) {
+				cfg := tt.cfg
+				if cfg.Sources == nil {
+					cfg.Sources = []string{server.URL + "/bread.md"}
+				}
+				cfg.CountingMethod = counter.Words
+				cfg.Links = extract.LinksReference
+				cfg.IncludeAll = true
+				cfg.Stream = stream
+				cfg.Quiet = true
+
+				result, err := Run(context.Background(), cfg)
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				for _, want := range tt.contains {
+					if !strings.Contains(result, want) {
+						t.Errorf("Run() =\n%s\nwant it to contain %q", result, want)
+					}
+				}
+				for _, unwanted := range tt.excludes {
+					if strings.Contains(result, unwanted) {
+						t.Errorf("Run() =\n%s\nwant it not to contain %q", result, unwanted)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,582 @@
+package app
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/chriscorrea/bm25md"
+	"github.com/chriscorrea/sift/internal/chunk"
+	"github.com/chriscorrea/sift/internal/classify"
+	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// minStreamHits is the fewest search results kept while streaming. Without a size limit, search
+// selects at most 5 results from the top half of relevant chunks, which 10 results always cover.
+const minStreamHits = 10
+
+// streamHitMargin is how many times the output budget the kept search results cover, since
+// results whose context overlaps add fewer units than their chunks hold
+const streamHitMargin = 2
+
+// streamChunk is a chunk read in streaming mode
+type streamChunk struct {
+	ChunkWithIndex
+	document int    // index of the document the chunk was read from
+	title    string // article title of the document, boosting the chunk in search
+	units    int    // size in the configured counting method
+}
+
+// runStream is Run for streaming mode: sources are read one at a time, keeping only the content
+// the output can use. Without a search query, Markdown and text output stop reading once the size
+// limit is met. With a query, or for JSON output, content is chunked as it is read: search keeps a
+// bounded heap of the highest-scoring chunks (and their context), Beginning sizing stops reading
+// once the limit is met, and End sizing keeps a sliding window of the last chunks. Middle sizing
+// needs the whole input and is not supported.
+func runStream(ctx context.Context, client *fetch.Client, sources []string, cfg Config) (string, error) {
+	searchQuery := strings.TrimSpace(cfg.SearchQuery)
+	if searchQuery == "" && cfg.OutputFormat != JSON {
+		return streamText(ctx, client, sources, cfg)
+	}
+	if searchQuery == "" && cfg.MaxUnits > 0 && cfg.SizingStrategy == Middle {
+		return "", fmt.Errorf("middle sizing needs the whole input and cannot be used when streaming")
+	}
+
+	selector, err := NewChunkSelector(cfg.CountingMethod, cfg.MaxUnits, cfg.SizingStrategy)
+	if err != nil {
+		return "", fmt.Errorf("failed to create chunk selector: %w", err)
+	}
+
+	// the length of streamed content is unknown up front, so it is chunked as large text
+	chunkSize := selector.calculateChunkSizeForLength(math.MaxInt)
+
+	var collector interface {
+		add(c streamChunk) bool
+		result() (kept []streamChunk, ordered []ChunkWithIndex)
+	}
+	if searchQuery != "" {
+		collector = newStreamSearch(selector, searchQuery, cfg)
+	} else {
+		collector = &streamWindow{selector: selector}
+	}
+
+	// classification filtering only applies to search, as with full reads; the position of a
+	// chunk in its document is unknown, so every chunk is judged like one of a short document
+	classifier := classify.NewClassifier()
+	filter := searchQuery != "" && !cfg.IncludeAll
+
+	read, index := 0, 0
+	documents, err := streamDocuments(ctx, client, sources, cfg, func(document int, doc Document, content io.Reader) (bool, error) {
+		stream := chunk.NewStream(content, chunkSize)
+		for text, ok := stream.Next(); ok; text, ok = stream.Next() {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			read++
+			if cfg.OutputFormat == Text {
+				text = extract.ToPlainText(withReferences(text, cfg), cfg.LinkFootnotes)
+			}
+			if filter && classifier.IsExtraneous(text, 1, 3) {
+				continue
+			}
+
+			c := streamChunk{ChunkWithIndex: ChunkWithIndex{Text: text, Index: index}, document: document, title: doc.Title}
+			index++
+			if !collector.add(c) {
+				return false, nil
+			}
+		}
+		if err := stream.Err(); err != nil {
+			return true, fmt.Errorf("failed to read content: %w", err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if read == 0 {
+		return "", fmt.Errorf("no content extracted from any source")
+	}
+
+	kept, ordered := collector.result()
+	selected, err := selectStreamChunks(selector, kept, ordered, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.OutputFormat == JSON {
+		documentOf := make(map[int]Document, len(selected))
+		for _, c := range kept {
+			documentOf[c.Index] = documents[c.document]
+		}
+		return encodeJSON(selected, selector, documents, func(index int) Document { return documentOf[index] }, cfg)
+	}
+
+	return listReferences(selector.formatSelectedChunks(selected), cfg), nil
+}
+
+// streamText is runStream without a search query for Markdown and text output. The content of
+// each source is read a line at a time until the size limit is met, and then converted and
+// truncated as Run does for a full read, so the output is the same.
+func streamText(ctx context.Context, client *fetch.Client, sources []string, cfg Config) (string, error) {
+	prefix, err := newTextPrefix(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = streamDocuments(ctx, client, sources, cfg, func(_ int, _ Document, content io.Reader) (bool, error) {
+		return prefix.read(content)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	content := prefix.String()
+	if cfg.OutputFormat == Text {
+		content = extract.ToPlainText(withReferences(content, cfg), cfg.LinkFootnotes)
+	}
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("no content extracted from any source")
+	}
+
+	return listReferences(applySimpleSizeLimit(content, cfg.MaxUnits, cfg.CountingMethod), cfg), nil
+}
+
+// selectStreamChunks runs chunk selection over the chunks kept while streaming, given in document
+// order, and the selection order of their positions in kept. Kept chunks are renumbered
+// consecutively for selection, so context stays within each run of consecutive chunks, and
+// selected chunks are given back their original indices.
+func selectStreamChunks(selector *ChunkSelector, kept []streamChunk, ordered []ChunkWithIndex, cfg Config) ([]ChunkWithIndex, error) {
+	if len(ordered) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, len(kept))
+	for i, c := range kept {
+		texts[i] = c.Text
+	}
+
+	contextBefore, contextAfter := selector.defaultContextBefore, selector.defaultContextAfter
+	if selector.isSearchMode {
+		contextBefore, contextAfter = cfg.ContextBefore, cfg.ContextAfter
+	}
+
+	selected, err := selector.SelectChunks(ordered, texts, contextBefore, contextAfter, cfg.ContextUnits, cfg.UseSmartContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select chunks: %w", err)
+	}
+
+	for i := range selected {
+		original := kept[selected[i].Index]
+		selected[i].Index, selected[i].Score = original.Index, original.Score
+	}
+	return selected, nil
+}
+
+// streamDocuments reads sources one at a time, in argument order, passing each document to read
+// along with its index in the returned documents (which hold no content) and its content as it is
+// fetched, preceded by front matter if configured. Plain text and Markdown are normalized, and
+// their links rewritten, as they are read; other formats are extracted in full as usual. Sources
+// that fail, including errors returned by read, are reported as warnings and skipped. Reading stops, without finishing the current source, once read returns false.
+func streamDocuments(ctx context.Context, client *fetch.Client, sources []string, cfg Config, read func(document int, doc Document, content io.Reader) (bool, error)) ([]Document, error) {
+	var documents []Document
+	readDocument := func(doc Document, content io.Reader) (bool, error) {
+		if cfg.references != nil {
+			content = cfg.references.NewReader(content)
+		}
+		if cfg.FrontMatter && cfg.OutputFormat == Markdown {
+			content = io.MultiReader(strings.NewReader(frontMatter(doc)), content)
+		}
+		doc.Content = ""
+		documents = append(documents, doc)
+		return read(len(documents)-1, doc, content)
+	}
+
+	for _, source := range sources {
+		more, err := streamSource(ctx, client, source, cfg, readDocument)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil && !cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "Warning: failed to process source %q: %v\n", source, err)
+		}
+		if !more {
+			break
+		}
+	}
+
+	return documents, nil
+}
+
+// streamSource passes the documents of a single source to read, streaming plain text and
+// Markdown and extracting other formats in full. Returns false once read stops reading.
+func streamSource(ctx context.Context, client *fetch.Client, source string, cfg Config, read func(Document, io.Reader) (bool, error)) (bool, error) {
+	var documents []Document
+	if cfg.Crawl && sourceURL(source) != nil {
+		var err error
+		if documents, err = processCrawl(ctx, client, source, cfg); err != nil {
+			return true, err
+		}
+	} else {
+		content, reader, format, err := openSource(ctx, client, source, sourceConfig(source, cfg))
+		if err != nil {
+			return true, err
+		}
+		defer content.Close()
+
+		if format == extract.FormatText || format == extract.FormatMarkdown {
+			normalized := extract.NewTextNormalizer(reader, format)
+			return read(Document{Source: source}, extract.NewLinkRewriter(normalized, documentLinks(cfg), sourceURL(source)))
+		}
+
+		if documents, err = processContent(ctx, client, source, reader, format, cfg); err != nil {
+			return true, err
+		}
+	}
+
+	for _, doc := range documents {
+		if more, err := read(doc, strings.NewReader(doc.Content)); !more || err != nil {
+			return more, err
+		}
+	}
+	return true, nil
+}
+
+// streamWindow keeps the chunks that sizing can select while streaming: all of them without a
+// size limit, the first ones up to the limit for Beginning sizing, or the last ones for End sizing
+type streamWindow struct {
+	selector *ChunkSelector
+	chunks   []streamChunk
+	units    int
+}
+
+// add keeps a chunk, returning false once no later chunk can be selected
+func (w *streamWindow) add(c streamChunk) bool {
+	w.chunks = append(w.chunks, c)
+	if w.selector.maxUnits <= 0 {
+		return true
+	}
+
+	c.units = w.selector.counter.Count(c.Text)
+	w.chunks[len(w.chunks)-1] = c
+	w.units += c.units
+
+	if w.selector.strategy == End {
+		// drop the oldest chunks once newer ones fill the limit on their own
+		for len(w.chunks) > 1 && w.units-w.chunks[0].units >= w.selector.maxUnits {
+			w.units -= w.chunks[0].units
+			w.chunks = w.chunks[1:]
+		}
+		return true
+	}
+
+	return w.units < w.selector.maxUnits
+}
+
+// result returns the kept chunks and their selection order under the sizing strategy
+func (w *streamWindow) result() ([]streamChunk, []ChunkWithIndex) {
+	texts := make([]string, len(w.chunks))
+	for i, c := range w.chunks {
+		texts[i] = c.Text
+	}
+	return w.chunks, w.selector.PrepareForStrategy(texts)
+}
+
+// streamHit is a search result kept while streaming, with the context selection may add around it
+type streamHit struct {
+	chunk       streamChunk
+	before      []streamChunk   // preceding chunks, oldest first
+	after       []streamChunk   // following chunks, filled in as they are read
+	frequencies termFrequencies // field-weighted frequency of each query term in the chunk
+	evicted     bool            // dropped from the heap before its following context was read
+}
+
+// hitHeap is a min-heap of search results, lowest score (and latest chunk, among equal scores) first
+type hitHeap []*streamHit
+
+func (h hitHeap) Len() int { return len(h) }
+func (h hitHeap) Less(i, j int) bool {
+	if h[i].chunk.Score != h[j].chunk.Score {
+		return h[i].chunk.Score < h[j].chunk.Score
+	}
+	return h[i].chunk.Index > h[j].chunk.Index
+}
+func (h hitHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x any)   { *h = append(*h, x.(*streamHit)) }
+func (h *hitHeap) Pop() any {
+	old := *h
+	hit := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return hit
+}
+
+// streamSearch keeps the highest-scoring chunks read while streaming, enough of them to fill the
+// output budget, along with the context chunks selection may add around each one
+type streamSearch struct {
+	selector      *ChunkSelector
+	scorer        *streamScorer
+	budget        int  // output units the kept results must be able to fill, 0 for no limit
+	smart         bool // context is sized in units (ContextUnits) rather than chunks
+	contextBefore int
+	contextAfter  int
+	contextUnits  int
+	recent        []streamChunk // latest chunks, the preceding context of the next result
+	pending       []*streamHit  // results still reading their following context
+	hits          hitHeap
+	hitUnits      int // units of the chunks of all kept results
+	rescoreAt     int // number of chunks read at which kept results are next rescored
+}
+
+func newStreamSearch(selector *ChunkSelector, query string, cfg Config) *streamSearch {
+	s := &streamSearch{
+		selector:      selector,
+		scorer:        newStreamScorer(query),
+		budget:        cfg.MaxUnits,
+		smart:         cfg.UseSmartContext && cfg.ContextUnits > 0,
+		contextBefore: cfg.ContextBefore,
+		contextAfter:  cfg.ContextAfter,
+		contextUnits:  cfg.ContextUnits,
+		rescoreAt:     minStreamHits,
+	}
+	if s.smart {
+		s.budget = cfg.ContextUnits
+	}
+	return s
+}
+
+// covered reports whether context chunks hold all the context selection can use on one side of
+// a result: limit chunks or, with smart context, the context budget
+func (s *streamSearch) covered(chunks []streamChunk, limit int) bool {
+	if !s.smart {
+		return len(chunks) >= limit
+	}
+	units := 0
+	for _, c := range chunks {
+		units += c.units
+	}
+	return units >= s.contextUnits
+}
+
+// add scores a chunk and keeps it if it ranks among the results selection can reach
+func (s *streamSearch) add(c streamChunk) bool {
+	c.units = s.selector.counter.Count(c.Text)
+
+	// the chunk is following context of earlier results
+	pending := s.pending[:0]
+	for _, hit := range s.pending {
+		if hit.evicted {
+			continue
+		}
+		hit.after = append(hit.after, c)
+		if !s.covered(hit.after, s.contextAfter) {
+			pending = append(pending, hit)
+		}
+	}
+	clear(s.pending[len(pending):])
+	s.pending = pending
+
+	hit := &streamHit{chunk: c, before: slices.Clone(s.recent), frequencies: s.scorer.add(c.Text, c.title)}
+	hit.chunk.Score = s.scorer.score(hit.frequencies)
+	heap.Push(&s.hits, hit)
+	s.hitUnits += c.units
+	if !s.covered(nil, s.contextAfter) {
+		s.pending = append(s.pending, hit)
+	}
+	s.evict()
+
+	s.recent = append(s.recent, c)
+	for len(s.recent) > 0 && s.covered(s.recent[1:], s.contextBefore) {
+		s.recent = s.recent[1:]
+	}
+
+	// scores depend on statistics of all chunks read so far, so kept results are rescored as
+	// those grow to keep eviction in step with the final ranking
+	if s.scorer.docs >= s.rescoreAt {
+		s.rescore()
+		s.rescoreAt *= 2
+	}
+	return true
+}
+
+// evict drops the lowest-scoring results that selection would never reach
+func (s *streamSearch) evict() {
+	for s.hits.Len() > minStreamHits {
+		lowest := s.hits[0]
+		if s.budget > 0 && s.hitUnits-lowest.chunk.units < streamHitMargin*s.budget {
+			return
+		}
+		heap.Pop(&s.hits)
+		lowest.evicted = true
+		s.hitUnits -= lowest.chunk.units
+	}
+}
+
+// rescore recomputes the scores of kept results with the current statistics
+func (s *streamSearch) rescore() {
+	for _, hit := range s.hits {
+		hit.chunk.Score = s.scorer.score(hit.frequencies)
+	}
+	heap.Init(&s.hits)
+}
+
+// result returns the chunks of kept results and their context in document order, and the
+// positions of the results within them, ordered by final score
+func (s *streamSearch) result() ([]streamChunk, []ChunkWithIndex) {
+	s.rescore()
+
+	unique := make(map[int]streamChunk)
+	for _, hit := range s.hits {
+		for _, c := range hit.before {
+			unique[c.Index] = c
+		}
+		for _, c := range hit.after {
+			unique[c.Index] = c
+		}
+	}
+	// results carry their scores, so they take precedence over the same chunk as context
+	for _, hit := range s.hits {
+		unique[hit.chunk.Index] = hit.chunk
+	}
+
+	kept := make([]streamChunk, 0, len(unique))
+	for _, c := range unique {
+		kept = append(kept, c)
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Index < kept[j].Index
+	})
+
+	position := make(map[int]int, len(kept))
+	for i, c := range kept {
+		position[c.Index] = i
+	}
+
+	scored := make([]ChunkScore, 0, len(s.hits))
+	for _, hit := range s.hits {
+		scored = append(scored, ChunkScore{Chunk: hit.chunk.Text, Score: hit.chunk.Score, Index: position[hit.chunk.Index]})
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].Index < scored[j].Index
+	})
+
+	return kept, s.selector.PrepareForSearch(scored)
+}
+
+// streamScorer scores chunks one at a time with the BM25F formula of bm25md.Corpus.Score,
+// keeping only the document frequencies of the query terms rather than a whole corpus
+type streamScorer struct {
+	terms       []string
+	parser      *bm25md.MarkdownFieldParser
+	tokenizer   bm25md.Tokenizer
+	docFreq     []int  // chunks containing each query term, counting article titles
+	bodyDocFreq []int  // chunks containing each query term, not counting article titles
+	docs        int    // chunks read
+	firstTitle  string // article title of the first chunk read
+	titled      bool   // chunks with differing article titles were read, so titles boost scores
+}
+
+// termFrequencies holds the field-weighted frequency of each query term in a chunk, without and
+// with the article title of its document
+type termFrequencies struct {
+	body   []float64
+	titled []float64
+}
+
+func newStreamScorer(query string) *streamScorer {
+	tokenizer := bm25md.DefaultTokenizer{}
+	terms := tokenizer.Tokenize(query)
+	return &streamScorer{
+		terms:       terms,
+		parser:      bm25md.NewMarkdownFieldParser(),
+		tokenizer:   tokenizer,
+		docFreq:     make([]int, len(terms)),
+		bodyDocFreq: make([]int, len(terms)),
+	}
+}
+
+// add counts a chunk toward the document frequencies and returns the field-weighted frequency of
+// each query term in it. As in performLexicalSearch, the article title of its document adds to
+// them only once titles differ between chunks.
+func (s *streamScorer) add(text, title string) termFrequencies {
+	frequencies := termFrequencies{body: make([]float64, len(s.terms)), titled: make([]float64, len(s.terms))}
+	if s.docs == 0 {
+		s.firstTitle = title
+	} else if title != s.firstTitle {
+		s.titled = true
+	}
+	s.docs++
+
+	// most chunks of a large input contain no query term, and parsing them can be skipped
+	lower := strings.ToLower(text + " " + title)
+	if !slices.ContainsFunc(s.terms, func(term string) bool { return strings.Contains(lower, term) }) {
+		return frequencies
+	}
+
+	// the title is scored as part of the h1 field, as searchFields adds it
+	for field, content := range s.parser.ParseDocument(text) {
+		if weight, ok := bm25md.DefaultFieldWeights[field]; ok {
+			s.weigh(frequencies.body, content, weight)
+		}
+	}
+	copy(frequencies.titled, frequencies.body)
+	s.weigh(frequencies.titled, title, bm25md.DefaultFieldWeights[bm25md.FieldH1])
+
+	for i := range s.terms {
+		if frequencies.titled[i] > 0 {
+			s.docFreq[i]++
+		}
+		if frequencies.body[i] > 0 {
+			s.bodyDocFreq[i]++
+		}
+	}
+
+	return frequencies
+}
+
+// weigh adds weight to the frequency of each query term for every occurrence of it in content
+func (s *streamScorer) weigh(frequencies []float64, content string, weight float64) {
+	for _, token := range s.tokenizer.Tokenize(content) {
+		for i, term := range s.terms {
+			if token == term {
+				frequencies[i] += weight
+			}
+		}
+	}
+}
+
+// score returns the BM25md score of a chunk from its query term frequencies, using the statistics
+// of all chunks added so far
+func (s *streamScorer) score(frequencies termFrequencies) float64 {
+	// bm25md combines field-weighted frequencies with a fixed k1 and no length normalization
+	const k1 = 1.2
+
+	weighted, docFreq := frequencies.body, s.bodyDocFreq
+	if s.titled {
+		weighted, docFreq = frequencies.titled, s.docFreq
+	}
+
+	score := 0.0
+	for i, frequency := range weighted {
+		if frequency == 0 || docFreq[i] == 0 {
+			continue
+		}
+		df := float64(docFreq[i])
+		idf := math.Log((float64(s.docs) - df + 0.5) / (df + 0.5))
+		if idf < 0 {
+			idf = 0 // as bm25md does for small corpora
+		}
+		score += idf * frequency * (k1 + 1) / (frequency + k1)
+	}
+	return score
+}
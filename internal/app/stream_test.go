@@ -0,0 +1,318 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chriscorrea/bm25md"
+	"github.com/chriscorrea/sift/internal/counter"
+)
+
+// writeLog writes a log of numbered paragraphs, replacing those listed in lines, and returns its path
+func writeLog(t *testing.T, paragraphs int, lines map[int]string) string {
+	t.Helper()
+
+	var b strings.Builder
+	for i := range paragraphs {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		if line, ok := lines[i]; ok {
+			b.WriteString(line)
+		} else {
+			fmt.Fprintf(&b, "Line %d of the proofing log records a steady oven temperature.", i)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "proofing.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	return path
+}
+
+func TestRunStream_Sizing(t *testing.T) {
+	log := writeLog(t, 3000, nil)
+
+	// a source after the size limit is met is never fetched
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, "Line from the server.")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		format   OutputFormat
+		strategy SizingStrategy
+		contains string
+		excludes string
+		wantErr  bool
+	}{
+		{
+			name:     "markdown stops reading at the limit",
+			format:   Markdown,
+			strategy: Beginning,
+			contains: "Line 0 of the proofing log",
+			excludes: "Line 100 ",
+		},
+		{
+			name:     "json beginning stops reading at the limit",
+			format:   JSON,
+			strategy: Beginning,
+			contains: "Line 0 of the proofing log",
+			excludes: "Line 100 ",
+		},
+		{
+			name:     "json end keeps the last chunks",
+			format:   JSON,
+			strategy: End,
+			contains: "Line 2999 of the proofing log",
+			excludes: "Line 0 ",
+		},
+		{
+			name:     "json middle is not supported",
+			format:   JSON,
+			strategy: Middle,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := []string{log}
+			if tt.strategy != End {
+				sources = append(sources, server.URL)
+			}
+
+			result, err := Run(context.Background(), Config{
+				Sources:        sources,
+				MaxUnits:       50,
+				CountingMethod: counter.Words,
+				SizingStrategy: tt.strategy,
+				OutputFormat:   tt.format,
+				Quiet:          true,
+				Stream:         true,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Run() expected an error, got %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if words := len(strings.Fields(result)); tt.format != JSON && words > 50 {
+				t.Errorf("Run() returned %d words, exceeding the limit of 50", words)
+			}
+			if !strings.Contains(result, tt.contains) {
+				t.Errorf("Run() = %q, want it to contain %q", result, tt.contains)
+			}
+			if strings.Contains(result, tt.excludes) {
+				t.Errorf("Run() = %q, want it not to contain %q", result, tt.excludes)
+			}
+		})
+	}
+
+	if n := requests.Load(); n != 0 {
+		t.Errorf("server received %d requests after the limit was met, want 0", n)
+	}
+}
+
+func TestRunStream_TextMatchesFullRead(t *testing.T) {
+	first := writeLog(t, 40, map[int]string{3: "Proofing [notes](https://example.com/notes) for **batch** two."})
+	second := writeLog(t, 40, nil)
+
+	for _, format := range []OutputFormat{Markdown, Text} {
+		for _, maxUnits := range []int{0, 30, 500} {
+			t.Run(fmt.Sprintf("%s/%d", format, maxUnits), func(t *testing.T) {
+				cfg := Config{
+					Sources:        []string{first, second},
+					MaxUnits:       maxUnits,
+					CountingMethod: counter.Words,
+					OutputFormat:   format,
+					Quiet:          true,
+				}
+
+				full, err := Run(context.Background(), cfg)
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+
+				cfg.Stream = true
+				result, err := Run(context.Background(), cfg)
+				if err != nil {
+					t.Fatalf("Run() with streaming error = %v", err)
+				}
+				if result != full {
+					t.Errorf("streamed output differs from a full read\nstreamed: %q\nfull:     %q", result, full)
+				}
+			})
+		}
+	}
+}
+
+func TestRunStream_SearchMatchesFullRead(t *testing.T) {
+	log := writeLog(t, 600, map[int]string{
+		100: "The sourdough starter collapsed, sourdough everywhere, sourdough ruined.",
+		300: "A second sourdough loaf rose well, and the sourdough crumb was open.",
+		500: "Nobody mentioned the sourdough again until the next proofing run.",
+	})
+
+	cfg := Config{
+		Sources:        []string{log},
+		MaxUnits:       100,
+		CountingMethod: counter.Words,
+		SearchQuery:    "sourdough",
+		ContextBefore:  1,
+		ContextAfter:   2,
+		IncludeAll:     true,
+		Quiet:          true,
+	}
+
+	for _, format := range []OutputFormat{Markdown, JSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			cfg.OutputFormat = format
+
+			full, err := Run(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			streamed := cfg
+			streamed.Stream = true
+			result, err := Run(context.Background(), streamed)
+			if err != nil {
+				t.Fatalf("Run() with streaming error = %v", err)
+			}
+
+			if format == JSON {
+				// scores sum field weights in map order, so they may differ in the last bits
+				result, full = roundScores(t, result), roundScores(t, full)
+			}
+			if result != full {
+				t.Errorf("streamed search differs from a full read\nstreamed: %s\nfull:     %s", result, full)
+			}
+			if !strings.Contains(result, "sourdough everywhere") {
+				t.Errorf("Run() = %q, want the best match", result)
+			}
+		})
+	}
+}
+
+// roundScores rounds the chunk scores of JSON output and re-encodes it
+func roundScores(t *testing.T, result string) string {
+	t.Helper()
+
+	var output jsonOutput
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	for i := range output.Chunks {
+		output.Chunks[i].Score = math.Round(output.Chunks[i].Score*1e9) / 1e9
+	}
+	encoded, _ := json.Marshal(output)
+	return string(encoded)
+}
+
+func TestStreamSearch_KeepsBoundedResults(t *testing.T) {
+	selector, err := NewChunkSelector(counter.Words, 40, Beginning)
+	if err != nil {
+		t.Fatalf("NewChunkSelector() error = %v", err)
+	}
+	search := newStreamSearch(selector, "failed", Config{MaxUnits: 40, ContextBefore: 1, ContextAfter: 2})
+
+	for i := range 5000 {
+		text := fmt.Sprintf("Line %d records the oven temperature.", i)
+		if i%1000 == 999 {
+			text = fmt.Sprintf("Line %d: the oven failed and the proofing failed.", i)
+		}
+		search.add(streamChunk{ChunkWithIndex: ChunkWithIndex{Text: text, Index: i}})
+
+		if search.hits.Len() > minStreamHits && search.hitUnits-search.hits[0].chunk.units >= streamHitMargin*40 {
+			t.Fatalf("after %d chunks, %d results (%d units) are kept", i+1, search.hits.Len(), search.hitUnits)
+		}
+		if len(search.pending) > 3 || len(search.recent) > 1 {
+			t.Fatalf("after %d chunks, %d pending results and %d context chunks are kept", i+1, len(search.pending), len(search.recent))
+		}
+	}
+
+	kept, ordered := search.result()
+	if len(kept) > 4*search.hits.Len() {
+		t.Errorf("kept %d chunks for %d results", len(kept), search.hits.Len())
+	}
+	if top := kept[ordered[0].Index]; !strings.Contains(top.Text, "oven failed") {
+		t.Errorf("top result = %q, want a chunk mentioning the failure", top.Text)
+	}
+}
+
+func TestStreamScorer_MatchesCorpus(t *testing.T) {
+	type titledChunk struct{ text, title string }
+	sets := []struct {
+		name   string
+		chunks []titledChunk
+		titled bool // titles differ, so the corpus is built with them
+	}{
+		{
+			name: "differing titles",
+			chunks: []titledChunk{
+				{"# Sourdough\n\nFeed the starter daily.", ""},
+				{"The **starter** doubles in size when it is active.", ""},
+				{"Bake the loaf in a covered pot.", "Keeping a Starter Alive"},
+				{"`starter` ratios: one part flour, one part water, starter optional.", ""},
+				{"Let the dough rest overnight.", "Keeping a Starter Alive"},
+				{"Cool the loaf before slicing.", ""},
+			},
+			titled: true,
+		},
+		{
+			name: "shared title",
+			chunks: []titledChunk{
+				{"# Sourdough\n\nFeed the starter daily.", "Keeping a Starter Alive"},
+				{"The **starter** doubles in size when it is active.", "Keeping a Starter Alive"},
+				{"Bake the loaf in a covered pot.", "Keeping a Starter Alive"},
+				{"Let the dough rest overnight.", "Keeping a Starter Alive"},
+				{"Cool the loaf before slicing.", "Keeping a Starter Alive"},
+			},
+		},
+	}
+
+	for _, set := range sets {
+		for _, query := range []string{"starter", "starter loaf", "sourdough starter starter", "alive", "missing"} {
+			t.Run(set.name+"/"+query, func(t *testing.T) {
+				corpus := bm25md.NewCorpus()
+				parser := bm25md.NewMarkdownFieldParser()
+				scorer := newStreamScorer(query)
+
+				frequencies := make([]termFrequencies, len(set.chunks))
+				for i, chunk := range set.chunks {
+					title := chunk.title
+					if !set.titled {
+						title = ""
+					}
+					corpus.AddDocument(bm25md.Document{ID: i, Fields: searchFields(parser, chunk.text, title), Original: chunk.text})
+					frequencies[i] = scorer.add(chunk.text, chunk.title)
+				}
+
+				for i := range set.chunks {
+					want := corpus.Score(query, i)
+					if got := scorer.score(frequencies[i]); math.Abs(got-want) > 1e-9 {
+						t.Errorf("score of chunk %d = %v, want %v", i, got, want)
+					}
+				}
+			})
+		}
+	}
+}
@@ -9,6 +9,9 @@
 //  3. Line boundaries (single newlines) - maintains formatting context
 //  4. Word boundaries - last resort for oversized content
 //
+// Pipe tables are kept whole where they fit; otherwise they are split between rows, and each
+// chunk of a table repeats its header row.
+//
 // Usage Example:
 //
 //	chunks := chunk.SplitText(content, 250)
@@ -92,6 +95,13 @@ func SplitText(text string, maxChunkSize int) []string {
 				continue
 			}
 
+			// a table is split between rows, repeating its header, rather than by sentences or words
+			if strategy.name != "paragraph" && isTable(chunk) {
+				slog.Debug("Splitting oversized table", "chunkLength", len(chunk))
+				finalChunks = append(finalChunks, splitTable(chunk, maxChunkSize)...)
+				continue
+			}
+
 			// this chunk is too big–split it with the current strategy
 			slog.Debug("Splitting oversized chunk", "strategy", strategy.name, "chunkLength", len(chunk))
 			subChunks := splitByDelimiter(chunk, strategy.delimiter, strategy.name, maxChunkSize)
@@ -121,12 +131,19 @@ func SplitText(text string, maxChunkSize int) []string {
 
 // splitByDelimiter splits text by a delimiter and packs segments together up to the size limit.
 func splitByDelimiter(text, delimiter, strategyName string, maxChunkSize int) []string {
-	if !strings.Contains(text, delimiter) {
+	parts := strings.Split(text, delimiter)
+	if strategyName == "paragraph" {
+		// tables are kept apart from the text around them
+		var separated []string
+		for _, part := range parts {
+			separated = append(separated, separateTables(part)...)
+		}
+		parts = separated
+	}
+	if len(parts) == 1 {
 		// no delimiter found, return original text
 		return []string{text}
 	}
-
-	parts := strings.Split(text, delimiter)
 	slog.Debug("Split by delimiter", "strategy", strategyName, "delimiter", delimiter, "parts", len(parts))
 
 	// prepare segments with proper delimiter restoration
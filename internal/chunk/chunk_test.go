@@ -1,6 +1,7 @@
 package chunk_test
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -700,3 +701,84 @@ func TestSplitTextMinimumChunkLength(t *testing.T) {
 		})
 	}
 }
+
+// bakeTable returns a pipe table of n rows
+func bakeTable(n int) string {
+	lines := []string{"| Loaf | Minutes | Notes |", "| --- | :-: | --- |"}
+	for i := range n {
+		lines = append(lines, fmt.Sprintf("| Loaf %d | %d | Score it. Then bake until it sounds hollow. |", i, 30+i))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestSplitTextTables(t *testing.T) {
+	const header = "| Loaf | Minutes | Notes |\n| --- | :-: | --- |"
+
+	tests := []struct {
+		name         string
+		text         string
+		maxChunkSize int
+		tableChunks  int // chunks holding table rows
+	}{
+		{
+			name:         "table that fits stays whole",
+			text:         "Bake times follow.\n\n" + bakeTable(3) + "\n\nLet the loaves cool.",
+			maxChunkSize: 260,
+			tableChunks:  1,
+		},
+		{
+			name:         "long table splits between rows",
+			text:         "Bake times follow.\n\n" + bakeTable(20) + "\n\nLet the loaves cool.",
+			maxChunkSize: 300,
+			tableChunks:  5,
+		},
+		{
+			name:         "table directly under a line of text",
+			text:         "Bake times follow:\n" + bakeTable(20),
+			maxChunkSize: 300,
+			tableChunks:  5,
+		},
+		{
+			name:         "row longer than a chunk",
+			text:         bakeTable(4),
+			maxChunkSize: 40,
+			tableChunks:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunk.SplitText(tt.text, tt.maxChunkSize)
+
+			var rows []string
+			tableChunks := 0
+			for _, c := range chunks {
+				if !strings.Contains(c, "hollow. |") {
+					continue
+				}
+				tableChunks++
+
+				// every chunk of the table has its header above its rows, and holds only whole rows
+				if i, j := strings.Index(c, header+"\n"), strings.Index(c, "hollow. |"); i < 0 || i > j {
+					t.Errorf("table chunk %q does not start with the header", c)
+				}
+				for _, line := range strings.Split(c, "\n") {
+					if !strings.Contains(line, "hollow.") {
+						continue
+					}
+					if !strings.HasPrefix(line, "| Loaf ") || !strings.HasSuffix(line, "hollow. |") {
+						t.Errorf("table chunk holds a partial row %q", line)
+					}
+					rows = append(rows, line)
+				}
+			}
+
+			if tableChunks != tt.tableChunks {
+				t.Errorf("got %d table chunks, want %d: %q", tableChunks, tt.tableChunks, chunks)
+			}
+			if want := strings.Count(tt.text, "hollow. |"); len(rows) != want {
+				t.Errorf("got %d rows, want %d", len(rows), want)
+			}
+		})
+	}
+}
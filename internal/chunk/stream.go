@@ -0,0 +1,109 @@
+package chunk
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// streamWindow is how many times maxChunkSize of text a Stream buffers before splitting it
+const streamWindow = 4
+
+// Stream splits text read from an io.Reader into chunks incrementally, using the same strategies
+// as SplitText. Text is buffered up to a few chunks' worth and split at the last paragraph (or,
+// failing that, line or word) boundary, so memory use does not grow with the size of the input.
+//
+// Usage Example:
+//
+//	stream := chunk.NewStream(file, 250)
+//	for text, ok := stream.Next(); ok; text, ok = stream.Next() {
+//		// use text
+//	}
+//	if err := stream.Err(); err != nil {
+//		// handle read error
+//	}
+type Stream struct {
+	reader       *bufio.Reader
+	maxChunkSize int
+	buffer       strings.Builder // text read but not yet split
+	ready        []string        // chunks split but not yet returned
+	err          error           // read error, io.EOF at the end of the text
+}
+
+// NewStream creates a Stream reading from r with chunks of at most maxChunkSize characters
+func NewStream(r io.Reader, maxChunkSize int) *Stream {
+	return &Stream{reader: bufio.NewReader(r), maxChunkSize: maxChunkSize}
+}
+
+// Next returns the next chunk, or ok=false once the text is exhausted or cannot be read
+func (s *Stream) Next() (string, bool) {
+	if s.maxChunkSize <= 0 {
+		return "", false
+	}
+
+	for len(s.ready) == 0 {
+		if s.err != nil {
+			if s.buffer.Len() == 0 {
+				return "", false
+			}
+			// the remaining text is split as a whole, as SplitText would
+			s.ready = SplitText(s.buffer.String(), s.maxChunkSize)
+			s.buffer.Reset()
+			continue
+		}
+
+		fragment, err := s.reader.ReadSlice('\n')
+		s.buffer.Write(fragment)
+		if err != nil && err != bufio.ErrBufferFull {
+			s.err = err
+		}
+		if s.buffer.Len() >= streamWindow*s.maxChunkSize {
+			s.split()
+		}
+	}
+
+	next := s.ready[0]
+	s.ready = s.ready[1:]
+	return next, true
+}
+
+// Err returns the error that ended the stream early, or nil if the text was read in full
+func (s *Stream) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// split chunks the buffered text up to its last paragraph boundary, keeping the rest buffered
+// so that a paragraph is never split only because it straddles two reads; a table cut between
+// rows repeats its header in the text kept. More than a chunk's worth of text is always kept,
+// so that the text left at the end is split as SplitText would split it within a longer text,
+// rather than returned whole because it fits in one chunk.
+func (s *Stream) split() {
+	text := s.buffer.String()
+	limit := len(text) - s.maxChunkSize - 1
+
+	cut := limit
+	for _, delimiter := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(text[:limit], delimiter); i > 0 {
+			cut = i + len(delimiter)
+			break
+		}
+	}
+	// text without spaces is cut between characters
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+
+	s.ready = SplitText(text[:cut], s.maxChunkSize)
+	s.buffer.Reset()
+	// a table cut between rows goes on under its header, which the text kept must outgrow
+	if header, ok := openTable(text[:cut]); ok && cut > len(header)+1 {
+		if row, _, _ := strings.Cut(text[cut:], "\n"); strings.Contains(row, "|") {
+			s.buffer.WriteString(header + "\n")
+		}
+	}
+	s.buffer.WriteString(text[cut:])
+}
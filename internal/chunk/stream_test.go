@@ -0,0 +1,127 @@
+package chunk_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/chriscorrea/sift/internal/chunk"
+)
+
+// collect reads every chunk from a stream
+func collect(stream *chunk.Stream) []string {
+	var chunks []string
+	for text, ok := stream.Next(); ok; text, ok = stream.Next() {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+func TestStream(t *testing.T) {
+	var paragraphs []string
+	for i := range 200 {
+		paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %d sifts flour, sugar, and cocoa into a bowl before folding in the eggs.", i))
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		maxChunkSize int
+		matchSplit   bool // chunks equal those of SplitText on the whole text
+	}{
+		{
+			name:         "empty text",
+			text:         "",
+			maxChunkSize: 100,
+			matchSplit:   true,
+		},
+		{
+			name:         "text smaller than the buffer window",
+			text:         strings.Join(paragraphs[:3], "\n\n"),
+			maxChunkSize: 150,
+			matchSplit:   true,
+		},
+		{
+			name:         "paragraphs across many windows",
+			text:         strings.Join(paragraphs, "\n\n"),
+			maxChunkSize: 100,
+			matchSplit:   true,
+		},
+		{
+			name:         "one long line",
+			text:         strings.Repeat("sift the flour ", 2000),
+			maxChunkSize: 120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := collect(chunk.NewStream(iotest.HalfReader(strings.NewReader(tt.text)), tt.maxChunkSize))
+
+			if tt.matchSplit {
+				if expected := chunk.SplitText(tt.text, tt.maxChunkSize); !slices.Equal(chunks, expected) {
+					t.Errorf("Stream chunks differ from SplitText: got %d chunks, want %d", len(chunks), len(expected))
+				}
+			}
+
+			for i, c := range chunks {
+				if len(c) > tt.maxChunkSize {
+					t.Errorf("chunk %d has %d characters, exceeding %d", i, len(c), tt.maxChunkSize)
+				}
+			}
+
+			// no text is lost between windows
+			if got, want := strings.Join(strings.Fields(strings.Join(chunks, " ")), " "), strings.Join(strings.Fields(tt.text), " "); got != want {
+				t.Errorf("Stream lost text: got %d words, want %d", len(strings.Fields(got)), len(strings.Fields(want)))
+			}
+		})
+	}
+}
+
+func TestStreamErr(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	stream := chunk.NewStream(io.MultiReader(strings.NewReader("first paragraph\n\n"), iotest.ErrReader(readErr)), 100)
+
+	chunks := collect(stream)
+	if !slices.Equal(chunks, chunk.SplitText("first paragraph\n\n", 100)) {
+		t.Errorf("chunks = %q, want the text read before the error", chunks)
+	}
+	if !errors.Is(stream.Err(), readErr) {
+		t.Errorf("Err() = %v, want %v", stream.Err(), readErr)
+	}
+
+	if err := chunk.NewStream(strings.NewReader("text"), 100).Err(); err != nil {
+		t.Errorf("Err() before reading = %v, want nil", err)
+	}
+}
+
+func TestStreamTables(t *testing.T) {
+	const header = "| Loaf | Minutes | Notes |\n| --- | :-: | --- |"
+	text := "Bake times follow.\n\n" + bakeTable(200) + "\n\nLet the loaves cool."
+
+	chunks := collect(chunk.NewStream(iotest.HalfReader(strings.NewReader(text)), 300))
+
+	// a table read across windows repeats its header in every chunk, and no row is lost or split
+	rows := 0
+	for _, c := range chunks {
+		if !strings.Contains(c, "hollow. |") {
+			continue
+		}
+		if !strings.HasPrefix(c, header+"\n") {
+			t.Errorf("table chunk %q does not start with the header", c)
+		}
+		for _, line := range strings.Split(strings.TrimPrefix(c, header+"\n"), "\n") {
+			if !strings.HasPrefix(line, "| Loaf ") || !strings.HasSuffix(line, "hollow. |") {
+				t.Errorf("table chunk holds a partial row %q", line)
+			}
+			rows++
+		}
+	}
+	if rows != 200 {
+		t.Errorf("got %d rows, want 200", rows)
+	}
+}
@@ -0,0 +1,98 @@
+package chunk
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tableDelimiter matches the delimiter row under the header of a pipe table, such as "| --- | :-: |"
+var tableDelimiter = regexp.MustCompile(`^\s*\|?(?:\s*:?-+:?\s*\|)+(?:\s*:?-+:?\s*)?$`)
+
+// tableStartsAt reports whether lines[i] is the header row of a pipe table
+func tableStartsAt(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") && tableDelimiter.MatchString(lines[i+1])
+}
+
+// isTable reports whether text is a pipe table: a header row, a delimiter row, and any rows after them
+func isTable(text string) bool {
+	lines := strings.Split(strings.Trim(text, "\n"), "\n")
+	if !tableStartsAt(lines, 0) {
+		return false
+	}
+	for _, line := range lines[2:] {
+		if !strings.Contains(line, "|") {
+			return false
+		}
+	}
+	return true
+}
+
+// separateTables splits a paragraph into its pipe tables and the runs of other lines around
+// them, so that a table written directly under a line of text is still a unit of its own
+func separateTables(paragraph string) []string {
+	lines := strings.Split(paragraph, "\n")
+
+	var blocks []string
+	start := 0
+	for i := 0; i < len(lines); i++ {
+		if !tableStartsAt(lines, i) {
+			continue
+		}
+		end := i + 2
+		for end < len(lines) && strings.Contains(lines[end], "|") {
+			end++
+		}
+		if i > start {
+			blocks = append(blocks, strings.Join(lines[start:i], "\n"))
+		}
+		blocks = append(blocks, strings.Join(lines[i:end], "\n"))
+		start, i = end, end-1
+	}
+	if start == 0 {
+		return []string{paragraph}
+	}
+	if start < len(lines) {
+		blocks = append(blocks, strings.Join(lines[start:], "\n"))
+	}
+	return blocks
+}
+
+// splitTable splits a pipe table between rows into chunks that each repeat its header and
+// delimiter rows. A row is never split, so a chunk holding a single long row may exceed maxChunkSize.
+func splitTable(table string, maxChunkSize int) []string {
+	lines := strings.Split(strings.Trim(table, "\n"), "\n")
+	header := lines[0] + "\n" + lines[1]
+
+	var chunks []string
+	var current strings.Builder
+	current.WriteString(header)
+	rows := 0
+	for _, row := range lines[2:] {
+		if rows > 0 && current.Len()+1+len(row) > maxChunkSize {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			current.WriteString(header)
+			rows = 0
+		}
+		current.WriteString("\n" + row)
+		rows++
+	}
+	return append(chunks, current.String())
+}
+
+// openTable returns the header and delimiter rows of a pipe table that text ends inside of,
+// after a complete row, so that the rest of the table can be read under the same header
+func openTable(text string) (string, bool) {
+	if !strings.HasSuffix(text, "\n") {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	// walk back over rows to the header of the table they belong to
+	for i := len(lines) - 1; i >= 0 && strings.Contains(lines[i], "|"); i-- {
+		if i >= 1 && tableDelimiter.MatchString(lines[i]) && strings.Contains(lines[i-1], "|") {
+			return lines[i-1] + "\n" + lines[i], true
+		}
+	}
+	return "", false
+}
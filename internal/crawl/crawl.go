@@ -0,0 +1,277 @@
+// Package crawl discovers the pages of a website by following same-site links or reading sitemaps.
+//
+// Crawls are breadth-first from a start URL, bounded by link depth and a page budget. Pages are
+// fetched with a fetch.Client, which enforces robots.txt and spaces out requests to each host.
+package crawl
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// Default crawl limits
+const (
+	DefaultMaxDepth = 2
+	DefaultMaxPages = 50
+	DefaultDelay    = 500 * time.Millisecond // suggested fetch.Options.HostDelay while crawling
+)
+
+// maxSitemaps bounds the number of sitemap files read from nested sitemap indexes
+const maxSitemaps = 20
+
+// Options bounds a crawl
+type Options struct {
+	MaxDepth int // link hops followed from the start page (0 reads only the start page)
+	MaxPages int // maximum number of pages fetched (0 means DefaultMaxPages)
+}
+
+// Page is a fetched page
+type Page struct {
+	URL         string
+	Depth       int    // link hops from the start page
+	ContentType string // media type reported by the server
+	Content     []byte
+}
+
+// skippedExtensions are linked files that never hold readable text
+var skippedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".css": true, ".js": true, ".json": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".zip": true, ".gz": true, ".tar": true, ".tgz": true, ".dmg": true, ".exe": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".webm": true, ".wav": true,
+}
+
+// queued is a URL waiting to be fetched
+type queued struct {
+	url   string
+	depth int
+}
+
+// crawler holds the state of a single crawl
+type crawler struct {
+	client   *fetch.Client
+	opts     Options
+	host     string
+	seen     map[string]bool
+	queue    []queued
+	sitemaps int
+}
+
+// Crawl visits pages on the same host as start, breadth-first, calling visit for each fetched
+// page in crawl order. If start is a sitemap (or sitemap index), the pages it lists are crawled
+// at depth 1 instead of start itself. Pages that fail to fetch, including those disallowed by
+// robots.txt, are logged and skipped; if the start page fails, its error is returned. The crawl
+// stops at the page budget, when ctx is cancelled, or when visit returns an error, which is returned.
+func Crawl(ctx context.Context, client *fetch.Client, start string, opts Options, visit func(Page) error) error {
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+
+	startURL, err := url.Parse(start)
+	if err != nil || (startURL.Scheme != "http" && startURL.Scheme != "https") {
+		return fmt.Errorf("cannot crawl %q: not an HTTP URL", start)
+	}
+
+	c := &crawler{
+		client: client,
+		opts:   opts,
+		host:   strings.ToLower(startURL.Host),
+		seen:   make(map[string]bool),
+	}
+	c.enqueue(start, 0)
+
+	fetched := 0
+	for len(c.queue) > 0 && fetched < opts.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		next := c.queue[0]
+		c.queue = c.queue[1:]
+
+		page, err := c.fetch(ctx, next)
+		if err != nil {
+			if next.depth == 0 || ctx.Err() != nil {
+				return err
+			}
+			slog.Debug("Skipping page that failed to fetch", "url", next.url, "error", err)
+			continue
+		}
+
+		// a sitemap lists pages rather than being one
+		if locs, nested, ok := parseSitemap(page.Content); ok {
+			c.enqueueSitemap(ctx, locs, nested)
+			continue
+		}
+		fetched++
+
+		if next.depth < opts.MaxDepth && isHTML(page) {
+			for _, link := range pageLinks(page) {
+				c.enqueue(link, next.depth+1)
+			}
+		}
+
+		if err := visit(page); err != nil {
+			return err
+		}
+	}
+
+	slog.Debug("Crawl finished", "start", start, "pages", fetched, "unvisited", len(c.queue))
+	return nil
+}
+
+// fetch retrieves a page in full
+func (c *crawler) fetch(ctx context.Context, next queued) (Page, error) {
+	content, err := c.client.GetContent(ctx, next.url)
+	if err != nil {
+		return Page{}, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to read %q: %w", next.url, err)
+	}
+
+	return Page{URL: next.url, Depth: next.depth, ContentType: content.ContentType, Content: data}, nil
+}
+
+// enqueue adds a same-host URL to the queue unless it has been seen
+func (c *crawler) enqueue(rawURL string, depth int) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || strings.ToLower(u.Host) != c.host {
+		return
+	}
+	if skippedExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return
+	}
+
+	key := normalize(u)
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.queue = append(c.queue, queued{url: key, depth: depth})
+}
+
+// enqueueSitemap queues the pages of a sitemap at depth 1 and reads nested sitemaps in place
+func (c *crawler) enqueueSitemap(ctx context.Context, locs, nested []string) {
+	for _, loc := range locs {
+		c.enqueue(loc, 1)
+	}
+
+	for _, loc := range nested {
+		if c.sitemaps >= maxSitemaps || ctx.Err() != nil {
+			return
+		}
+		c.sitemaps++
+
+		page, err := c.fetch(ctx, queued{url: loc, depth: 1})
+		if err != nil {
+			slog.Debug("Skipping sitemap that failed to fetch", "url", loc, "error", err)
+			continue
+		}
+		if pages, more, ok := parseSitemap(page.Content); ok {
+			c.enqueueSitemap(ctx, pages, more)
+		}
+	}
+}
+
+// normalize returns a canonical form of u for deduplication: no fragment, lowercase host, and a non-empty path
+func normalize(u *url.URL) string {
+	normalized := *u
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.Host = strings.ToLower(normalized.Host)
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+	return normalized.String()
+}
+
+// isHTML reports whether a page is HTML, by its content type or, failing that, its content
+func isHTML(page Page) bool {
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(page.Content)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// pageLinks returns the absolute URLs of a page's links, resolved against its <base> or its URL
+func pageLinks(page Page) []string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.Content))
+	if err != nil {
+		return nil
+	}
+
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+
+	var links []string
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		if rel, _ := s.Attr("rel"); strings.Contains(strings.ToLower(rel), "nofollow") {
+			return
+		}
+		href, _ := s.Attr("href")
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		links = append(links, base.ResolveReference(ref).String())
+	})
+	return links
+}
+
+// sitemapDocument covers both <urlset> sitemaps and <sitemapindex> indexes
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// parseSitemap returns the page and nested sitemap URLs of a sitemap, or ok=false if content is not one
+func parseSitemap(content []byte) (pages, sitemaps []string, ok bool) {
+	trimmed := bytes.TrimSpace(content)
+	if !bytes.HasPrefix(trimmed, []byte("<?xml")) && !bytes.HasPrefix(trimmed, []byte("<urlset")) &&
+		!bytes.HasPrefix(trimmed, []byte("<sitemapindex")) {
+		return nil, nil, false
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(trimmed, &doc); err != nil {
+		return nil, nil, false
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, nil, false
+	}
+
+	for _, loc := range doc.URLs {
+		pages = append(pages, strings.TrimSpace(loc))
+	}
+	for _, loc := range doc.Sitemaps {
+		sitemaps = append(sitemaps, strings.TrimSpace(loc))
+	}
+	return pages, sitemaps, true
+}
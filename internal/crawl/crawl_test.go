@@ -0,0 +1,191 @@
+package crawl_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chriscorrea/sift/internal/crawl"
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// site serves linked HTML pages, a robots.txt, and sitemaps, recording requested paths
+type site struct {
+	mu        sync.Mutex
+	requested []string
+	times     []time.Time
+}
+
+func (s *site) handler(serverURL *string) http.Handler {
+	pages := map[string]string{
+		"/docs/":       `<a href="intro">Intro</a> <a href="/docs/api#auth">API</a> <a href="/private/x">Private</a> <a href="https://other.example/">Elsewhere</a> <a href="logo.png">Logo</a> <a href="/docs/intro">Intro again</a>`,
+		"/docs/intro":  `<p>Introduction</p> <a href="/docs/deep">Deeper</a>`,
+		"/docs/api":    `<p>API reference</p> <a href="/docs/" rel="nofollow">Home</a>`,
+		"/docs/deep":   `<p>Deep page</p> <a href="/docs/deeper">Deeper still</a>`,
+		"/docs/deeper": `<p>Too deep</p>`,
+		"/private/x":   `<p>Secret</p>`,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		if r.URL.Path != "/robots.txt" {
+			s.requested = append(s.requested, r.URL.Path)
+			s.times = append(s.times, time.Now())
+		}
+		s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>%s/sitemap-docs.xml</loc></sitemap></sitemapindex>`, *serverURL)
+		case "/sitemap-docs.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/docs/api</loc></url>
+  <url><loc>%[1]s/docs/deeper</loc></url>
+  <url><loc>%[1]s/private/x</loc></url>
+</urlset>`, *serverURL)
+		default:
+			page, ok := pages[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, "<html><body>%s</body></html>", page)
+		}
+	})
+}
+
+func TestCrawl(t *testing.T) {
+	tests := []struct {
+		name        string
+		start       string
+		opts        crawl.Options
+		expectPages []string
+	}{
+		{
+			name:        "follows same-site links breadth-first up to depth",
+			start:       "/docs/",
+			opts:        crawl.Options{MaxDepth: 2},
+			expectPages: []string{"/docs/", "/docs/intro", "/docs/api", "/docs/deep"},
+		},
+		{
+			name:        "depth 0 reads only the start page",
+			start:       "/docs/",
+			opts:        crawl.Options{MaxDepth: 0},
+			expectPages: []string{"/docs/"},
+		},
+		{
+			name:        "page budget",
+			start:       "/docs/",
+			opts:        crawl.Options{MaxDepth: 5, MaxPages: 2},
+			expectPages: []string{"/docs/", "/docs/intro"},
+		},
+		{
+			name:        "sitemap index lists pages",
+			start:       "/sitemap_index.xml",
+			opts:        crawl.Options{MaxDepth: 1},
+			expectPages: []string{"/docs/api", "/docs/deeper"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &site{}
+			var serverURL string
+			server := httptest.NewServer(s.handler(&serverURL))
+			defer server.Close()
+			serverURL = server.URL
+
+			client, err := fetch.NewClient(fetch.Options{})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			var visited []string
+			err = crawl.Crawl(context.Background(), client, server.URL+tt.start, tt.opts, func(page crawl.Page) error {
+				if !strings.Contains(string(page.Content), "<html>") {
+					t.Errorf("page %s has unexpected content %q", page.URL, page.Content)
+				}
+				visited = append(visited, strings.TrimPrefix(page.URL, server.URL))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			if strings.Join(visited, ",") != strings.Join(tt.expectPages, ",") {
+				t.Errorf("visited %v, want %v", visited, tt.expectPages)
+			}
+			for _, path := range s.requested {
+				if strings.HasPrefix(path, "/private/") {
+					t.Errorf("requested %s despite robots.txt", path)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawl_PerHostDelay(t *testing.T) {
+	s := &site{}
+	var serverURL string
+	server := httptest.NewServer(s.handler(&serverURL))
+	defer server.Close()
+	serverURL = server.URL
+
+	delay := 30 * time.Millisecond
+	client, err := fetch.NewClient(fetch.Options{HostDelay: delay})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	err = crawl.Crawl(context.Background(), client, server.URL+"/docs/", crawl.Options{MaxDepth: 1}, func(crawl.Page) error { return nil })
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	if len(s.times) < 3 {
+		t.Fatalf("expected at least 3 requests, got %d", len(s.times))
+	}
+	for i := 1; i < len(s.times); i++ {
+		// allow for timer granularity
+		if gap := s.times[i].Sub(s.times[i-1]); gap < delay-5*time.Millisecond {
+			t.Errorf("request %d followed the previous one after %v, want >= %v", i, gap, delay)
+		}
+	}
+}
+
+func TestCrawl_Errors(t *testing.T) {
+	s := &site{}
+	var serverURL string
+	server := httptest.NewServer(s.handler(&serverURL))
+	defer server.Close()
+	serverURL = server.URL
+
+	client, err := fetch.NewClient(fetch.Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	visit := func(crawl.Page) error { return nil }
+
+	if err := crawl.Crawl(context.Background(), client, server.URL+"/private/x", crawl.Options{}, visit); err == nil || !strings.Contains(err.Error(), "robots.txt") {
+		t.Errorf("Crawl() error = %v, want robots.txt error", err)
+	}
+	if err := crawl.Crawl(context.Background(), client, "docs/index.html", crawl.Options{}, visit); err == nil {
+		t.Error("Crawl() expected error for a non-HTTP start")
+	}
+
+	stop := fmt.Errorf("stop")
+	if err := crawl.Crawl(context.Background(), client, server.URL+"/docs/", crawl.Options{MaxDepth: 2}, func(crawl.Page) error { return stop }); err != stop {
+		t.Errorf("Crawl() error = %v, want the visit error", err)
+	}
+}
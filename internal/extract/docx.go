@@ -0,0 +1,383 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// docxHeadingStyle matches built-in heading style IDs when styles.xml does not describe them
+var docxHeadingStyle = regexp.MustCompile(`^(?i)heading\s*(\d)$`)
+
+// docxStyle is a paragraph style from styles.xml
+type docxStyle struct {
+	name       string
+	basedOn    string
+	outlineLvl int // outline level + 1, or 0 if unset
+}
+
+// DOCXToMarkdown extracts the body of a Word (.docx) document as Markdown.
+// Heading styles become Markdown headings, numbered and bulleted paragraphs become list items,
+// tables become pipe tables, and bold, italic, and hyperlinks are kept as inline Markdown.
+// maxBytes limits the decompressed size of each file read from the document (0 for no limit).
+func DOCXToMarkdown(content io.Reader, maxBytes int64) (string, error) {
+	archive, err := openZip(content, "DOCX")
+	if err != nil {
+		return "", err
+	}
+
+	document, err := readZipFile(archive, "word/document.xml", maxBytes)
+	if err != nil {
+		return "", err
+	}
+	if document == nil {
+		return "", fmt.Errorf("not a Word document: missing word/document.xml")
+	}
+
+	// styles, numbering, and relationships are optional
+	stylesXML, err := readZipFile(archive, "word/styles.xml", maxBytes)
+	if err != nil {
+		return "", err
+	}
+	numberingXML, err := readZipFile(archive, "word/numbering.xml", maxBytes)
+	if err != nil {
+		return "", err
+	}
+	relsXML, err := readZipFile(archive, "word/_rels/document.xml.rels", maxBytes)
+	if err != nil {
+		return "", err
+	}
+
+	parser := &docxParser{
+		headings:  docxHeadingLevels(stylesXML),
+		numbering: docxNumbering(numberingXML),
+		links:     docxRelationships(relsXML),
+	}
+	if err := parser.parse(document); err != nil {
+		return "", err
+	}
+
+	return renderOfficeBlocks(parser.blocks), nil
+}
+
+// docxParser walks word/document.xml, collecting blocks
+type docxParser struct {
+	headings  map[string]int          // paragraph style ID -> heading level
+	numbering map[string]map[int]bool // numbering ID -> level -> ordered
+	links     map[string]string       // relationship ID -> hyperlink target
+	blocks    []officeBlock
+	tables    officeTables
+
+	// current paragraph
+	inline     officeInline
+	style      string
+	numID      string
+	numLevel   int
+	outlineLvl int
+
+	// current run
+	bold, italic bool
+	link         string
+}
+
+// parse tokenizes the document body
+func (p *docxParser) parse(document []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	var inText, inRunProps, inParaProps bool
+	skipDepth := 0 // nesting depth inside content that is not rendered
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse Word document: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+
+			switch t.Name.Local {
+			case "Fallback", "delText", "instrText", "footnoteReference", "endnoteReference":
+				// alternate renderings, deleted text, and field codes would duplicate content
+				skipDepth = 1
+			case "p":
+				p.inline.reset()
+				p.style, p.numID, p.numLevel, p.outlineLvl = "", "", 0, 0
+			case "pPr":
+				inParaProps = true
+			case "pStyle":
+				if inParaProps {
+					p.style = xmlAttr(t, "val")
+				}
+			case "numId":
+				if inParaProps {
+					p.numID = xmlAttr(t, "val")
+				}
+			case "ilvl":
+				if inParaProps {
+					p.numLevel, _ = strconv.Atoi(xmlAttr(t, "val"))
+				}
+			case "outlineLvl":
+				if inParaProps {
+					if level, err := strconv.Atoi(xmlAttr(t, "val")); err == nil {
+						p.outlineLvl = level + 1
+					}
+				}
+			case "r":
+				p.bold, p.italic = false, false
+			case "rPr":
+				inRunProps = !inParaProps
+			case "b":
+				if inRunProps {
+					p.bold = docxOn(t)
+				}
+			case "i":
+				if inRunProps {
+					p.italic = docxOn(t)
+				}
+			case "hyperlink":
+				p.link = p.links[xmlAttr(t, "id")]
+			case "t":
+				inText = true
+			case "tab", "br", "cr":
+				if !inParaProps && !inRunProps {
+					p.inline.add(" ", p.bold, p.italic, p.link)
+				}
+			case "tbl":
+				p.tables.start()
+			case "tr":
+				p.tables.startRow()
+			case "tc":
+				p.tables.startCell()
+			}
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+
+			switch t.Name.Local {
+			case "pPr":
+				inParaProps = false
+			case "rPr":
+				inRunProps = false
+			case "t":
+				inText = false
+			case "hyperlink":
+				p.link = ""
+			case "p":
+				p.endParagraph()
+			case "tc":
+				p.tables.endCell()
+			case "tr":
+				p.tables.endRow()
+			case "tbl":
+				if rows := p.tables.end(); rows != nil {
+					p.blocks = append(p.blocks, officeBlock{kind: officeTable, rows: rows})
+				}
+			}
+
+		case xml.CharData:
+			if inText && skipDepth == 0 {
+				p.inline.add(string(t), p.bold, p.italic, p.link)
+			}
+		}
+	}
+}
+
+// endParagraph turns the current paragraph into a block (or a table cell paragraph)
+func (p *docxParser) endParagraph() {
+	text := p.inline.markdown()
+	p.inline.reset()
+	if text == "" {
+		return
+	}
+
+	if p.tables.addParagraph(text) {
+		return
+	}
+
+	level := p.headings[p.style]
+	if level == 0 {
+		if m := docxHeadingStyle.FindStringSubmatch(p.style); m != nil {
+			level, _ = strconv.Atoi(m[1])
+		} else if p.outlineLvl > 0 && p.outlineLvl <= 6 {
+			level = p.outlineLvl
+		}
+	}
+
+	switch {
+	case level > 0:
+		p.blocks = append(p.blocks, officeBlock{kind: officeHeading, level: level, text: text})
+	case p.numID != "" && p.numID != "0":
+		p.blocks = append(p.blocks, officeBlock{
+			kind:    officeListItem,
+			level:   p.numLevel,
+			ordered: p.numbering[p.numID][p.numLevel],
+			text:    text,
+		})
+	default:
+		p.blocks = append(p.blocks, officeBlock{kind: officeParagraph, text: text})
+	}
+}
+
+// docxOn reports whether a toggle property such as <w:b/> is switched on
+func docxOn(t xml.StartElement) bool {
+	switch strings.ToLower(xmlAttr(t, "val")) {
+	case "0", "false", "off", "none":
+		return false
+	default:
+		return true
+	}
+}
+
+// docxHeadingLevels maps paragraph style IDs to heading levels using style names, outline levels, and inheritance
+func docxHeadingLevels(stylesXML []byte) map[string]int {
+	styles := make(map[string]*docxStyle)
+	if stylesXML != nil {
+		decoder := xml.NewDecoder(bytes.NewReader(stylesXML))
+		var current *docxStyle
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				break // styles are best effort
+			}
+			t, ok := token.(xml.StartElement)
+			if !ok {
+				continue
+			}
+			switch t.Name.Local {
+			case "style":
+				current = &docxStyle{}
+				styles[xmlAttr(t, "styleId")] = current
+			case "name":
+				if current != nil {
+					current.name = strings.ToLower(xmlAttr(t, "val"))
+				}
+			case "basedOn":
+				if current != nil {
+					current.basedOn = xmlAttr(t, "val")
+				}
+			case "outlineLvl":
+				if current != nil {
+					if level, err := strconv.Atoi(xmlAttr(t, "val")); err == nil {
+						current.outlineLvl = level + 1
+					}
+				}
+			}
+		}
+	}
+
+	levels := make(map[string]int)
+	for id := range styles {
+		// follow basedOn chains, bounded in case of cycles
+		style := styles[id]
+		for hops := 0; style != nil && hops < 10; hops++ {
+			if m := docxHeadingStyle.FindStringSubmatch(style.name); m != nil {
+				levels[id], _ = strconv.Atoi(m[1])
+				break
+			}
+			if style.name == "title" {
+				levels[id] = 1
+				break
+			}
+			if style.outlineLvl > 0 && style.outlineLvl <= 6 {
+				levels[id] = style.outlineLvl
+				break
+			}
+			style = styles[style.basedOn]
+		}
+	}
+
+	return levels
+}
+
+// docxNumbering maps numbering IDs and levels to whether they are ordered (numbered) lists
+func docxNumbering(numberingXML []byte) map[string]map[int]bool {
+	abstract := make(map[string]map[int]bool) // abstract numbering ID -> level -> ordered
+	numToAbstract := make(map[string]string)
+
+	if numberingXML != nil {
+		decoder := xml.NewDecoder(bytes.NewReader(numberingXML))
+		var abstractID, numID string
+		level := 0
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				break // numbering is best effort; unknown lists render as bullets
+			}
+			t, ok := token.(xml.StartElement)
+			if !ok {
+				continue
+			}
+			switch t.Name.Local {
+			case "abstractNum":
+				abstractID, numID = xmlAttr(t, "abstractNumId"), ""
+				abstract[abstractID] = make(map[int]bool)
+			case "lvl":
+				level, _ = strconv.Atoi(xmlAttr(t, "ilvl"))
+			case "numFmt":
+				if levels := abstract[abstractID]; levels != nil && numID == "" {
+					format := xmlAttr(t, "val")
+					levels[level] = format != "bullet" && format != "none"
+				}
+			case "num":
+				numID = xmlAttr(t, "numId")
+			case "abstractNumId":
+				if numID != "" {
+					numToAbstract[numID] = xmlAttr(t, "val")
+				}
+			}
+		}
+	}
+
+	numbering := make(map[string]map[int]bool)
+	for numID, abstractID := range numToAbstract {
+		numbering[numID] = abstract[abstractID]
+	}
+	return numbering
+}
+
+// docxRelationships maps relationship IDs to targets (used for hyperlinks)
+func docxRelationships(relsXML []byte) map[string]string {
+	links := make(map[string]string)
+	if relsXML == nil {
+		return links
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(relsXML))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if t, ok := token.(xml.StartElement); ok && t.Name.Local == "Relationship" {
+			if strings.HasSuffix(xmlAttr(t, "Type"), "/hyperlink") {
+				links[xmlAttr(t, "Id")] = xmlAttr(t, "Target")
+			}
+		}
+	}
+
+	return links
+}
+
+// xmlAttr returns the value of the attribute with the given local name, ignoring namespaces
+func xmlAttr(t xml.StartElement, local string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
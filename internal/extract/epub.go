@@ -0,0 +1,267 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Chapter is a section of a multi-part document, such as an EPUB chapter
+type Chapter struct {
+	Title    string // chapter title from the table of contents (or its first heading); may be empty
+	Markdown string // chapter content, starting with a heading for the title
+}
+
+// leadingHeading matches a Markdown ATX heading on the first line of a chapter
+var leadingHeading = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*\s*(?:\n|$)`)
+
+// epubContainer is META-INF/container.xml, which locates the package (OPF) document
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the subset of the OPF package document needed to read chapters in order
+type epubPackage struct {
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// EPUBToChapters reads an EPUB book in spine (reading) order and converts each chapter to Markdown.
+// Chapter XHTML is converted with ToMarkdown; without a selector the whole chapter body is kept, since
+// chapter files carry no site boilerplate for readability to remove. Chapter titles come from the
+// EPUB 3 navigation document or the EPUB 2 NCX table of contents, falling back to the chapter's
+// first heading; each chapter's Markdown starts with its title as a heading.
+// Non-linear spine items (such as covers) and chapters without text are skipped. maxBytes limits
+// the decompressed size of each file read from the book (0 for no limit).
+func EPUBToChapters(content io.Reader, selector string, maxBytes int64) ([]Chapter, error) {
+	archive, err := openZip(content, "EPUB")
+	if err != nil {
+		return nil, err
+	}
+
+	containerXML, err := readZipFile(archive, "META-INF/container.xml", maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if containerXML == nil {
+		return nil, fmt.Errorf("not an EPUB: missing META-INF/container.xml")
+	}
+
+	var container epubContainer
+	if err := xml.Unmarshal(containerXML, &container); err != nil || len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("invalid EPUB container: no package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	opfXML, err := readZipFile(archive, opfPath, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if opfXML == nil {
+		return nil, fmt.Errorf("invalid EPUB: missing package document %q", opfPath)
+	}
+
+	var pkg epubPackage
+	if err := xml.Unmarshal(opfXML, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid EPUB package document: %w", err)
+	}
+
+	// manifest hrefs are relative to the package document
+	baseDir := path.Dir(opfPath)
+	items := make(map[string]string) // manifest ID -> archive path
+	var navPath, ncxPath string
+	for _, item := range pkg.Manifest {
+		itemPath := resolveEPUBPath(baseDir, item.Href)
+		items[item.ID] = itemPath
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			navPath = itemPath
+		}
+		if item.ID == pkg.Spine.Toc || (ncxPath == "" && item.MediaType == "application/x-dtbncx+xml") {
+			ncxPath = itemPath
+		}
+	}
+
+	titles := epubTitles(archive, navPath, ncxPath, maxBytes)
+
+	var chapters []Chapter
+	for _, ref := range pkg.Spine.Itemrefs {
+		if ref.Linear == "no" {
+			continue
+		}
+		itemPath, ok := items[ref.IDRef]
+		if !ok {
+			continue
+		}
+
+		chapterXML, err := readZipFile(archive, itemPath, maxBytes)
+		if err != nil || chapterXML == nil {
+			slog.Debug("Skipping unreadable EPUB chapter", "path", itemPath, "error", err)
+			continue
+		}
+
+		chapterSelector := selector
+		if chapterSelector == "" {
+			chapterSelector = "body" // skip the <head>, whose <title> would otherwise be converted as text
+		}
+		markdown, err := ToMarkdown(bytes.NewReader(chapterXML), chapterSelector, true, nil)
+		if err != nil || strings.TrimSpace(markdown) == "" {
+			slog.Debug("Skipping EPUB chapter without content", "path", itemPath, "error", err)
+			continue
+		}
+		markdown = strings.TrimSpace(markdown)
+
+		title := titles[itemPath]
+		if m := leadingHeading.FindStringSubmatch(markdown); m != nil && title == "" {
+			title = m[1]
+		}
+		markdown = WithTitleHeading(markdown, title)
+
+		chapters = append(chapters, Chapter{Title: title, Markdown: markdown})
+	}
+
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no chapters found in EPUB")
+	}
+
+	return chapters, nil
+}
+
+// WithTitleHeading prepends title to markdown as a top-level heading, unless markdown already
+// starts with a heading or title is empty
+func WithTitleHeading(markdown, title string) string {
+	if title == "" || leadingHeading.MatchString(markdown) {
+		return markdown
+	}
+	return "# " + title + "\n\n" + markdown
+}
+
+// resolveEPUBPath resolves a (URL-encoded) href against a directory in the archive, dropping any fragment
+func resolveEPUBPath(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(dir, href)
+}
+
+// epubTitles maps chapter paths to their titles in the table of contents, preferring the EPUB 3 navigation document
+func epubTitles(archive *zip.Reader, navPath, ncxPath string, maxBytes int64) map[string]string {
+	titles := make(map[string]string)
+
+	if navPath != "" {
+		if data, err := readZipFile(archive, navPath, maxBytes); err == nil && data != nil {
+			collectNavTitles(data, path.Dir(navPath), titles)
+		}
+	}
+	if len(titles) == 0 && ncxPath != "" {
+		if data, err := readZipFile(archive, ncxPath, maxBytes); err == nil && data != nil {
+			collectNCXTitles(data, path.Dir(ncxPath), titles)
+		}
+	}
+
+	return titles
+}
+
+// collectNavTitles reads link text from the table of contents of an EPUB 3 navigation document
+func collectNavTitles(data []byte, dir string, titles map[string]string) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	navDepth := 0 // depth inside the toc <nav>, 0 outside
+	var href string
+	var text strings.Builder
+	inLink := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return // titles are best effort
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if navDepth > 0 {
+				navDepth++
+			} else if t.Name.Local == "nav" && xmlAttr(t, "type") == "toc" {
+				navDepth = 1
+			}
+			if navDepth > 0 && t.Name.Local == "a" {
+				inLink, href = true, xmlAttr(t, "href")
+				text.Reset()
+			}
+		case xml.EndElement:
+			if navDepth > 0 && t.Name.Local == "a" && inLink {
+				inLink = false
+				target := resolveEPUBPath(dir, href)
+				if title := strings.Join(strings.Fields(text.String()), " "); title != "" && titles[target] == "" {
+					titles[target] = title
+				}
+			}
+			if navDepth > 0 {
+				navDepth--
+			}
+		case xml.CharData:
+			if inLink {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// collectNCXTitles reads navigation point labels from an EPUB 2 NCX table of contents
+func collectNCXTitles(data []byte, dir string, titles map[string]string) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var label strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return // titles are best effort
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "navLabel":
+				label.Reset()
+			case "text":
+				inText = true
+			case "content":
+				target := resolveEPUBPath(dir, xmlAttr(t, "src"))
+				if title := strings.Join(strings.Fields(label.String()), " "); title != "" && titles[target] == "" {
+					titles[target] = title
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "text" {
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				label.Write(t)
+			}
+		}
+	}
+}
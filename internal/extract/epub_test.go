@@ -0,0 +1,129 @@
+package extract_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+const epubContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+
+// epubChapter wraps body HTML in a minimal XHTML chapter
+func epubChapter(title, body string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>` + title + `</title></head><body>` + body + `</body></html>`
+}
+
+func TestEPUBToChapters(t *testing.T) {
+	chapters := [][2]string{
+		{"OEBPS/text/chapter%201.xhtml", epubChapter("One", `<h1>Chapter I. Loomings</h1><p>Call me Ishmael.</p>`)},
+		{"OEBPS/text/chapter2.xhtml", epubChapter("Two", `<p>I stuffed a shirt or two into my old carpet-bag.</p>`)},
+	}
+
+	tests := []struct {
+		name     string
+		opf      string
+		toc      [2]string
+		expected []extract.Chapter
+	}{
+		{
+			name: "EPUB 3 navigation document",
+			opf: `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="cover" linear="no"/>
+    <itemref idref="c1"/>
+    <itemref idref="c2"/>
+  </spine>
+</package>`,
+			toc: [2]string{"OEBPS/nav.xhtml", `<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="landmarks"><ol><li><a href="text/chapter2.xhtml">Wrong Title</a></li></ol></nav>
+<nav epub:type="toc"><ol>
+  <li><a href="text/chapter%201.xhtml">Loomings</a></li>
+  <li><a href="text/chapter2.xhtml#start">The   Carpet-Bag</a></li>
+</ol></nav></body></html>`},
+			expected: []extract.Chapter{
+				{Title: "Loomings", Markdown: "# Chapter I. Loomings\n\nCall me Ishmael."},
+				{Title: "The Carpet-Bag", Markdown: "# The Carpet-Bag\n\nI stuffed a shirt or two into my old carpet-bag."},
+			},
+		},
+		{
+			name: "EPUB 2 NCX",
+			opf: `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <manifest>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx"><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+			toc: [2]string{"OEBPS/toc.ncx", `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><navMap>
+  <navPoint id="n1"><navLabel><text>Chapter 2. The Carpet-Bag</text></navLabel><content src="text/chapter2.xhtml"/></navPoint>
+</navMap></ncx>`},
+			expected: []extract.Chapter{
+				{Title: "Chapter I. Loomings", Markdown: "# Chapter I. Loomings\n\nCall me Ishmael."},
+				{Title: "Chapter 2. The Carpet-Bag", Markdown: "# Chapter 2. The Carpet-Bag\n\nI stuffed a shirt or two into my old carpet-bag."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := [][2]string{
+				{"mimetype", "application/epub+zip"},
+				{"META-INF/container.xml", epubContainer},
+				{"OEBPS/content.opf", tt.opf},
+				tt.toc,
+				{"OEBPS/cover.xhtml", epubChapter("Cover", `<p>Cover page</p>`)},
+				{"OEBPS/text/chapter 1.xhtml", chapters[0][1]},
+				{"OEBPS/text/chapter2.xhtml", chapters[1][1]},
+			}
+			book := buildZip(t, files...)
+
+			format, _ := extract.DetectFormat(bytes.NewReader(book), "", "-")
+			if format != extract.FormatEPUB {
+				t.Errorf("DetectFormat() = %v, want %v", format, extract.FormatEPUB)
+			}
+
+			result, err := extract.EPUBToChapters(bytes.NewReader(book), "", 0)
+			if err != nil {
+				t.Fatalf("EPUBToChapters() error = %v", err)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("EPUBToChapters() returned %d chapters, want %d: %+v", len(result), len(tt.expected), result)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("chapter %d = %+v, want %+v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEPUBToChaptersErrors(t *testing.T) {
+	_, err := extract.EPUBToChapters(bytes.NewReader(buildZip(t, [2]string{"mimetype", "application/epub+zip"})), "", 0)
+	if err == nil || !strings.Contains(err.Error(), "container.xml") {
+		t.Errorf("EPUBToChapters() error = %v, want missing container error", err)
+	}
+
+	bomb := buildZip(t, [2]string{"META-INF/container.xml", "<container>" + strings.Repeat(" ", 1<<20) + "</container>"})
+	_, err = extract.EPUBToChapters(bytes.NewReader(bomb), "", 64*1024)
+	if err == nil || !strings.Contains(err.Error(), "META-INF/container.xml decompresses to more than the 65536-byte limit") {
+		t.Errorf("EPUBToChapters() error = %v, want decompressed size limit error", err)
+	}
+}
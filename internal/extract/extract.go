@@ -3,16 +3,32 @@
 package extract
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/url"
 	"strings"
+	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
 	"github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
 )
 
+// Article is the Markdown content extracted from an HTML page, along with the article metadata
+// readability finds in it. Metadata is only set for main content extraction.
+type Article struct {
+	Markdown  string
+	Title     string
+	Byline    string    // author credit, as written on the page
+	Published time.Time // zero if unknown
+	SiteName  string
+	Language  string
+	Excerpt   string // summary from the page's description metadata or its first paragraph
+}
+
 // ToMarkdown extracts the main content from HTML and converts it to Markdown.
 // Optional CSS selector filtering is supported.
 //
@@ -24,35 +40,107 @@ import (
 //
 // Returns clean Markdown string or error if extraction/conversion fails.
 func ToMarkdown(content io.Reader, selector string, includeAll bool, baseURL *url.URL) (string, error) {
+	article, err := ToArticle(content, selector, nil, includeAll, baseURL)
+	return article.Markdown, err
+}
+
+// ToArticle works like ToMarkdown but also returns the article metadata found by readability.
+// Elements matching any of the exclude selectors are removed first, whatever the extraction mode.
+func ToArticle(content io.Reader, selector string, exclude []string, includeAll bool, baseURL *url.URL) (Article, error) {
+	if len(exclude) > 0 {
+		var err error
+		if content, err = removeExcluded(content, exclude); err != nil {
+			return Article{}, err
+		}
+	}
+
 	// if selector is specified, use it (override includeAll setting)
 	if selector != "" {
-		return extractWithSelector(content, selector)
+		markdown, err := extractWithSelector(content, selector)
+		return Article{Markdown: markdown}, err
 	}
 
 	// if includeAll is true, convert entire HTML without readability filtering
 	if includeAll {
-		return convertAllHTML(content)
+		markdown, err := convertAllHTML(content)
+		return Article{Markdown: markdown}, err
 	}
 
 	// default: use go-readability to extract main content
 	return extractMainContent(content, baseURL)
 }
 
-// extractMainContent uses go-readability to extract the main article content
-func extractMainContent(content io.Reader, baseURL *url.URL) (string, error) {
+// extractMainContent uses go-readability to extract the main article content and its metadata
+func extractMainContent(content io.Reader, baseURL *url.URL) (Article, error) {
 	// use empty URL if none provided
 	if baseURL == nil {
 		baseURL = &url.URL{}
 	}
 
-	// parse with go-readability to extract main content directly from reader
-	article, err := readability.FromReader(content, baseURL)
+	// content is already UTF-8 (fetch transcodes it), so it is parsed directly rather than with
+	// readability.FromReader, whose charset sniffing could decode it a second time
+	doc, err := html.Parse(content)
 	if err != nil {
-		return "", fmt.Errorf("failed to extract main content: %w", err)
+		return Article{}, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	// extract main content with go-readability
+	article, err := readability.FromDocument(doc, baseURL)
+	if err != nil {
+		return Article{}, fmt.Errorf("failed to extract main content: %w", err)
 	}
 
 	// convert extracted HTML to Markdown
-	return convertToMarkdown(article.Content)
+	markdown, err := convertToMarkdown(article.Content)
+	if err != nil {
+		return Article{}, err
+	}
+
+	extracted := Article{
+		Markdown: markdown,
+		Title:    strings.TrimSpace(article.Title),
+		Byline:   strings.TrimSpace(article.Byline),
+		SiteName: strings.TrimSpace(article.SiteName),
+		Language: strings.TrimSpace(article.Language),
+		Excerpt:  strings.TrimSpace(article.Excerpt),
+	}
+	if article.PublishedTime != nil {
+		extracted.Published = *article.PublishedTime
+	}
+
+	return extracted, nil
+}
+
+// CheckSelectors reports the first of the CSS selectors that cannot be parsed
+func CheckSelectors(selectors []string) error {
+	for _, selector := range selectors {
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return fmt.Errorf("invalid CSS selector %q: %w", selector, err)
+		}
+	}
+	return nil
+}
+
+// removeExcluded removes the elements matching any of the exclude selectors from an HTML page,
+// returning the remaining page
+func removeExcluded(content io.Reader, exclude []string) (io.Reader, error) {
+	if err := CheckSelectors(exclude); err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	for _, selector := range exclude {
+		doc.Find(selector).Remove()
+	}
+
+	var page bytes.Buffer
+	if err := html.Render(&page, doc.Get(0)); err != nil {
+		return nil, fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return &page, nil
 }
 
 // extractWithSelector uses a CSS selector to extract specific content
@@ -117,6 +205,7 @@ func convertToMarkdown(htmlString string) (string, error) {
 			},
 		},
 	)
+	converter.AddRules(tableRules()...)
 
 	// convert HTML to Markdown
 	markdown, err := converter.ConvertString(htmlString)
@@ -3,6 +3,7 @@ package extract_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/chriscorrea/sift/internal/extract"
 )
@@ -431,3 +432,211 @@ func TestToMarkdownLineBreaks(t *testing.T) {
 		})
 	}
 }
+
+func TestToMarkdownTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "header row of th cells",
+			html:     `<table><tr><th>Flour</th><th>Grams</th></tr><tr><td>Bread flour</td><td>500</td></tr><tr><td>Rye</td><td>100</td></tr></table>`,
+			expected: "| Flour | Grams |\n| --- | --- |\n| Bread flour | 500 |\n| Rye | 100 |",
+		},
+		{
+			name: "merged header rows and spans",
+			html: `<table><caption>Bake log</caption><thead>` +
+				`<tr><th rowspan="2">Loaf</th><th colspan="2">Oven</th></tr><tr><th>Minutes</th><th>Degrees</th></tr></thead>` +
+				`<tbody><tr><td rowspan="2">Sourdough</td><td>45</td><td>230</td></tr><tr><td colspan="2">rested overnight</td></tr>` +
+				`<tr><td>Rye</td><td>60</td><td>210</td></tr></tbody></table>`,
+			expected: "Bake log\n\n| Loaf | Oven Minutes | Oven Degrees |\n| --- | --- | --- |\n" +
+				"| Sourdough | 45 | 230 |\n| Sourdough | rested overnight |  |\n| Rye | 60 | 210 |",
+		},
+		{
+			name:     "rowspan to the end of the table",
+			html:     `<table><tr><td rowspan="0">Proof</td><td>1 hour</td></tr><tr><td>2 hours</td></tr></table>`,
+			expected: "|  |  |\n| --- | --- |\n| Proof | 1 hour |\n| Proof | 2 hours |",
+		},
+		{
+			name:     "inline formatting and pipes in cells",
+			html:     `<table><tr><th>Step</th><th>Note</th></tr><tr><td><strong>Fold</strong></td><td>left | right<br>then <a href="https://example.com/fold">rest</a></td></tr></table>`,
+			expected: "| Step | Note |\n| --- | --- |\n| **Fold** | left \\| right then [rest](https://example.com/fold) |",
+		},
+		{
+			name:     "ragged rows",
+			html:     `<table><tr><th>A</th><th>B</th></tr><tr><td>1</td></tr><tr><td>2</td><td>3</td><td>4</td></tr></table>`,
+			expected: "| A | B |  |\n| --- | --- | --- |\n| 1 |  |  |\n| 2 | 3 | 4 |",
+		},
+		{
+			name:     "nested table is flattened into its cell",
+			html:     `<table><tr><th>Loaf</th><th>Flours</th></tr><tr><td>Miche</td><td><table><tr><td>wheat</td><td>spelt</td></tr></table></td></tr></table>`,
+			expected: "| Loaf | Flours |\n| --- | --- |\n| Miche | wheat spelt |",
+		},
+		{
+			name:     "layout table keeps its blocks",
+			html:     `<table><tr><td><h2>Method</h2><p>Mix the dough.</p></td></tr><tr><td><p>Bake it.</p></td></tr></table>`,
+			expected: "## Method\n\nMix the dough.\n\nBake it.",
+		},
+		{
+			name:     "presentational table keeps its blocks",
+			html:     `<table role="presentation"><tr><td><p>Left column.</p></td><td><p>Right column.</p></td></tr></table>`,
+			expected: "Left column.\n\nRight column.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := extract.ToMarkdown(strings.NewReader(tt.html), "", true, nil)
+			if err != nil {
+				t.Fatalf("ToMarkdown() unexpected error: %v", err)
+			}
+			if result = strings.TrimSpace(result); result != tt.expected {
+				t.Errorf("ToMarkdown() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestToArticle(t *testing.T) {
+	articleHTML := `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <title>Proofing Sourdough | The Bakehouse</title>
+    <meta property="og:title" content="Proofing Sourdough">
+    <meta property="og:site_name" content="The Bakehouse">
+    <meta name="author" content="Ada Crumb">
+    <meta name="description" content="How long to proof a sourdough loaf.">
+    <meta property="article:published_time" content="2024-03-05T08:30:00Z">
+</head>
+<body>
+    <nav><a href="/">Home</a> <a href="/recipes">Recipes</a></nav>
+    <article>
+        <h1>Proofing Sourdough</h1>
+        <p>A sourdough loaf proofs slowly because wild yeast is less vigorous than commercial yeast, so the bulk ferment takes several hours at room temperature.</p>
+        <p>Watch the dough rather than the clock: it should grow by about half, feel airy, and spring back slowly when pressed with a floured finger.</p>
+        <p>A cold proof in the refrigerator overnight deepens the flavor and makes the loaf easier to score before baking.</p>
+    </article>
+</body>
+</html>`
+
+	tests := []struct {
+		name       string
+		selector   string
+		includeAll bool
+		expected   extract.Article
+	}{
+		{
+			name: "readability finds metadata",
+			expected: extract.Article{
+				Title:     "Proofing Sourdough",
+				Byline:    "Ada Crumb",
+				Published: time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC),
+				SiteName:  "The Bakehouse",
+				Language:  "en",
+				Excerpt:   "How long to proof a sourdough loaf.",
+			},
+		},
+		{
+			name:     "selector extraction has no metadata",
+			selector: "article",
+		},
+		{
+			name:       "include all has no metadata",
+			includeAll: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article, err := extract.ToArticle(strings.NewReader(articleHTML), tt.selector, nil, tt.includeAll, nil)
+			if err != nil {
+				t.Fatalf("ToArticle() unexpected error: %v", err)
+			}
+
+			if !strings.Contains(article.Markdown, "wild yeast") {
+				t.Errorf("ToArticle() Markdown = %q, want the article text", article.Markdown)
+			}
+
+			article.Markdown = ""
+			if !article.Published.Equal(tt.expected.Published) {
+				t.Errorf("ToArticle() Published = %v, want %v", article.Published, tt.expected.Published)
+			}
+			article.Published, tt.expected.Published = time.Time{}, time.Time{}
+			if article != tt.expected {
+				t.Errorf("ToArticle() metadata = %+v, want %+v", article, tt.expected)
+			}
+		})
+	}
+}
+
+func TestToArticleExclude(t *testing.T) {
+	page := `<html><body>
+    <nav><a href="/">Home</a> <a href="/shop">Shop</a></nav>
+    <article>
+        <h1>Laminating Croissant Dough</h1>
+        <p>Fold the butter block into the dough and roll it out evenly, keeping everything cold so the layers stay distinct through three letter folds.</p>
+        <figure class="promo"><p>Buy our premium rolling pin today and save twenty percent on every order.</p></figure>
+        <p>Rest the dough in the refrigerator for thirty minutes between folds so the gluten relaxes and the butter firms up again.</p>
+        <div class="comments"><p>First! Great recipe, my croissants came out flaky and buttery every time.</p></div>
+    </article>
+</body></html>`
+
+	tests := []struct {
+		name       string
+		selector   string
+		includeAll bool
+		exclude    []string
+		excluded   []string
+		wantErr    bool
+	}{
+		{
+			name:     "readability",
+			exclude:  []string{"figure.promo", ".comments"},
+			excluded: []string{"rolling pin", "First!"},
+		},
+		{
+			name:     "selector",
+			selector: "article",
+			exclude:  []string{"figure.promo, .comments"},
+			excluded: []string{"rolling pin", "First!"},
+		},
+		{
+			name:       "include all",
+			includeAll: true,
+			exclude:    []string{"nav", ".promo", ".comments"},
+			excluded:   []string{"Shop", "rolling pin", "First!"},
+		},
+		{
+			name:    "invalid selector",
+			exclude: []string{"figure[class"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article, err := extract.ToArticle(strings.NewReader(page), tt.selector, tt.exclude, tt.includeAll, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ToArticle() expected an error, got %q", article.Markdown)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToArticle() unexpected error: %v", err)
+			}
+
+			for _, kept := range []string{"Fold the butter block", "Rest the dough"} {
+				if !strings.Contains(article.Markdown, kept) {
+					t.Errorf("ToArticle() = %q, want it to contain %q", article.Markdown, kept)
+				}
+			}
+			for _, excluded := range tt.excluded {
+				if strings.Contains(article.Markdown, excluded) {
+					t.Errorf("ToArticle() = %q, want %q excluded", article.Markdown, excluded)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Format identifies the input format of a source
+type Format int
+
+const (
+	FormatAuto     Format = iota // detect from content type, file extension, and content
+	FormatHTML                   // HTML, extracted with readability or a CSS selector
+	FormatMarkdown               // Markdown, passed through with light normalization
+	FormatText                   // plain text, passed through with light normalization
+	FormatPDF                    // PDF, converted to Markdown from its text layout
+	FormatDOCX                   // Word document, converted to Markdown from its structure
+	FormatODT                    // OpenDocument text, converted to Markdown from its structure
+	FormatEPUB                   // EPUB book, converted chapter by chapter in reading order
+	FormatFeed                   // RSS or Atom feed, expanded into one document per entry
+)
+
+// sniffLen is the number of leading bytes inspected when sniffing content, as in http.DetectContentType
+const sniffLen = 512
+
+// String returns the string representation of Format
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatHTML:
+		return "html"
+	case FormatMarkdown:
+		return "markdown"
+	case FormatText:
+		return "text"
+	case FormatPDF:
+		return "pdf"
+	case FormatDOCX:
+		return "docx"
+	case FormatODT:
+		return "odt"
+	case FormatEPUB:
+		return "epub"
+	case FormatFeed:
+		return "feed"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseFormat parses an input format name: auto, html, markdown (md), text (txt), pdf, docx, odt, epub, or feed (rss, atom)
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return FormatAuto, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "text", "txt":
+		return FormatText, nil
+	case "pdf":
+		return FormatPDF, nil
+	case "docx":
+		return FormatDOCX, nil
+	case "odt":
+		return FormatODT, nil
+	case "epub":
+		return FormatEPUB, nil
+	case "feed", "rss", "atom":
+		return FormatFeed, nil
+	default:
+		return FormatAuto, fmt.Errorf("unknown input format %q (expected auto, html, markdown, text, pdf, docx, odt, epub, or feed)", name)
+	}
+}
+
+// DetectFormat determines the format of content, checking in order:
+//   - the media type (e.g. an HTTP Content-Type header), unless it is generic
+//   - the file extension of name (a file path or URL)
+//   - the leading bytes of the content
+//
+// Content that cannot be identified is treated as HTML, matching the behavior for HTML sources.
+// Returns the detected format and a reader that yields the full content, including any sniffed bytes.
+func DetectFormat(content io.Reader, contentType, name string) (Format, io.Reader) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		return FormatHTML, content
+	case "text/markdown", "text/x-markdown":
+		return FormatMarkdown, content
+	case "application/pdf", "application/x-pdf":
+		return FormatPDF, content
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return FormatDOCX, content
+	case "application/vnd.oasis.opendocument.text":
+		return FormatODT, content
+	case "application/epub+zip":
+		return FormatEPUB, content
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml":
+		return FormatFeed, content
+	case "text/plain":
+		// Markdown is commonly served as text/plain
+		if formatFromExtension(name) == FormatMarkdown {
+			return FormatMarkdown, content
+		}
+		return FormatText, content
+	}
+
+	if format := formatFromExtension(name); format != FormatAuto {
+		return format, content
+	}
+
+	buffered := bufio.NewReaderSize(content, sniffLen)
+	head, _ := buffered.Peek(sniffLen) // short reads are expected for small content
+	return sniffFormat(head), buffered
+}
+
+// compressedExtensions are ignored when mapping extensions to formats
+var compressedExtensions = map[string]bool{".gz": true, ".zst": true, ".bz2": true}
+
+// formatFromExtension maps a file path or URL extension to a format, or FormatAuto if unrecognized
+func formatFromExtension(name string) Format {
+	if u, err := url.Parse(name); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		name = u.Path
+	}
+
+	// compressed files are decompressed when read, so "notes.md.gz" is Markdown
+	ext := strings.ToLower(path.Ext(name))
+	if compressedExtensions[ext] {
+		ext = strings.ToLower(path.Ext(strings.TrimSuffix(name, path.Ext(name))))
+	}
+
+	switch ext {
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".md", ".markdown", ".mdown", ".mkd":
+		return FormatMarkdown
+	case ".txt", ".text":
+		return FormatText
+	case ".pdf":
+		return FormatPDF
+	case ".docx":
+		return FormatDOCX
+	case ".odt":
+		return FormatODT
+	case ".epub":
+		return FormatEPUB
+	case ".rss", ".atom":
+		return FormatFeed
+	default:
+		return FormatAuto
+	}
+}
+
+// sniffFormat classifies leading content bytes; text that does not look like HTML is treated as Markdown,
+// since plain text passes through the Markdown path unchanged
+func sniffFormat(head []byte) Format {
+	detected := http.DetectContentType(head)
+	switch {
+	case strings.HasPrefix(detected, "text/html"):
+		return FormatHTML
+	case detected == "application/pdf":
+		return FormatPDF
+	case detected == "application/zip":
+		// ODT and EPUB store their media type uncompressed as the first entry; DOCX lists its parts in [Content_Types].xml
+		if bytes.Contains(head, []byte("mimetypeapplication/vnd.oasis.opendocument.text")) {
+			return FormatODT
+		}
+		if bytes.Contains(head, []byte("mimetypeapplication/epub+zip")) {
+			return FormatEPUB
+		}
+		if bytes.Contains(head, []byte("[Content_Types].xml")) || bytes.Contains(head, []byte("word/")) {
+			return FormatDOCX
+		}
+		return FormatHTML
+	case strings.HasPrefix(detected, "text/xml"):
+		// XHTML documents and feeds start with an XML declaration
+		if bytes.Contains(bytes.ToLower(head), []byte("<html")) {
+			return FormatHTML
+		}
+		if isFeed(head) {
+			return FormatFeed
+		}
+		return FormatText
+	case strings.HasPrefix(detected, "text/plain"):
+		// feeds without an XML declaration are not recognized as XML
+		if bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte("<")) && isFeed(head) {
+			return FormatFeed
+		}
+		return FormatMarkdown
+	default:
+		return FormatHTML
+	}
+}
+
+// feedRoots are the document elements of RSS 2.0, Atom, and RSS 1.0 feeds
+var feedRoots = [][]byte{[]byte("<rss"), []byte("<feed"), []byte("<rdf:RDF")}
+
+// isFeed reports whether XML content starts with a feed's document element
+func isFeed(head []byte) bool {
+	for _, root := range feedRoots {
+		if bytes.Contains(head, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeText prepares Markdown or plain text content for chunking without HTML extraction.
+// A leading byte order mark is removed, line endings are normalized to "\n", runs of blank lines
+// are collapsed, and leading and trailing blank lines are trimmed. Plain text also has trailing
+// whitespace removed from each line; Markdown keeps it, since two trailing spaces mark a line break.
+func NormalizeText(content io.Reader, format Format) (string, error) {
+	data, err := io.ReadAll(NewTextNormalizer(content, format))
+	if err != nil {
+		return "", fmt.Errorf("failed to read content: %w", err)
+	}
+
+	return strings.Trim(string(data), "\n"), nil
+}
+
+// maxLineBytes is the longest line normalized as a whole; longer lines pass through in pieces
+const maxLineBytes = 64 * 1024
+
+// textNormalizer applies NormalizeText line by line as content is read
+type textNormalizer struct {
+	src     *bufio.Reader
+	format  Format
+	out     []byte // normalized text not yet returned
+	blank   bool   // a blank line is held back until more text follows
+	started bool   // text has been written
+	midLine bool   // the start of an overlong line has been written
+	err     error
+}
+
+// NewTextNormalizer returns a reader that normalizes Markdown or plain text as NormalizeText does,
+// one line at a time, so that content of any size can be read with bounded memory. Unlike
+// NormalizeText, the final line keeps its line ending.
+func NewTextNormalizer(content io.Reader, format Format) io.Reader {
+	src := bufio.NewReaderSize(content, maxLineBytes)
+	if bom, _ := src.Peek(3); bytes.Equal(bom, []byte("\uFEFF")) {
+		src.Discard(len(bom))
+	}
+	return &textNormalizer{src: src, format: format}
+}
+
+func (n *textNormalizer) Read(p []byte) (int, error) {
+	for len(n.out) == 0 {
+		if n.err != nil {
+			return 0, n.err
+		}
+		fragment, err := n.src.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// an overlong line is written as it is, without trimming
+			n.writeBlank()
+			n.out = append(n.out, fragment...)
+			n.started, n.midLine = true, true
+			continue
+		}
+		if len(fragment) > 0 {
+			n.writeLines(string(fragment))
+		}
+		n.err = err
+	}
+
+	copied := copy(p, n.out)
+	n.out = n.out[copied:]
+	return copied, nil
+}
+
+// writeLines normalizes a line read in full; a lone "\r" ends a line of its own
+func (n *textNormalizer) writeLines(text string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if n.format == FormatText || strings.TrimSpace(line) == "" {
+			line = strings.TrimRight(line, " \t")
+		}
+
+		if n.midLine {
+			// the end of an overlong line
+			n.midLine = false
+		} else if line == "" {
+			n.blank = n.started
+			continue
+		}
+
+		n.writeBlank()
+		n.out = append(n.out, line...)
+		n.out = append(n.out, '\n')
+		n.started = true
+	}
+}
+
+// writeBlank writes a held-back blank line, collapsing a run of them into one
+func (n *textNormalizer) writeBlank() {
+	if n.blank && !n.midLine {
+		n.out = append(n.out, '\n')
+	}
+	n.blank = false
+}
@@ -0,0 +1,174 @@
+package extract
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		source      string
+		content     string
+		expected    Format
+	}{
+		{"HTML content type", "text/html; charset=utf-8", "https://example.com/recipe", "# not markdown", FormatHTML},
+		{"XHTML content type", "application/xhtml+xml", "https://example.com/recipe", "", FormatHTML},
+		{"Markdown content type", "text/markdown", "https://example.com/recipe", "<p>ignored</p>", FormatMarkdown},
+		{"text/plain with Markdown extension", "text/plain", "https://example.com/README.md", "# Recipe", FormatMarkdown},
+		{"text/plain without extension", "text/plain", "https://example.com/recipe", "Sift the flour.", FormatText},
+		{"generic content type falls back to extension", "application/octet-stream", "https://example.com/notes.md?raw=1", "# Recipe", FormatMarkdown},
+		{"Markdown file", "", "notes/cake.markdown", "<div>inline html</div>", FormatMarkdown},
+		{"text file", "", "notes/cake.TXT", "Sift the flour.", FormatText},
+		{"HTML file", "", "page.htm", "plain words", FormatHTML},
+		{"compressed Markdown file", "", "notes/cake.md.gz", "<div>inline html</div>", FormatMarkdown},
+		{"sniffed HTML", "", "-", "\n  <!DOCTYPE html><html><body><p>Cake</p></body></html>", FormatHTML},
+		{"sniffed XHTML", "", "-", `<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"></html>`, FormatHTML},
+		{"sniffed text", "", "-", "# Carrot Cake\n\nSift the flour.", FormatMarkdown},
+		{"PDF content type", "application/pdf", "https://example.com/paper", "", FormatPDF},
+		{"PDF file", "", "reports/annual.PDF", "", FormatPDF},
+		{"sniffed PDF", "", "-", "%PDF-1.7\n%binary", FormatPDF},
+		{"EPUB content type", "application/epub+zip", "https://example.com/book", "", FormatEPUB},
+		{"EPUB file", "", "books/moby-dick.epub", "", FormatEPUB},
+		{"RSS content type", "application/rss+xml; charset=utf-8", "https://example.com/feed", "", FormatFeed},
+		{"Atom file", "", "feeds/news.atom", "", FormatFeed},
+		{"sniffed RSS served as XML", "application/xml", "https://example.com/feed.xml", `<?xml version="1.0"?><rss version="2.0"><channel></channel></rss>`, FormatFeed},
+		{"sniffed Atom without declaration", "", "-", `<feed xmlns="http://www.w3.org/2005/Atom"></feed>`, FormatFeed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, reader := DetectFormat(strings.NewReader(tt.content), tt.contentType, tt.source)
+			if format != tt.expected {
+				t.Errorf("DetectFormat() = %v, want %v", format, tt.expected)
+			}
+
+			// sniffing must not consume content
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to read content: %v", err)
+			}
+			if string(data) != tt.content {
+				t.Errorf("reader yielded %q, want %q", string(data), tt.content)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Format
+		expectError bool
+	}{
+		{"", FormatAuto, false},
+		{"auto", FormatAuto, false},
+		{"HTML", FormatHTML, false},
+		{"md", FormatMarkdown, false},
+		{"markdown", FormatMarkdown, false},
+		{"txt", FormatText, false},
+		{"pdf", FormatPDF, false},
+		{"epub", FormatEPUB, false},
+		{"rss", FormatFeed, false},
+		{"rtf", FormatAuto, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			format, err := ParseFormat(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ParseFormat(%q) error = %v, expectError %v", tt.input, err, tt.expectError)
+			}
+			if format != tt.expected {
+				t.Errorf("ParseFormat(%q) = %v, want %v", tt.input, format, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   Format
+		expected string
+	}{
+		{
+			name:     "Markdown is passed through",
+			input:    "# Carrot Cake\n\n<div>Sift the **flour**.</div>\n\n| a | b |\n|---|---|\n| 1 | 2 |\n",
+			format:   FormatMarkdown,
+			expected: "# Carrot Cake\n\n<div>Sift the **flour**.</div>\n\n| a | b |\n|---|---|\n| 1 | 2 |",
+		},
+		{
+			name:     "Markdown keeps hard line breaks",
+			input:    "first line  \nsecond line",
+			format:   FormatMarkdown,
+			expected: "first line  \nsecond line",
+		},
+		{
+			name:     "byte order mark and CRLF line endings",
+			input:    "\uFEFFline one\r\nline two\r\n",
+			format:   FormatText,
+			expected: "line one\nline two",
+		},
+		{
+			name:     "blank lines are collapsed and trimmed",
+			input:    "\n\n  \nfirst\n\n\n\n\nsecond   \n\n",
+			format:   FormatText,
+			expected: "first\n\nsecond",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NormalizeText(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("NormalizeText() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("NormalizeText() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewTextNormalizer(t *testing.T) {
+	longLine := strings.Repeat("x", 3*maxLineBytes) + "  "
+
+	tests := []struct {
+		name     string
+		input    string
+		format   Format
+		expected string
+	}{
+		{
+			name:     "matches NormalizeText with a trailing line ending",
+			input:    "\uFEFF\r\nfirst  \r\n\r\n\r\n\rsecond\n\n",
+			format:   FormatText,
+			expected: "first\n\nsecond\n",
+		},
+		{
+			name:     "overlong lines pass through whole",
+			input:    "before\n\n\n" + longLine + "\n\n\nafter",
+			format:   FormatText,
+			expected: "before\n\n" + strings.TrimRight(longLine, " ") + "\n\nafter\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a small read buffer exercises lines split across reads
+			reader := iotest.OneByteReader(NewTextNormalizer(strings.NewReader(tt.input), tt.format))
+			result, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("NewTextNormalizer() = %.80q (%d bytes), want %.80q (%d bytes)", result, len(result), tt.expected, len(tt.expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,594 @@
+package extract
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LinkMode selects how links are written in extracted Markdown
+type LinkMode int
+
+const (
+	LinksInline    LinkMode = iota // [text](url), as extracted
+	LinksReference                 // [text][1], with the numbered URLs listed at the end
+	LinksText                      // anchor text only
+	LinksStrip                     // links removed along with their anchor text
+)
+
+// String returns the string representation of LinkMode
+func (m LinkMode) String() string {
+	switch m {
+	case LinksInline:
+		return "inline"
+	case LinksReference:
+		return "reference"
+	case LinksText:
+		return "text"
+	case LinksStrip:
+		return "strip"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLinkMode parses a link mode name: inline, reference, text, or strip
+func ParseLinkMode(name string) (LinkMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "inline":
+		return LinksInline, nil
+	case "reference":
+		return LinksReference, nil
+	case "text":
+		return LinksText, nil
+	case "strip":
+		return LinksStrip, nil
+	default:
+		return LinksInline, fmt.Errorf("unknown link mode %q (expected inline, reference, text, or strip)", name)
+	}
+}
+
+// linkTarget matches what follows the bracketed text of a link or image: an inline URL, either in
+// angle brackets or bare with balanced parentheses (nested up to two deep, such as
+// "Foo_(bar)"), with an optional title, or a reference label
+const linkTarget = `\]` +
+	`(?:\(\s*(?:<([^<>\n]*)>|((?:[^()\s]|\((?:[^()\s]|\([^()\s]*\))*\))*))((?:\s+"[^"]*")?)\s*\)|\[([^\]]*)\])`
+
+// linkPatterns holds compiled patterns for rewriting Markdown links
+var linkPatterns = struct {
+	image    *regexp.Regexp
+	link     *regexp.Regexp
+	autolink *regexp.Regexp
+	stripped *regexp.Regexp
+	listItem *regexp.Regexp
+	numbered *regexp.Regexp
+}{
+	image:    regexp.MustCompile(`!\[([^\]]*)` + linkTarget),
+	link:     regexp.MustCompile(`\[([^\]]*)` + linkTarget),
+	autolink: regexp.MustCompile(`<((?:https?|ftp|mailto):[^>\s]+)>`),
+	stripped: regexp.MustCompile(`( *)\x{E102}+( *)`),
+	listItem: regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])?\s*$`),
+	numbered: regexp.MustCompile(`\]\[(\d+)\]`),
+}
+
+// placeholderStart and placeholderEnd enclose the number of a rewritten link, which keeps it
+// from being matched again, such as an image as the text of a link; strippedMark stands in for
+// a stripped link until the spaces around it are removed; literalMark and placeholderEnd enclose
+// the number of a character of the input that is itself one of these private-use marks
+const (
+	placeholderStart = '\uE100'
+	placeholderEnd   = '\uE101'
+	strippedMark     = '\uE102'
+	literalMark      = '\uE103'
+)
+
+// isMark reports whether c is one of the private-use characters used as marks while rewriting
+func isMark(c rune) bool {
+	return (c >= escapeBase && c < escapeBase+utf8.RuneSelf) || (c >= placeholderStart && c <= literalMark)
+}
+
+// RewriteLinks rewrites the links and images of Markdown in the given mode, resolving relative
+// URLs against base (which may be nil). Code blocks and code spans are left as they are.
+//
+// Parameters:
+//   - markdown: Markdown content, typically produced by ToMarkdown
+//   - mode: LinksInline keeps links, LinksReference numbers their URLs in a list of reference
+//     definitions at the end, LinksText keeps only their text, and LinksStrip removes them
+//   - base: URL of the page the Markdown was extracted from, or nil
+//
+// Returns the rewritten Markdown.
+func RewriteLinks(markdown string, mode LinkMode, base *url.URL) string {
+	if mode == LinksInline && base == nil {
+		return markdown
+	}
+
+	rewriter := newLinkRewriter(mode, base)
+	return rewriter.withReferences(rewriter.lines(markdown))
+}
+
+// NewLinkRewriter returns a reader that rewrites links as RewriteLinks does, one line at a
+// time, so that content of any size can be read with bounded memory
+func NewLinkRewriter(content io.Reader, mode LinkMode, base *url.URL) io.Reader {
+	if mode == LinksInline && base == nil {
+		return content
+	}
+	return &linkReader{src: bufio.NewReaderSize(content, maxLineBytes), rewriter: newLinkRewriter(mode, base)}
+}
+
+// References numbers the reference links of several documents in a single list, so that a link
+// keeps its number wherever it ends up once the documents are combined, chunked, and sized, and
+// lists the URLs of the numbers that the sized output uses. It is not safe for concurrent use.
+type References struct {
+	notes footnoteList
+}
+
+// NewReferences returns an empty list of references
+func NewReferences() *References {
+	return &References{notes: footnoteList{numbers: make(map[string]int)}}
+}
+
+// Rewrite rewrites the links of a document as RewriteLinks does in LinksReference mode, numbering
+// new URLs after those of the documents rewritten before it, but without listing the URLs
+func (r *References) Rewrite(markdown string) string {
+	return r.rewriter().lines(markdown)
+}
+
+// NewReader is the streaming form of Rewrite, as NewLinkRewriter is of RewriteLinks
+func (r *References) NewReader(content io.Reader) io.Reader {
+	return &linkReader{src: bufio.NewReaderSize(content, maxLineBytes), rewriter: r.rewriter()}
+}
+
+func (r *References) rewriter() *linkRewriter {
+	rewriter := newLinkRewriter(LinksReference, nil)
+	rewriter.notes, rewriter.listed = &r.notes, false
+	return rewriter
+}
+
+// Append appends the reference definitions of the numbers used by Markdown rewritten with
+// Rewrite, such as the chunks selected from it, outside of code blocks
+func (r *References) Append(markdown string) string {
+	used := make(map[int]bool)
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		var isFence bool
+		if fence, isFence = nextFence(fence, line); isFence || fence != "" {
+			continue
+		}
+		for _, m := range linkPatterns.numbered.FindAllStringSubmatch(line, -1) {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				used[n] = true
+			}
+		}
+	}
+	return appendReferences(markdown, references(r.notes.urls, used))
+}
+
+// linkReader applies a linkRewriter line by line as content is read
+type linkReader struct {
+	src      *bufio.Reader
+	rewriter *linkRewriter
+	out      []byte // rewritten text not yet returned
+	partial  []byte // start of an overlong line
+	ended    bool   // the last line written ended with a newline
+	err      error
+}
+
+func (r *linkReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		fragment, err := r.src.ReadSlice('\n')
+		r.partial = append(r.partial, fragment...)
+		if err == bufio.ErrBufferFull {
+			// an overlong line passes through without its links rewritten
+			r.out, r.partial = append(r.out, r.partial...), r.partial[:0]
+			continue
+		}
+
+		if len(r.partial) > 0 {
+			line, ending := strings.CutSuffix(string(r.partial), "\n")
+			if rewritten, ok := r.rewriter.line(line); ok {
+				r.out = append(r.out, rewritten...)
+				if ending {
+					r.out = append(r.out, '\n')
+				}
+				r.ended = ending
+			}
+			r.partial = r.partial[:0]
+		}
+		if err == io.EOF && r.rewriter.listed {
+			if references := references(r.rewriter.notes.urls, nil); references != "" {
+				if !r.ended {
+					r.out = append(r.out, '\n')
+				}
+				r.out = append(r.out, references...)
+			}
+		}
+		r.err = err
+	}
+
+	copied := copy(p, r.out)
+	r.out = r.out[copied:]
+	return copied, nil
+}
+
+// linkRewriter rewrites the links of Markdown a line at a time
+type linkRewriter struct {
+	mode    LinkMode
+	base    *url.URL
+	fence   string            // active code fence marker, empty outside code blocks
+	dropped bool              // the previous line was dropped
+	blank   bool              // the last line written was blank
+	listed  bool              // reference definitions are listed at the end of the text
+	notes   *footnoteList     // reference numbers by URL; a number awaiting its definition has an empty URL
+	labels  map[string]int    // reference numbers given to labels used before their definition
+	defined map[string]string // URLs of the reference definitions read so far, by lowercase label
+	literal []rune            // characters of the text being rewritten that were marks, by number
+}
+
+func newLinkRewriter(mode LinkMode, base *url.URL) *linkRewriter {
+	return &linkRewriter{
+		mode:    mode,
+		base:    base,
+		blank:   true,
+		listed:  true,
+		notes:   &footnoteList{numbers: make(map[string]int)},
+		labels:  make(map[string]int),
+		defined: make(map[string]string),
+	}
+}
+
+// lines rewrites the lines of Markdown, without its reference definitions
+func (r *linkRewriter) lines(markdown string) string {
+	var out []string
+	for _, line := range strings.Split(markdown, "\n") {
+		if rewritten, ok := r.line(line); ok {
+			out = append(out, rewritten)
+		}
+	}
+	rewritten := strings.Join(out, "\n")
+	if r.dropped {
+		// definitions ending the text leave the blank line before them
+		rewritten = strings.TrimRight(rewritten, "\n")
+	}
+	return rewritten
+}
+
+// line rewrites a line, reporting false if it is dropped: a reference definition (except in
+// inline mode), a line left empty by stripping its links, or a blank line after a dropped one
+func (r *linkRewriter) line(line string) (string, bool) {
+	var isFence bool
+	if r.fence, isFence = nextFence(r.fence, line); isFence || r.fence != "" {
+		return r.keep(line)
+	}
+
+	if m := plainTextPatterns.linkDef.FindStringSubmatchIndex(line); m != nil {
+		label, target := strings.ToLower(line[m[2]:m[3]]), r.resolve(line[m[4]:m[5]])
+		r.defined[label] = target
+		if n, ok := r.labels[label]; ok && r.notes.urls[n-1] == "" {
+			r.notes.urls[n-1] = target
+		}
+		if r.mode == LinksInline {
+			return r.keep(line[:m[4]] + target + line[m[5]:])
+		}
+		return r.drop()
+	}
+
+	rewritten := r.rewriteInline(line)
+	if r.mode == LinksStrip && rewritten != line {
+		if linkPatterns.listItem.MatchString(rewritten) {
+			return r.drop()
+		}
+		rewritten = strings.TrimRight(rewritten, " ")
+	}
+	return r.keep(rewritten)
+}
+
+// nextFence returns the code fence marker active after a line, given the one active before it,
+// and whether the line is itself a fence
+func nextFence(fence, line string) (string, bool) {
+	m := plainTextPatterns.fence.FindStringSubmatch(line)
+	if m == nil {
+		return fence, false
+	}
+	marker := m[1][:1]
+	if fence == "" {
+		return marker, true
+	}
+	if marker == fence {
+		return "", true
+	}
+	return fence, true
+}
+
+// keep writes a line, unless it is a blank line following a dropped line and a blank one
+func (r *linkRewriter) keep(line string) (string, bool) {
+	blank := strings.TrimSpace(line) == ""
+	if blank && r.dropped && r.blank {
+		return "", false
+	}
+	r.dropped, r.blank = false, blank
+	return line, true
+}
+
+// drop records a dropped line
+func (r *linkRewriter) drop() (string, bool) {
+	r.dropped = true
+	return "", false
+}
+
+// rewriteInline rewrites the links of a line, leaving code spans intact
+func (r *linkRewriter) rewriteInline(line string) string {
+	var b strings.Builder
+
+	// split on code spans so their contents are never rewritten
+	for line != "" {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			b.WriteString(r.rewriteText(line))
+			break
+		}
+
+		// match the backtick run length to find the closing delimiter
+		run := 0
+		for start+run < len(line) && line[start+run] == '`' {
+			run++
+		}
+		delim := line[start : start+run]
+		end := strings.Index(line[start+run:], delim)
+		if end < 0 {
+			b.WriteString(r.rewriteText(line))
+			break
+		}
+
+		b.WriteString(r.rewriteText(line[:start]))
+		b.WriteString(line[start : start+run+end+run])
+		line = line[start+run+end+run:]
+	}
+
+	return b.String()
+}
+
+// rewriteText rewrites the links and images of text outside of code spans
+func (r *linkRewriter) rewriteText(text string) string {
+	// number any marks already in the text, then shield escaped punctuation, such as escaped
+	// brackets, from the patterns below
+	r.literal = r.literal[:0]
+	text = r.shield(text)
+	text = plainTextPatterns.escaped.ReplaceAllStringFunc(text, func(m string) string {
+		return string(escapeBase + rune(m[1]))
+	})
+
+	// images go first, so that a linked image is rewritten as the text of its link
+	var rewritten []string
+	for _, pattern := range []*regexp.Regexp{linkPatterns.image, linkPatterns.link} {
+		image := pattern == linkPatterns.image
+		text = pattern.ReplaceAllStringFunc(text, func(m string) string {
+			// submatches hold the text, inline URL (in angle brackets or bare), title, and reference label
+			rewritten = append(rewritten, r.rewriteLink(pattern.FindStringSubmatch(m), image))
+			return string(placeholderStart) + strconv.Itoa(len(rewritten)-1) + string(placeholderEnd)
+		})
+	}
+	text = linkPatterns.autolink.ReplaceAllStringFunc(text, func(m string) string {
+		switch r.mode {
+		case LinksText:
+			return m[1 : len(m)-1]
+		case LinksStrip:
+			return string(strippedMark)
+		}
+		return m
+	})
+
+	// expand placeholders; those within a rewritten link stand for links rewritten before it
+	var expand func(text string, limit int) string
+	expand = func(text string, limit int) string {
+		return replaceMarks(text, placeholderStart, func(n int) (string, bool) {
+			if n >= limit {
+				return "", false
+			}
+			return expand(rewritten[n], n), true
+		})
+	}
+	text = expand(text, len(rewritten))
+
+	if r.mode == LinksStrip {
+		text = removeStripped(text)
+	}
+
+	// restore shielded characters with their escapes
+	var b strings.Builder
+	for _, c := range text {
+		if c >= escapeBase && c < escapeBase+utf8.RuneSelf {
+			b.WriteByte('\\')
+			c -= escapeBase
+		}
+		b.WriteRune(c)
+	}
+	return r.unshield(b.String())
+}
+
+// shield replaces the marks in text with numbered stand-ins, so that they are not taken for
+// marks of the rewriter, until unshield restores them
+func (r *linkRewriter) shield(text string) string {
+	if !strings.ContainsFunc(text, isMark) {
+		return text
+	}
+	var b strings.Builder
+	for _, c := range text {
+		if isMark(c) {
+			fmt.Fprintf(&b, "%c%d%c", literalMark, len(r.literal), placeholderEnd)
+			r.literal = append(r.literal, c)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// unshield restores the marks that shield replaced in text
+func (r *linkRewriter) unshield(text string) string {
+	return replaceMarks(text, literalMark, func(n int) (string, bool) {
+		if n >= len(r.literal) {
+			return "", false
+		}
+		return string(r.literal[n]), true
+	})
+}
+
+// replaceMarks replaces each mark in text that is followed by a number and placeholderEnd with
+// the value for that number, leaving a mark as it is if the number is missing or has no value
+func replaceMarks(text string, mark rune, value func(n int) (string, bool)) string {
+	if !strings.ContainsRune(text, mark) {
+		return text
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexRune(text, mark)
+		if start < 0 {
+			break
+		}
+		b.WriteString(text[:start])
+		rest := text[start+utf8.RuneLen(mark):]
+		if end := strings.IndexRune(rest, placeholderEnd); end > 0 {
+			if n, err := strconv.Atoi(rest[:end]); err == nil && n >= 0 {
+				if v, ok := value(n); ok {
+					b.WriteString(v)
+					text = rest[end+utf8.RuneLen(placeholderEnd):]
+					continue
+				}
+			}
+		}
+		b.WriteRune(mark)
+		text = rest
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// removeStripped removes the marks of stripped links along with the spaces around them, leaving
+// one space between the words on either side and the indentation of a line
+func removeStripped(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range linkPatterns.stripped.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(text[last:m[0]])
+		lead, trail, after := text[m[2]:m[3]], text[m[4]:m[5]], text[m[1]:]
+		switch {
+		case strings.TrimSpace(b.String()) == "":
+			b.WriteString(lead)
+		case after == "":
+			b.WriteString(trail)
+		case strings.ContainsRune(".,;:!?)]", rune(after[0])):
+		case lead != "" || trail != "":
+			b.WriteString(" ")
+		}
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// rewriteLink rewrites a link or image from its submatches: the whole match, text, inline URL in
+// angle brackets, bare inline URL, title, and reference label
+func (r *linkRewriter) rewriteLink(sub []string, image bool) string {
+	// the URL and label are used as they were read, without the stand-ins for marks
+	anchor, target, title, label := sub[1], r.unshield(sub[2]+sub[3]), sub[4], sub[5]
+	isReference := strings.HasSuffix(sub[0], "]")
+	bang := ""
+	if image {
+		bang = "!"
+	}
+
+	switch r.mode {
+	case LinksText:
+		return anchor
+	case LinksStrip:
+		return string(strippedMark)
+	case LinksReference:
+		var n int
+		if isReference {
+			if label == "" {
+				label = anchor
+			}
+			n = r.labelNumber(strings.ToLower(r.unshield(label)))
+		} else {
+			n = r.notes.add(r.resolve(target))
+		}
+		return fmt.Sprintf("%s[%s][%d]", bang, anchor, n)
+	}
+
+	if isReference {
+		return sub[0]
+	}
+	return fmt.Sprintf("%s[%s](%s%s)", bang, anchor, r.shield(destination(r.resolve(target))), title)
+}
+
+// destination writes a URL as a link destination, in angle brackets if it holds spaces or
+// unbalanced parentheses, which would end a bare destination early
+func destination(target string) string {
+	if strings.ContainsAny(target, " \t") || strings.Count(target, "(") != strings.Count(target, ")") {
+		return "<" + target + ">"
+	}
+	return target
+}
+
+// labelNumber returns the reference number for a definition label, reserving one until the
+// definition is read if it has not been yet
+func (r *linkRewriter) labelNumber(label string) int {
+	if target, ok := r.defined[label]; ok {
+		return r.notes.add(target)
+	}
+	if n, ok := r.labels[label]; ok {
+		return n
+	}
+	r.notes.urls = append(r.notes.urls, "")
+	r.labels[label] = len(r.notes.urls)
+	return len(r.notes.urls)
+}
+
+// resolve resolves a URL against the base URL, leaving it as it is without one
+func (r *linkRewriter) resolve(target string) string {
+	if r.base == nil || target == "" {
+		return target
+	}
+	ref, err := url.Parse(target)
+	if err != nil || ref.IsAbs() {
+		return target
+	}
+	return r.base.ResolveReference(ref).String()
+}
+
+// references returns the reference definitions of numbered URLs, those used if used is not nil,
+// preceded by a blank line
+func references(urls []string, used map[int]bool) string {
+	var b strings.Builder
+	for i, target := range urls {
+		if target == "" || (used != nil && !used[i+1]) {
+			continue // a label that was never defined, or a number not used
+		}
+		if b.Len() == 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%d]: %s\n", i+1, destination(target))
+	}
+	return b.String()
+}
+
+// withReferences appends the reference definitions to rewritten Markdown
+func (r *linkRewriter) withReferences(markdown string) string {
+	return appendReferences(markdown, references(r.notes.urls, nil))
+}
+
+// appendReferences appends reference definitions, as returned by references, to Markdown
+func appendReferences(markdown, references string) string {
+	if references == "" {
+		return markdown
+	}
+	return strings.TrimRight(markdown, "\n") + "\n" + strings.TrimSuffix(references, "\n")
+}
@@ -0,0 +1,230 @@
+package extract_test
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+func TestParseLinkMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected extract.LinkMode
+		wantErr  bool
+	}{
+		{input: "", expected: extract.LinksInline},
+		{input: "inline", expected: extract.LinksInline},
+		{input: "Reference", expected: extract.LinksReference},
+		{input: "text", expected: extract.LinksText},
+		{input: " strip ", expected: extract.LinksStrip},
+		{input: "footnote", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, err := extract.ParseLinkMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLinkMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && mode != tt.expected {
+				t.Errorf("ParseLinkMode(%q) = %v, want %v", tt.input, mode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/recipes/cake")
+
+	tests := []struct {
+		name     string
+		markdown string
+		mode     extract.LinkMode
+		base     *url.URL
+		expected string
+	}{
+		{
+			name:     "inline without base unchanged",
+			markdown: "Read the [recipe](recipes/cake.html).",
+			mode:     extract.LinksInline,
+			expected: "Read the [recipe](recipes/cake.html).",
+		},
+		{
+			name:     "inline resolves relative links",
+			markdown: "Read the [recipe](carrot \"Carrot\"), the [icing](/icing#glaze), and [more](https://other.example/).\n\n[tips]: ../tips",
+			mode:     extract.LinksInline,
+			base:     base,
+			expected: "Read the [recipe](https://example.com/recipes/carrot \"Carrot\"), the [icing](https://example.com/icing#glaze), and [more](https://other.example/).\n\n[tips]: https://example.com/tips",
+		},
+		{
+			name:     "reference numbers urls once",
+			markdown: "Read the [recipe](carrot), the [icing][glaze], and the [recipe](carrot) again.\n\n[glaze]: /icing\n\nSift twice.",
+			mode:     extract.LinksReference,
+			base:     base,
+			expected: "Read the [recipe][1], the [icing][2], and the [recipe][1] again.\n\nSift twice.\n\n[1]: https://example.com/recipes/carrot\n[2]: https://example.com/icing",
+		},
+		{
+			name:     "reference keeps images and autolinks",
+			markdown: "![A sifted cake](cake.png) from <https://example.com>",
+			mode:     extract.LinksReference,
+			expected: "![A sifted cake][1] from <https://example.com>\n\n[1]: cake.png",
+		},
+		{
+			name:     "reference label defined before use",
+			markdown: "[flour]: https://example.com/flour\n\nSift the [flour][].",
+			mode:     extract.LinksReference,
+			expected: "Sift the [flour][1].\n\n[1]: https://example.com/flour",
+		},
+		{
+			name:     "text keeps anchor text",
+			markdown: "Read the [recipe](carrot), the [icing][glaze], [![logo](logo.png)](/), and <https://example.com>.\n\n[glaze]: /icing",
+			mode:     extract.LinksText,
+			expected: "Read the recipe, the icing, logo, and https://example.com.",
+		},
+		{
+			name:     "strip removes links and their text",
+			markdown: "Read [the recipe](carrot) first, then [this](/icing).\n\n- [Home](/)\n- Whisk eggs\n\n  [Indented](/x) text",
+			mode:     extract.LinksStrip,
+			expected: "Read first, then.\n\n- Whisk eggs\n\n  text",
+		},
+		{
+			name:     "reference keeps parentheses in urls",
+			markdown: "See [Foo](https://en.wikipedia.org/wiki/Foo_(bar)), [nested](/a_(b_(c))) and [spaced](<my notes.md>).",
+			mode:     extract.LinksReference,
+			base:     base,
+			expected: "See [Foo][1], [nested][2] and [spaced][3].\n\n[1]: https://en.wikipedia.org/wiki/Foo_(bar)\n[2]: https://example.com/a_(b_(c))\n[3]: https://example.com/recipes/my%20notes.md",
+		},
+		{
+			name:     "reference keeps urls with spaces in angle brackets",
+			markdown: "See [spaced](<my notes.md>).",
+			mode:     extract.LinksReference,
+			expected: "See [spaced][1].\n\n[1]: <my notes.md>",
+		},
+		{
+			name:     "inline resolves urls with parentheses",
+			markdown: "See [Foo](Foo_(bar) \"Foo\") (the page).",
+			mode:     extract.LinksInline,
+			base:     base,
+			expected: "See [Foo](https://example.com/recipes/Foo_(bar) \"Foo\") (the page).",
+		},
+		{
+			name:     "text keeps parentheses of urls out of the text",
+			markdown: "See [Foo](https://en.wikipedia.org/wiki/Foo_(bar)) first.",
+			mode:     extract.LinksText,
+			expected: "See Foo first.",
+		},
+		{
+			name:     "strip urls with parentheses",
+			markdown: "See [Foo](https://en.wikipedia.org/wiki/Foo_(bar)).",
+			mode:     extract.LinksStrip,
+			expected: "See.",
+		},
+		{
+			name:     "inline keeps a private-use character without links",
+			markdown: "x \uE100 y",
+			mode:     extract.LinksInline,
+			base:     base,
+			expected: "x \uE100 y",
+		},
+		{
+			name:     "inline keeps private-use characters of the text",
+			markdown: "Icons \uE100 \uE101\uE05C [\uE102 home](/\uE100) \uE103",
+			mode:     extract.LinksInline,
+			base:     base,
+			expected: "Icons \uE100 \uE101\uE05C [\uE102 home](https://example.com/%EE%84%80) \uE103",
+		},
+		{
+			name:     "reference keeps private-use characters of the text",
+			markdown: "[a\uE100]: /icon\n\nx \uE100 y [\uE102 icon][a\uE100] \uE000",
+			mode:     extract.LinksReference,
+			expected: "x \uE100 y [\uE102 icon][1] \uE000\n\n[1]: /icon",
+		},
+		{
+			name:     "strip keeps private-use characters of the text",
+			markdown: "a \uE102 [x](/y) b \uE101",
+			mode:     extract.LinksStrip,
+			expected: "a \uE102 b \uE101",
+		},
+		{
+			name:     "code left alone",
+			markdown: "Use `[x](y)` or [docs](/docs).\n\n```\n[keep](this)\n```",
+			mode:     extract.LinksText,
+			expected: "Use `[x](y)` or docs.\n\n```\n[keep](this)\n```",
+		},
+		{
+			name:     "escaped brackets are not links",
+			markdown: `Not a \[link\](here), but [this](/is).`,
+			mode:     extract.LinksText,
+			expected: `Not a \[link\](here), but this.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extract.RewriteLinks(tt.markdown, tt.mode, tt.base)
+			if result != tt.expected {
+				t.Errorf("RewriteLinks() =\n%q\nwant\n%q", result, tt.expected)
+			}
+
+			// the streaming rewriter gives the same text, apart from newlines at the end
+			streamed, err := io.ReadAll(extract.NewLinkRewriter(strings.NewReader(tt.markdown), tt.mode, tt.base))
+			if err != nil {
+				t.Fatalf("NewLinkRewriter() unexpected error: %v", err)
+			}
+			if got := strings.TrimRight(string(streamed), "\n"); got != tt.expected {
+				t.Errorf("NewLinkRewriter() =\n%q\nwant\n%q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	references := extract.NewReferences()
+
+	first := references.Rewrite("Sift the [flour](https://example.com/flour).\n\n[sugar]: https://example.com/sugar\n\nAdd [sugar][].")
+	if want := "Sift the [flour][1].\n\nAdd [sugar][2]."; first != want {
+		t.Errorf("Rewrite() =\n%q\nwant\n%q", first, want)
+	}
+
+	// numbers continue across documents, and URLs seen before keep theirs
+	streamed, err := io.ReadAll(references.NewReader(strings.NewReader("Whisk [eggs](https://example.com/eggs) into the [flour](https://example.com/flour).\n")))
+	if err != nil {
+		t.Fatalf("NewReader() unexpected error: %v", err)
+	}
+	if want := "Whisk [eggs][3] into the [flour][1].\n"; string(streamed) != want {
+		t.Errorf("NewReader() =\n%q\nwant\n%q", streamed, want)
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{
+			name:     "lists numbers used",
+			markdown: "Whisk [eggs][3] into the [flour][1].\n",
+			expected: "Whisk [eggs][3] into the [flour][1].\n\n[1]: https://example.com/flour\n[3]: https://example.com/eggs",
+		},
+		{
+			name:     "code ignored",
+			markdown: "Add [sugar][2].\n\n```\nx[0][1]\n```",
+			expected: "Add [sugar][2].\n\n```\nx[0][1]\n```\n\n[2]: https://example.com/sugar",
+		},
+		{
+			name:     "no links",
+			markdown: "Knead the dough.",
+			expected: "Knead the dough.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := references.Append(tt.markdown); result != tt.expected {
+				t.Errorf("Append() =\n%q\nwant\n%q", result, tt.expected)
+			}
+		})
+	}
+}
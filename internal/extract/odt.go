@@ -0,0 +1,291 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odtTextStyle is the inline formatting of an ODT text style
+type odtTextStyle struct {
+	bold, italic bool
+}
+
+// ODTToMarkdown extracts the body of an OpenDocument text (.odt) document as Markdown.
+// Headings keep their outline level, lists become Markdown list items (numbered when their list
+// style numbers them), tables become pipe tables, and bold, italic, and hyperlinks are kept as inline Markdown.
+// maxBytes limits the decompressed size of each file read from the document (0 for no limit).
+func ODTToMarkdown(content io.Reader, maxBytes int64) (string, error) {
+	archive, err := openZip(content, "ODT")
+	if err != nil {
+		return "", err
+	}
+
+	contentXML, err := readZipFile(archive, "content.xml", maxBytes)
+	if err != nil {
+		return "", err
+	}
+	if contentXML == nil {
+		return "", fmt.Errorf("not an OpenDocument text: missing content.xml")
+	}
+	stylesXML, err := readZipFile(archive, "styles.xml", maxBytes)
+	if err != nil {
+		return "", err
+	}
+
+	parser := &odtParser{
+		textStyles: make(map[string]odtTextStyle),
+		listStyles: make(map[string]map[int]bool),
+	}
+	// named styles come from styles.xml; automatic styles in content.xml refine them
+	if stylesXML != nil {
+		parser.parseStyles(stylesXML)
+	}
+	parser.parseStyles(contentXML)
+
+	if err := parser.parse(contentXML); err != nil {
+		return "", err
+	}
+
+	return renderOfficeBlocks(parser.blocks), nil
+}
+
+// odtList is an open list and the style that numbers it
+type odtList struct {
+	style string
+}
+
+// odtParser walks content.xml, collecting blocks
+type odtParser struct {
+	textStyles map[string]odtTextStyle // style name -> inline formatting
+	listStyles map[string]map[int]bool // list style name -> level (1-based) -> ordered
+	blocks     []officeBlock
+	tables     officeTables
+	lists      []odtList // open lists, innermost last
+
+	inline   officeInline
+	heading  int            // outline level of the current heading, 0 for paragraphs
+	spans    []odtTextStyle // formatting of open spans, innermost last
+	link     string
+	depth    int // nesting depth of open paragraphs and headings
+	inListIt bool
+}
+
+// parseStyles records text formatting and list numbering from style definitions
+func (p *odtParser) parseStyles(data []byte) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var textStyle, listStyle string
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return // styles are best effort
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch t.Name.Local {
+		case "style":
+			textStyle = xmlAttr(t, "name")
+			// inherit from the parent style, if already known
+			p.textStyles[textStyle] = p.textStyles[xmlAttr(t, "parent-style-name")]
+		case "text-properties":
+			if textStyle == "" {
+				continue
+			}
+			style := p.textStyles[textStyle]
+			if weight := xmlAttr(t, "font-weight"); weight != "" {
+				numeric, _ := strconv.Atoi(weight)
+				style.bold = weight == "bold" || numeric >= 600
+			}
+			if fontStyle := xmlAttr(t, "font-style"); fontStyle != "" {
+				style.italic = fontStyle == "italic" || fontStyle == "oblique"
+			}
+			p.textStyles[textStyle] = style
+		case "list-style":
+			listStyle = xmlAttr(t, "name")
+			p.listStyles[listStyle] = make(map[int]bool)
+		case "list-level-style-number", "list-level-style-bullet":
+			if levels := p.listStyles[listStyle]; levels != nil {
+				level, _ := strconv.Atoi(xmlAttr(t, "level"))
+				levels[level] = t.Name.Local == "list-level-style-number" && xmlAttr(t, "num-format") != ""
+			}
+		}
+	}
+}
+
+// parse tokenizes the document body
+func (p *odtParser) parse(contentXML []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(contentXML))
+	inBody := false
+	skipDepth := 0 // nesting depth inside content that is not rendered
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse OpenDocument text: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "text" && t.Name.Space == odtOfficeNamespace {
+				inBody = true
+				continue
+			}
+			if !inBody {
+				continue
+			}
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+
+			switch t.Name.Local {
+			case "note", "tracked-changes", "sequence-decls", "annotation":
+				// notes, change tracking, and comments are not part of the running text
+				skipDepth = 1
+			case "h", "p":
+				if p.depth == 0 {
+					p.inline.reset()
+					p.heading = 0
+					if t.Name.Local == "h" {
+						p.heading, _ = strconv.Atoi(xmlAttr(t, "outline-level"))
+						p.heading = max(p.heading, 1)
+					}
+				}
+				p.depth++
+				p.spans = append(p.spans[:0], p.textStyles[xmlAttr(t, "style-name")])
+			case "span":
+				style := p.textStyles[xmlAttr(t, "style-name")]
+				outer := p.currentStyle()
+				p.spans = append(p.spans, odtTextStyle{bold: outer.bold || style.bold, italic: outer.italic || style.italic})
+			case "a":
+				p.link = xmlAttr(t, "href")
+			case "s":
+				count, err := strconv.Atoi(xmlAttr(t, "c"))
+				if err != nil || count < 1 {
+					count = 1
+				}
+				p.addText(strings.Repeat(" ", count))
+			case "tab", "line-break":
+				p.addText(" ")
+			case "list":
+				style := xmlAttr(t, "style-name")
+				if style == "" && len(p.lists) > 0 {
+					style = p.lists[len(p.lists)-1].style // nested lists inherit the outer list style
+				}
+				p.lists = append(p.lists, odtList{style: style})
+			case "list-item", "list-header":
+				p.inListIt = true
+			case "table":
+				p.tables.start()
+			case "table-row":
+				p.tables.startRow()
+			case "table-cell":
+				p.tables.startCell()
+			}
+
+		case xml.EndElement:
+			if !inBody {
+				continue
+			}
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+
+			switch t.Name.Local {
+			case "text":
+				if t.Name.Space == odtOfficeNamespace {
+					inBody = false
+				}
+			case "h", "p":
+				p.depth--
+				if p.depth == 0 {
+					p.endParagraph()
+				}
+			case "span":
+				if len(p.spans) > 1 {
+					p.spans = p.spans[:len(p.spans)-1]
+				}
+			case "a":
+				p.link = ""
+			case "list":
+				if len(p.lists) > 0 {
+					p.lists = p.lists[:len(p.lists)-1]
+				}
+			case "table-cell":
+				p.tables.endCell()
+			case "table-row":
+				p.tables.endRow()
+			case "table":
+				if rows := p.tables.end(); rows != nil {
+					p.blocks = append(p.blocks, officeBlock{kind: officeTable, rows: rows})
+				}
+			}
+
+		case xml.CharData:
+			if inBody && skipDepth == 0 && p.depth > 0 {
+				p.addText(string(t))
+			}
+		}
+	}
+}
+
+// odtOfficeNamespace is the namespace of the <office:text> body element
+const odtOfficeNamespace = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+
+// currentStyle returns the formatting of the innermost open span or paragraph
+func (p *odtParser) currentStyle() odtTextStyle {
+	if len(p.spans) == 0 {
+		return odtTextStyle{}
+	}
+	return p.spans[len(p.spans)-1]
+}
+
+// addText appends text to the current paragraph with the current formatting
+func (p *odtParser) addText(text string) {
+	style := p.currentStyle()
+	p.inline.add(text, style.bold, style.italic, p.link)
+}
+
+// endParagraph turns the current paragraph into a block (or a table cell paragraph)
+func (p *odtParser) endParagraph() {
+	text := p.inline.markdown()
+	p.inline.reset()
+
+	// only the first paragraph of a list item carries the bullet
+	listItem := p.inListIt
+	p.inListIt = false
+
+	if text == "" {
+		return
+	}
+
+	if p.tables.addParagraph(text) {
+		return
+	}
+
+	switch {
+	case p.heading > 0:
+		p.blocks = append(p.blocks, officeBlock{kind: officeHeading, level: p.heading, text: text})
+	case len(p.lists) > 0 && listItem:
+		level := len(p.lists)
+		p.blocks = append(p.blocks, officeBlock{
+			kind:    officeListItem,
+			level:   level - 1,
+			ordered: p.listStyles[p.lists[level-1].style][level],
+			text:    text,
+		})
+	default:
+		p.blocks = append(p.blocks, officeBlock{kind: officeParagraph, text: text})
+	}
+}
@@ -0,0 +1,329 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// officeBlockKind identifies the kind of a block in a word processing document
+type officeBlockKind int
+
+const (
+	officeParagraph officeBlockKind = iota
+	officeHeading
+	officeListItem
+	officeTable
+)
+
+// officeBlock is a block of a DOCX or ODT document, with inline content already rendered as Markdown
+type officeBlock struct {
+	kind    officeBlockKind
+	level   int        // heading level (1-6) or list nesting level (0 for top-level items)
+	ordered bool       // numbered rather than bulleted list item
+	text    string     // inline Markdown for paragraphs, headings, and list items
+	rows    [][]string // cells of each table row as inline Markdown; the first row is the header
+}
+
+// officeRun is a run of text sharing the same formatting
+type officeRun struct {
+	text   string
+	bold   bool
+	italic bool
+	link   string // hyperlink target, if any
+}
+
+// officeInline accumulates the formatted runs of a paragraph
+type officeInline struct {
+	runs []officeRun
+}
+
+// add appends text with the given formatting, merging it into the previous run when formatting matches
+func (in *officeInline) add(text string, bold, italic bool, link string) {
+	if text == "" {
+		return
+	}
+	if n := len(in.runs); n > 0 {
+		last := &in.runs[n-1]
+		if last.bold == bold && last.italic == italic && last.link == link {
+			last.text += text
+			return
+		}
+	}
+	in.runs = append(in.runs, officeRun{text: text, bold: bold, italic: italic, link: link})
+}
+
+// reset clears accumulated runs
+func (in *officeInline) reset() {
+	in.runs = in.runs[:0]
+}
+
+// markdown renders the runs as inline Markdown with collapsed whitespace
+func (in *officeInline) markdown() string {
+	var b strings.Builder
+
+	for i := 0; i < len(in.runs); {
+		// runs sharing a link target form a single link
+		j := i + 1
+		for j < len(in.runs) && in.runs[j].link == in.runs[i].link {
+			j++
+		}
+
+		var group strings.Builder
+		for _, run := range in.runs[i:j] {
+			group.WriteString(emphasize(escapeMarkdown(collapseSpaces(run.text)), run.bold, run.italic))
+		}
+
+		text := group.String()
+		if link := in.runs[i].link; link != "" && strings.TrimSpace(text) != "" {
+			lead, core, trail := splitSpaces(text)
+			text = lead + "[" + core + "](" + link + ")" + trail
+		}
+		b.WriteString(text)
+		i = j
+	}
+
+	return strings.TrimSpace(collapseSpaces(b.String()))
+}
+
+// emphasize wraps text in bold and italic markers, keeping surrounding whitespace outside them
+func emphasize(text string, bold, italic bool) string {
+	if (!bold && !italic) || strings.TrimSpace(text) == "" {
+		return text
+	}
+	lead, core, trail := splitSpaces(text)
+	if italic {
+		core = "*" + core + "*"
+	}
+	if bold {
+		core = "**" + core + "**"
+	}
+	return lead + core + trail
+}
+
+// splitSpaces splits text into leading whitespace, content, and trailing whitespace
+func splitSpaces(text string) (string, string, string) {
+	core := strings.TrimSpace(text)
+	start := strings.Index(text, core)
+	return text[:start], core, text[start+len(core):]
+}
+
+// collapseSpaces replaces runs of whitespace with a single space
+func collapseSpaces(text string) string {
+	var b strings.Builder
+	pendingSpace := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			pendingSpace = true
+			continue
+		}
+		if pendingSpace {
+			b.WriteByte(' ')
+			pendingSpace = false
+		}
+		b.WriteRune(r)
+	}
+	if pendingSpace {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// markdownEscaper escapes characters that would otherwise be read as inline Markdown syntax
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// escapeMarkdown escapes inline Markdown syntax in document text
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// renderOfficeBlocks renders document blocks as Markdown
+func renderOfficeBlocks(blocks []officeBlock) string {
+	var b strings.Builder
+	var counters []int // item numbers per list level
+	prevList := false
+
+	for _, block := range blocks {
+		if block.kind != officeTable && strings.TrimSpace(block.text) == "" {
+			continue
+		}
+		if block.kind == officeTable && len(block.rows) == 0 {
+			continue
+		}
+
+		isList := block.kind == officeListItem
+		if b.Len() > 0 {
+			if isList && prevList {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		if !isList {
+			counters = counters[:0]
+		}
+		prevList = isList
+
+		switch block.kind {
+		case officeHeading:
+			b.WriteString(strings.Repeat("#", min(max(block.level, 1), 6)))
+			b.WriteString(" ")
+			b.WriteString(block.text)
+		case officeListItem:
+			for len(counters) <= block.level {
+				counters = append(counters, 0)
+			}
+			counters = counters[:block.level+1]
+			counters[block.level]++
+
+			b.WriteString(strings.Repeat("  ", block.level))
+			if block.ordered {
+				b.WriteString(strconv.Itoa(counters[block.level]) + ". ")
+			} else {
+				b.WriteString("- ")
+			}
+			b.WriteString(block.text)
+		case officeTable:
+			b.WriteString(renderPipeTable(block.rows))
+		default:
+			b.WriteString(block.text)
+		}
+	}
+
+	return b.String()
+}
+
+// officeTableBuilder accumulates the rows of a table being parsed
+type officeTableBuilder struct {
+	rows  [][]string
+	row   []string
+	cell  []string // paragraphs of the current cell
+	inRow bool
+}
+
+// officeTables tracks the open tables of a document, innermost last
+type officeTables struct {
+	open []*officeTableBuilder
+}
+
+func (ts *officeTables) current() *officeTableBuilder {
+	if len(ts.open) == 0 {
+		return nil
+	}
+	return ts.open[len(ts.open)-1]
+}
+
+func (ts *officeTables) start() {
+	ts.open = append(ts.open, &officeTableBuilder{})
+}
+
+func (ts *officeTables) startRow() {
+	if table := ts.current(); table != nil {
+		table.row, table.inRow = nil, true
+	}
+}
+
+func (ts *officeTables) startCell() {
+	if table := ts.current(); table != nil {
+		table.cell = nil
+	}
+}
+
+func (ts *officeTables) endCell() {
+	if table := ts.current(); table != nil {
+		table.row = append(table.row, strings.Join(table.cell, " "))
+	}
+}
+
+func (ts *officeTables) endRow() {
+	if table := ts.current(); table != nil && table.inRow {
+		table.rows = append(table.rows, table.row)
+		table.inRow = false
+	}
+}
+
+// addParagraph adds a paragraph to the current cell, reporting false when outside any table
+func (ts *officeTables) addParagraph(text string) bool {
+	table := ts.current()
+	if table == nil {
+		return false
+	}
+	table.cell = append(table.cell, text)
+	return true
+}
+
+// end closes the innermost table and returns its rows; nested tables are flattened
+// into the enclosing cell, in which case nil is returned
+func (ts *officeTables) end() [][]string {
+	table := ts.current()
+	if table == nil {
+		return nil
+	}
+	ts.open = ts.open[:len(ts.open)-1]
+
+	if parent := ts.current(); parent != nil {
+		for _, row := range table.rows {
+			parent.cell = append(parent.cell, strings.Join(row, " "))
+		}
+		return nil
+	}
+
+	return table.rows
+}
+
+// openZip reads a zip-based document into memory
+func openZip(content io.Reader, kind string) (*zip.Reader, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s content: %w", kind, err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", kind, err)
+	}
+
+	return archive, nil
+}
+
+// readZipFile returns the contents of a named file in a zip archive, or nil if it does not exist.
+// Files that decompress to more than maxBytes (if above 0) are rejected, so that a small document
+// cannot expand into gigabytes of XML.
+func readZipFile(archive *zip.Reader, name string, maxBytes int64) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer reader.Close()
+
+		var limited io.Reader = reader
+		if maxBytes > 0 {
+			limited = io.LimitReader(reader, maxBytes+1)
+		}
+		data, err := io.ReadAll(limited)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if maxBytes > 0 && int64(len(data)) > maxBytes {
+			return nil, fmt.Errorf("%s decompresses to more than the %d-byte limit", name, maxBytes)
+		}
+		return data, nil
+	}
+
+	return nil, nil
+}
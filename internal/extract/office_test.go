@@ -0,0 +1,204 @@
+package extract_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+// buildZip creates an uncompressed zip archive holding the given files, in order
+// (ODT requires its leading mimetype entry to be stored uncompressed)
+func buildZip(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file[0], Method: zip.Store})
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", file[0], err)
+		}
+		if _, err := w.Write([]byte(file[1])); err != nil {
+			t.Fatalf("failed to write %s: %v", file[0], err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+const docxDocument = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<w:body>
+  <w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Carrot Cake</w:t></w:r></w:p>
+  <w:p><w:pPr><w:pStyle w:val="RecipeSection"/></w:pPr><w:r><w:t>Method</w:t></w:r></w:p>
+  <w:p>
+    <w:r><w:t xml:space="preserve">Always </w:t></w:r>
+    <w:r><w:rPr><w:b/></w:rPr><w:t>sift</w:t></w:r>
+    <w:r><w:t xml:space="preserve"> the </w:t></w:r>
+    <w:hyperlink r:id="rId5"><w:r><w:rPr><w:i/></w:rPr><w:t>flour</w:t></w:r></w:hyperlink>
+    <w:r><w:rPr><w:b w:val="0"/></w:rPr><w:t>, twice_over.</w:t></w:r>
+    <w:del><w:r><w:delText>deleted</w:delText></w:r></w:del>
+  </w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Grate carrots</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Peel first</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Fold in</w:t></w:r></w:p>
+  <w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t>Walnuts</w:t></w:r></w:p>
+  <w:tbl>
+    <w:tr><w:tc><w:p><w:r><w:t>Ingredient</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Amount</w:t></w:r></w:p></w:tc></w:tr>
+    <w:tr><w:tc><w:p><w:r><w:t>Flour</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>2 cups</w:t></w:r></w:p><w:p><w:r><w:t>sifted</w:t></w:r></w:p></w:tc></w:tr>
+  </w:tbl>
+  <w:sectPr/>
+</w:body>
+</w:document>`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/></w:style>
+  <w:style w:type="paragraph" w:styleId="RecipeSection"><w:name w:val="Recipe Section"/><w:basedOn w:val="Heading2"/></w:style>
+</w:styles>`
+
+const docxNumbering = `<?xml version="1.0" encoding="UTF-8"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:abstractNum w:abstractNumId="0">
+    <w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl>
+    <w:lvl w:ilvl="1"><w:numFmt w:val="bullet"/></w:lvl>
+  </w:abstractNum>
+  <w:abstractNum w:abstractNumId="1">
+    <w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/></w:lvl>
+  </w:abstractNum>
+  <w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+  <w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>
+</w:numbering>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/flour" TargetMode="External"/>
+</Relationships>`
+
+func TestDOCXToMarkdown(t *testing.T) {
+	document := buildZip(t,
+		[2]string{"[Content_Types].xml", `<Types/>`},
+		[2]string{"word/document.xml", docxDocument},
+		[2]string{"word/styles.xml", docxStyles},
+		[2]string{"word/numbering.xml", docxNumbering},
+		[2]string{"word/_rels/document.xml.rels", docxRels},
+	)
+
+	result, err := extract.DOCXToMarkdown(bytes.NewReader(document), 0)
+	if err != nil {
+		t.Fatalf("DOCXToMarkdown() error = %v", err)
+	}
+
+	expected := "# Carrot Cake\n\n" +
+		"## Method\n\n" +
+		"Always **sift** the [*flour*](https://example.com/flour), twice\\_over.\n\n" +
+		"1. Grate carrots\n" +
+		"  - Peel first\n" +
+		"2. Fold in\n" +
+		"- Walnuts\n\n" +
+		"| Ingredient | Amount |\n" +
+		"| --- | --- |\n" +
+		"| Flour | 2 cups sifted |"
+	if result != expected {
+		t.Errorf("DOCXToMarkdown() =\n%s\nwant\n%s", result, expected)
+	}
+
+	// format detection recognizes the archive layout
+	format, _ := extract.DetectFormat(bytes.NewReader(document), "", "-")
+	if format != extract.FormatDOCX {
+		t.Errorf("DetectFormat() = %v, want %v", format, extract.FormatDOCX)
+	}
+}
+
+const odtContent = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content
+  xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+  xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+  xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"
+  xmlns:xlink="http://www.w3.org/1999/xlink">
+<office:automatic-styles>
+  <style:style style:name="T1" style:family="text"><style:text-properties fo:font-weight="bold"/></style:style>
+  <style:style style:name="T2" style:family="text"><style:text-properties fo:font-style="italic"/></style:style>
+  <text:list-style style:name="L1">
+    <text:list-level-style-number text:level="1" style:num-format="1"/>
+    <text:list-level-style-bullet text:level="2" text:bullet-char="•"/>
+  </text:list-style>
+</office:automatic-styles>
+<office:body>
+<office:text>
+  <text:sequence-decls><text:sequence-decl text:name="Figure"/></text:sequence-decls>
+  <text:h text:outline-level="1">Carrot Cake</text:h>
+  <text:p>Always <text:span text:style-name="T1">sift</text:span><text:s text:c="3"/>the <text:a xlink:href="https://example.com/flour"><text:span text:style-name="T2">flour</text:span></text:a>.<text:note><text:note-body><text:p>A footnote</text:p></text:note-body></text:note></text:p>
+  <text:h text:outline-level="2">Steps</text:h>
+  <text:list text:style-name="L1">
+    <text:list-item><text:p>Grate carrots</text:p>
+      <text:list><text:list-item><text:p>Peel first</text:p></text:list-item></text:list>
+    </text:list-item>
+    <text:list-item><text:p>Fold in</text:p></text:list-item>
+  </text:list>
+  <table:table>
+    <table:table-row><table:table-cell><text:p>Ingredient</text:p></table:table-cell><table:table-cell><text:p>Amount</text:p></table:table-cell></table:table-row>
+    <table:table-row><table:table-cell><text:p>Flour</text:p></table:table-cell><table:table-cell><text:p>2 cups</text:p></table:table-cell></table:table-row>
+  </table:table>
+</office:text>
+</office:body>
+</office:document-content>`
+
+func TestODTToMarkdown(t *testing.T) {
+	document := buildZip(t,
+		[2]string{"mimetype", "application/vnd.oasis.opendocument.text"},
+		[2]string{"content.xml", odtContent},
+	)
+
+	result, err := extract.ODTToMarkdown(bytes.NewReader(document), 0)
+	if err != nil {
+		t.Fatalf("ODTToMarkdown() error = %v", err)
+	}
+
+	expected := "# Carrot Cake\n\n" +
+		"Always **sift** the [*flour*](https://example.com/flour).\n\n" +
+		"## Steps\n\n" +
+		"1. Grate carrots\n" +
+		"  - Peel first\n" +
+		"2. Fold in\n\n" +
+		"| Ingredient | Amount |\n" +
+		"| --- | --- |\n" +
+		"| Flour | 2 cups |"
+	if result != expected {
+		t.Errorf("ODTToMarkdown() =\n%s\nwant\n%s", result, expected)
+	}
+
+	format, _ := extract.DetectFormat(bytes.NewReader(document), "", "-")
+	if format != extract.FormatODT {
+		t.Errorf("DetectFormat() = %v, want %v", format, extract.FormatODT)
+	}
+}
+
+func TestOfficeDocumentErrors(t *testing.T) {
+	if _, err := extract.DOCXToMarkdown(bytes.NewReader([]byte("not a zip")), 0); err == nil {
+		t.Error("DOCXToMarkdown() expected error for non-zip content")
+	}
+	if _, err := extract.DOCXToMarkdown(bytes.NewReader(buildZip(t, [2]string{"content.xml", "<x/>"})), 0); err == nil {
+		t.Error("DOCXToMarkdown() expected error for archive without word/document.xml")
+	}
+	if _, err := extract.ODTToMarkdown(bytes.NewReader(buildZip(t, [2]string{"word/document.xml", "<x/>"})), 0); err == nil {
+		t.Error("ODTToMarkdown() expected error for archive without content.xml")
+	}
+
+	// files that decompress past the limit are rejected rather than read into memory
+	bomb := buildZip(t, [2]string{"word/document.xml", "<w:document>" + strings.Repeat(" ", 1<<20) + "</w:document>"})
+	if _, err := extract.DOCXToMarkdown(bytes.NewReader(bomb), 64*1024); err == nil || !strings.Contains(err.Error(), "word/document.xml decompresses to more than the 65536-byte limit") {
+		t.Errorf("DOCXToMarkdown() error = %v, want decompressed size limit error", err)
+	}
+	bomb = buildZip(t, [2]string{"content.xml", "<office:document-content>" + strings.Repeat(" ", 1<<20) + "</office:document-content>"})
+	if _, err := extract.ODTToMarkdown(bytes.NewReader(bomb), 64*1024); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("ODTToMarkdown() error = %v, want decompressed size limit error", err)
+	}
+}
@@ -0,0 +1,244 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDF layout heuristics, relative to the font size of the text involved
+const (
+	pdfLineTolerance   = 0.5  // vertical offset within which glyphs belong to the same line
+	pdfWordGap         = 0.15 // horizontal gap between glyphs that implies a space
+	pdfParagraphGap    = 1.6  // vertical distance between lines that implies a new paragraph
+	pdfHeadingRatio    = 1.15 // font size relative to body text that marks a heading
+	pdfMaxHeadingRunes = 200  // longer lines are never treated as headings
+	pdfMaxHeadingLevel = 3
+)
+
+// pdfPageBreak separates pages in the Markdown output
+const pdfPageBreak = "\n\n---\n\n"
+
+// pdfLine is a line of text reassembled from positioned glyphs
+type pdfLine struct {
+	text  strings.Builder
+	y     float64
+	size  float64 // dominant font size on the line
+	gap   float64 // vertical distance from the previous line on the page (0 for the first line)
+	sizes map[float64]int
+}
+
+// PDFToMarkdown extracts the text of a PDF document as Markdown.
+// Glyphs are reassembled into lines and paragraphs from their positions; lines set in a larger
+// font than the body text become headings (largest first, up to ###), and pages are separated by
+// thematic breaks. Text-less PDFs (such as scanned images) produce an error.
+func PDFToMarkdown(content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF content: %w", err)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	// first pass: reassemble lines on every page and tally font sizes across the document
+	pages := make([][]*pdfLine, 0, reader.NumPage())
+	sizeCounts := make(map[float64]int)
+	for i := 1; i <= reader.NumPage(); i++ {
+		lines, err := pdfPageLines(reader.Page(i))
+		if err != nil {
+			slog.Debug("Skipping unreadable PDF page", "page", i, "error", err)
+			continue
+		}
+		for _, line := range lines {
+			for size, count := range line.sizes {
+				sizeCounts[size] += count
+			}
+		}
+		pages = append(pages, lines)
+	}
+
+	body := dominantSize(sizeCounts)
+	levels := headingLevels(sizeCounts, body)
+
+	// second pass: render lines as Markdown blocks
+	var rendered []string
+	for _, lines := range pages {
+		if page := renderPDFPage(lines, levels); page != "" {
+			rendered = append(rendered, page)
+		}
+	}
+
+	if len(rendered) == 0 {
+		return "", fmt.Errorf("no text found in PDF (it may contain only scanned images)")
+	}
+
+	return strings.Join(rendered, pdfPageBreak), nil
+}
+
+// pdfPageLines groups a page's glyphs into lines, in content stream order
+func pdfPageLines(page pdf.Page) (lines []*pdfLine, err error) {
+	if page.V.IsNull() {
+		return nil, fmt.Errorf("missing page")
+	}
+
+	// malformed content streams can panic inside the PDF library
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed page content: %v", r)
+		}
+	}()
+
+	var current *pdfLine
+	var prev pdf.Text
+	for _, glyph := range page.Content().Text {
+		if glyph.S == "" {
+			continue
+		}
+		size := math.Round(glyph.FontSize*2) / 2 // group sizes to the nearest half point
+
+		if current == nil || math.Abs(glyph.Y-current.y) > math.Max(size, current.size)*pdfLineTolerance {
+			line := &pdfLine{y: glyph.Y, size: size, sizes: make(map[float64]int)}
+			if current != nil {
+				line.gap = math.Abs(current.y - glyph.Y)
+			}
+			lines = append(lines, line)
+			current = line
+		} else if prev.W > 0 && glyph.X-(prev.X+prev.W) > size*pdfWordGap &&
+			!strings.HasSuffix(current.text.String(), " ") && glyph.S != " " {
+			// glyph widths are only known for fonts that declare them; otherwise rely on explicit spaces
+			current.text.WriteString(" ")
+		}
+
+		current.text.WriteString(glyph.S)
+		if strings.TrimSpace(glyph.S) != "" {
+			current.sizes[size]++
+			if current.sizes[size] > current.sizes[current.size] {
+				current.size = size
+			}
+		}
+		prev = glyph
+	}
+
+	return lines, nil
+}
+
+// dominantSize returns the font size used for the most glyphs
+func dominantSize(counts map[float64]int) float64 {
+	best, bestCount := 0.0, 0
+	for size, count := range counts {
+		if count > bestCount || (count == bestCount && size < best) {
+			best, bestCount = size, count
+		}
+	}
+	return best
+}
+
+// headingLevels maps font sizes larger than the body text to heading levels, largest first
+func headingLevels(counts map[float64]int, body float64) map[float64]int {
+	var sizes []float64
+	for size := range counts {
+		if body > 0 && size >= body*pdfHeadingRatio {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	levels := make(map[float64]int, len(sizes))
+	for i, size := range sizes {
+		levels[size] = min(i+1, pdfMaxHeadingLevel)
+	}
+	return levels
+}
+
+// renderPDFPage joins a page's lines into headings and paragraphs
+func renderPDFPage(lines []*pdfLine, levels map[float64]int) string {
+	var blocks []string
+	var paragraph strings.Builder
+	var heading strings.Builder
+	headingLevel := 0
+
+	flushParagraph := func() {
+		if paragraph.Len() > 0 {
+			blocks = append(blocks, paragraph.String())
+			paragraph.Reset()
+		}
+	}
+	flushHeading := func() {
+		if heading.Len() > 0 {
+			blocks = append(blocks, strings.Repeat("#", headingLevel)+" "+heading.String())
+			heading.Reset()
+		}
+	}
+
+	for _, line := range lines {
+		text := strings.Join(strings.Fields(line.text.String()), " ")
+		if text == "" {
+			continue
+		}
+
+		level, isHeading := levels[line.size]
+		if isHeading && len([]rune(text)) > pdfMaxHeadingRunes {
+			isHeading = false
+		}
+
+		if isHeading {
+			flushParagraph()
+			// consecutive lines at the same heading size form one wrapped heading
+			if level != headingLevel || line.gap > line.size*pdfParagraphGap {
+				flushHeading()
+			}
+			headingLevel = level
+			appendPDFLine(&heading, text)
+			continue
+		}
+
+		flushHeading()
+		if line.gap > line.size*pdfParagraphGap {
+			flushParagraph()
+		}
+		appendPDFLine(&paragraph, text)
+	}
+
+	flushHeading()
+	flushParagraph()
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// appendPDFLine appends a wrapped line to a block, rejoining words hyphenated across lines
+func appendPDFLine(b *strings.Builder, text string) {
+	if b.Len() == 0 {
+		b.WriteString(text)
+		return
+	}
+
+	current := b.String()
+	first, _ := firstRune(text)
+	if strings.HasSuffix(current, "-") && !strings.HasSuffix(current, " -") && unicode.IsLower(first) {
+		b.Reset()
+		b.WriteString(strings.TrimSuffix(current, "-"))
+		b.WriteString(text)
+		return
+	}
+
+	b.WriteString(" ")
+	b.WriteString(text)
+}
+
+// firstRune returns the first rune of s
+func firstRune(s string) (rune, bool) {
+	for _, r := range s {
+		return r, true
+	}
+	return 0, false
+}
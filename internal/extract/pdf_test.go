@@ -0,0 +1,107 @@
+package extract_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+// pdfText is a line of text placed on a test PDF page
+type pdfText struct {
+	size int
+	y    int
+	text string
+}
+
+// buildPDF assembles a minimal PDF with one Helvetica font and the given pages of text
+func buildPDF(pages ...[]pdfText) []byte {
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	objects = append(objects, "") // page tree, filled in below
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	var kids []string
+	for _, lines := range pages {
+		var stream strings.Builder
+		for _, line := range lines {
+			fmt.Fprintf(&stream, "BT /F1 %d Tf 72 %d Td (%s) Tj ET\n", line.size, line.y, line.text)
+		}
+		contentID := len(objects) + 1
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", stream.Len(), stream.String()))
+		pageID := len(objects) + 1
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func TestPDFToMarkdown(t *testing.T) {
+	document := buildPDF(
+		[]pdfText{
+			{24, 720, "Carrot Cake"},
+			{11, 690, "Always sift the flour before"},
+			{11, 677, "folding in the grated carrots."},
+			{16, 640, "Icing"},
+			{11, 615, "Beat the cream cheese until smooth and spread-"},
+			{11, 602, "able."},
+		},
+		[]pdfText{
+			{11, 720, "Serve at room temperature."},
+		},
+	)
+
+	result, err := extract.PDFToMarkdown(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("PDFToMarkdown() error = %v", err)
+	}
+
+	expected := "# Carrot Cake\n\n" +
+		"Always sift the flour before folding in the grated carrots.\n\n" +
+		"## Icing\n\n" +
+		"Beat the cream cheese until smooth and spreadable.\n\n" +
+		"---\n\n" +
+		"Serve at room temperature."
+	if result != expected {
+		t.Errorf("PDFToMarkdown() =\n%q\nwant\n%q", result, expected)
+	}
+}
+
+func TestPDFToMarkdownErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		expectError string
+	}{
+		{"not a PDF", []byte("<html>not a pdf</html>"), "failed to parse PDF"},
+		{"no text", buildPDF([]pdfText{}), "no text found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := extract.PDFToMarkdown(bytes.NewReader(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("PDFToMarkdown() error = %v, want error containing %q", err, tt.expectError)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// escapeBase is the start of a private-use range used to shield backslash-escaped
+// punctuation from inline processing; escaped ASCII characters are mapped into it and back
+const escapeBase = '\uE000'
+
+// plainTextPatterns holds compiled patterns for Markdown-to-text conversion
+var plainTextPatterns = struct {
+	heading       *regexp.Regexp
+	setext        *regexp.Regexp
+	thematicBreak *regexp.Regexp
+	blockquote    *regexp.Regexp
+	fence         *regexp.Regexp
+	linkDef       *regexp.Regexp
+	tableDelim    *regexp.Regexp
+	escaped       *regexp.Regexp
+	image         *regexp.Regexp
+	inlineLink    *regexp.Regexp
+	refLink       *regexp.Regexp
+	autolink      *regexp.Regexp
+	strong        *regexp.Regexp
+	strongUnder   *regexp.Regexp
+	em            *regexp.Regexp
+	emUnder       *regexp.Regexp
+	strike        *regexp.Regexp
+}{
+	heading:       regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`),
+	setext:        regexp.MustCompile(`^\s{0,3}(?:=+|-+)\s*$`),
+	thematicBreak: regexp.MustCompile(`^\s{0,3}(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`),
+	blockquote:    regexp.MustCompile(`^\s{0,3}(?:>\s?)+`),
+	fence:         regexp.MustCompile("^\\s{0,3}(`{3,}|~{3,})"),
+	linkDef:       regexp.MustCompile(`^\s{0,3}\[([^\]]+)\]:\s*<?(\S+?)>?(?:\s+["'(].*["')])?\s*$`),
+	tableDelim:    regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(?:\|\s*:?-{3,}:?\s*)*\|?\s*$`),
+	escaped:       regexp.MustCompile(`\\([!-/:-@\[-` + "`" + `{-~])`),
+	image:         regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+"[^"]*")?\s*\)`),
+	inlineLink:    regexp.MustCompile(`\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+"[^"]*")?\s*\)`),
+	refLink:       regexp.MustCompile(`\[([^\]]+)\]\[([^\]]*)\]`),
+	autolink:      regexp.MustCompile(`<((?:https?|ftp|mailto):[^>\s]+)>`),
+	strong:        regexp.MustCompile(`\*\*([^*]+?)\*\*`),
+	strongUnder:   regexp.MustCompile(`(^|[^\w])__([^_]+?)__([^\w]|$)`),
+	em:            regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`),
+	emUnder:       regexp.MustCompile(`(^|[^\w])_([^_\s](?:[^_]*[^_\s])?)_([^\w]|$)`),
+	strike:        regexp.MustCompile(`~~([^~]+?)~~`),
+}
+
+// footnoteList collects link URLs in order of first appearance
+type footnoteList struct {
+	urls    []string
+	numbers map[string]int
+}
+
+// add registers a URL and returns its footnote number; repeated URLs share a number
+func (f *footnoteList) add(url string) int {
+	if n, ok := f.numbers[url]; ok {
+		return n
+	}
+	f.urls = append(f.urls, url)
+	f.numbers[url] = len(f.urls)
+	return len(f.urls)
+}
+
+// ToPlainText renders Markdown as plain text.
+// Heading markers, emphasis, code fences, and link and image syntax are removed while
+// their text is kept; tables are rendered as aligned columns.
+//
+// Parameters:
+//   - markdown: Markdown content, typically produced by ToMarkdown
+//   - footnotes: if true, link URLs are kept as numbered references listed after the text
+//
+// Returns the plain text rendering of the Markdown.
+func ToPlainText(markdown string, footnotes bool) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	notes := &footnoteList{numbers: make(map[string]int)}
+
+	// collect reference-style link definitions up front; they are dropped from the output
+	definitions := make(map[string]string)
+	for _, line := range lines {
+		if m := plainTextPatterns.linkDef.FindStringSubmatch(line); m != nil {
+			definitions[strings.ToLower(m[1])] = m[2]
+		}
+	}
+
+	var out []string
+	var fence string // active code fence marker, empty outside code blocks
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// code blocks are kept verbatim without their fences
+		if m := plainTextPatterns.fence.FindStringSubmatch(line); m != nil {
+			marker := m[1][:1]
+			if fence == "" {
+				fence = marker
+				continue
+			}
+			if marker == fence {
+				fence = ""
+				continue
+			}
+		}
+		if fence != "" {
+			out = append(out, line)
+			continue
+		}
+
+		if plainTextPatterns.linkDef.MatchString(line) {
+			continue
+		}
+
+		// tables: a header row followed by a delimiter row
+		if i+1 < len(lines) && strings.Contains(line, "|") && strings.Contains(lines[i+1], "|") && plainTextPatterns.tableDelim.MatchString(lines[i+1]) {
+			var rows [][]string
+			rows = append(rows, splitTableRow(line))
+			j := i + 2
+			for ; j < len(lines) && strings.Contains(lines[j], "|") && strings.TrimSpace(lines[j]) != ""; j++ {
+				rows = append(rows, splitTableRow(lines[j]))
+			}
+			for r := range rows {
+				for c := range rows[r] {
+					rows[r][c] = renderInline(rows[r][c], definitions, notes, footnotes)
+				}
+			}
+			out = append(out, alignTable(rows)...)
+			i = j - 1
+			continue
+		}
+
+		// setext underlines directly below text are heading markers
+		if plainTextPatterns.setext.MatchString(line) && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			continue
+		}
+		if plainTextPatterns.thematicBreak.MatchString(line) {
+			out = append(out, "")
+			continue
+		}
+
+		if m := plainTextPatterns.heading.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		line = plainTextPatterns.blockquote.ReplaceAllString(line, "")
+
+		out = append(out, renderInline(line, definitions, notes, footnotes))
+	}
+
+	text := strings.Join(out, "\n")
+
+	// normalize 3+ consecutive newlines to 2, as convertToMarkdown does
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	text = strings.Trim(text, "\n")
+
+	if footnotes && len(notes.urls) > 0 {
+		var b strings.Builder
+		b.WriteString(text)
+		b.WriteString("\n\n")
+		for i, url := range notes.urls {
+			fmt.Fprintf(&b, "[%d] %s\n", i+1, url)
+		}
+		text = strings.TrimRight(b.String(), "\n")
+	}
+
+	return text
+}
+
+// renderInline strips inline Markdown syntax from a single line, leaving code spans intact
+func renderInline(line string, definitions map[string]string, notes *footnoteList, footnotes bool) string {
+	var b strings.Builder
+
+	// split on code spans so their contents are never rewritten
+	for line != "" {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			b.WriteString(renderInlineText(line, definitions, notes, footnotes))
+			break
+		}
+
+		// match the backtick run length to find the closing delimiter
+		run := 0
+		for start+run < len(line) && line[start+run] == '`' {
+			run++
+		}
+		delim := line[start : start+run]
+		end := strings.Index(line[start+run:], delim)
+		if end < 0 {
+			b.WriteString(renderInlineText(line, definitions, notes, footnotes))
+			break
+		}
+
+		b.WriteString(renderInlineText(line[:start], definitions, notes, footnotes))
+		b.WriteString(strings.TrimSpace(line[start+run : start+run+end]))
+		line = line[start+run+end+run:]
+	}
+
+	return b.String()
+}
+
+// renderInlineText strips links, images, and emphasis from text outside of code spans
+func renderInlineText(text string, definitions map[string]string, notes *footnoteList, footnotes bool) string {
+	p := plainTextPatterns
+
+	// shield escaped punctuation from the patterns below
+	text = p.escaped.ReplaceAllStringFunc(text, func(m string) string {
+		return string(escapeBase + rune(m[1]))
+	})
+
+	// linkText keeps anchor text and, optionally, a footnote reference to the URL
+	linkText := func(anchor, url string) string {
+		anchor = strings.TrimSpace(anchor)
+		if !footnotes || url == "" || strings.HasPrefix(url, "#") {
+			return anchor
+		}
+		if anchor == "" {
+			anchor = url
+		}
+		return fmt.Sprintf("%s [%d]", anchor, notes.add(url))
+	}
+
+	text = p.image.ReplaceAllString(text, "$1")
+	text = p.inlineLink.ReplaceAllStringFunc(text, func(m string) string {
+		sub := p.inlineLink.FindStringSubmatch(m)
+		return linkText(sub[1], sub[2])
+	})
+	text = p.refLink.ReplaceAllStringFunc(text, func(m string) string {
+		sub := p.refLink.FindStringSubmatch(m)
+		ref := sub[2]
+		if ref == "" {
+			ref = sub[1]
+		}
+		return linkText(sub[1], definitions[strings.ToLower(ref)])
+	})
+	text = p.autolink.ReplaceAllString(text, "$1")
+
+	text = p.strong.ReplaceAllString(text, "$1")
+	text = p.strongUnder.ReplaceAllString(text, "$1$2$3")
+	text = p.em.ReplaceAllString(text, "$1")
+	text = p.emUnder.ReplaceAllString(text, "$1$2$3")
+	text = p.strike.ReplaceAllString(text, "$1")
+
+	// restore shielded characters as literals
+	return strings.Map(func(r rune) rune {
+		if r >= escapeBase && r < escapeBase+utf8.RuneSelf {
+			return r - escapeBase
+		}
+		return r
+	}, text)
+}
+
+// splitTableRow splits a pipe table row into trimmed cells
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+
+	// escaped pipes belong to the cell content
+	row = strings.ReplaceAll(row, `\|`, string(escapeBase+'|'))
+	cells := strings.Split(row, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(strings.ReplaceAll(cell, string(escapeBase+'|'), "|"))
+	}
+
+	return cells
+}
+
+// alignTable renders table rows as space-padded columns, underlining the header row
+func alignTable(rows [][]string) []string {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	widths := make([]int, columns)
+	for _, row := range rows {
+		for c, cell := range row {
+			widths[c] = max(widths[c], utf8.RuneCountInString(cell))
+		}
+	}
+
+	renderRow := func(cells []string) string {
+		var b strings.Builder
+		for c := 0; c < columns; c++ {
+			cell := ""
+			if c < len(cells) {
+				cell = cells[c]
+			}
+			if c > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+			if c < columns-1 {
+				b.WriteString(strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell)))
+			}
+		}
+		return strings.TrimRight(b.String(), " ")
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	lines = append(lines, renderRow(rows[0]))
+
+	underline := make([]string, columns)
+	for c := range underline {
+		underline[c] = strings.Repeat("-", max(widths[c], 1))
+	}
+	lines = append(lines, strings.Join(underline, "  "))
+
+	for _, row := range rows[1:] {
+		lines = append(lines, renderRow(row))
+	}
+
+	return lines
+}
@@ -0,0 +1,119 @@
+package extract_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/extract"
+)
+
+func TestToPlainText(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		footnotes bool
+		expected  string
+	}{
+		{
+			name:     "heading markers removed",
+			markdown: "# Carrot Cake\n\n## Ingredients ##\n\nSift the flour.",
+			expected: "Carrot Cake\n\nIngredients\n\nSift the flour.",
+		},
+		{
+			name:     "setext headings and thematic breaks removed",
+			markdown: "Carrot Cake\n===========\n\nFirst part.\n\n* * *\n\nSecond part.",
+			expected: "Carrot Cake\n\nFirst part.\n\nSecond part.",
+		},
+		{
+			name:     "emphasis removed",
+			markdown: "This is **bold**, __strong__, *italic*, _emphasis_ and ~~struck~~ text.",
+			expected: "This is bold, strong, italic, emphasis and struck text.",
+		},
+		{
+			name:     "snake_case and escaped characters preserved",
+			markdown: `Use the sift\_flour variable and 2 \* 3 cups, not \*\*bold\*\*.`,
+			expected: "Use the sift_flour variable and 2 * 3 cups, not **bold**.",
+		},
+		{
+			name:     "links keep anchor text",
+			markdown: "Read the [carrot cake recipe](https://example.com/cake \"Recipe\") or the [icing][1].\n\n[1]: https://example.com/icing",
+			expected: "Read the carrot cake recipe or the icing.",
+		},
+		{
+			name:      "links as footnotes",
+			markdown:  "Read the [recipe](https://example.com/cake), the [icing][icing], and the [recipe](https://example.com/cake) again.\n\n[icing]: https://example.com/icing",
+			footnotes: true,
+			expected:  "Read the recipe [1], the icing [2], and the recipe [1] again.\n\n[1] https://example.com/cake\n[2] https://example.com/icing",
+		},
+		{
+			name:      "fragment links are not footnoted",
+			markdown:  "Jump to [instructions](#instructions).",
+			footnotes: true,
+			expected:  "Jump to instructions.",
+		},
+		{
+			name:     "images keep alt text",
+			markdown: "![A sifted cake](cake.png) and [![logo](logo.png)](https://example.com)",
+			expected: "A sifted cake and logo",
+		},
+		{
+			name:     "autolinks unwrapped",
+			markdown: "Visit <https://example.com/cake> for more.",
+			expected: "Visit https://example.com/cake for more.",
+		},
+		{
+			name:     "blockquotes unwrapped",
+			markdown: "> The secret is in the *sifting*.",
+			expected: "The secret is in the sifting.",
+		},
+		{
+			name:     "code fences removed and content kept verbatim",
+			markdown: "Run this:\n\n```bash\nsift **example** --search \"cake\"\n```\n\nDone.",
+			expected: "Run this:\n\nsift **example** --search \"cake\"\n\nDone.",
+		},
+		{
+			name:     "inline code kept verbatim",
+			markdown: "Pass `--search *cake*` to **sift**.",
+			expected: "Pass --search *cake* to sift.",
+		},
+		{
+			name:     "tables aligned",
+			markdown: "| Ingredient | Amount |\n| --- | --- |\n| **Flour** | 2 cups |\n| Eggs | 3 |",
+			expected: "Ingredient  Amount\n----------  ------\nFlour       2 cups\nEggs        3",
+		},
+		{
+			name:     "lists preserved",
+			markdown: "- 2 cups flour\n- 1 cup *grated* carrots\n\n1. Sift\n2. Bake",
+			expected: "- 2 cups flour\n- 1 cup grated carrots\n\n1. Sift\n2. Bake",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extract.ToPlainText(tt.markdown, tt.footnotes)
+			if result != tt.expected {
+				t.Errorf("ToPlainText() =\n%q\nwant\n%q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestToPlainTextFromHTML(t *testing.T) {
+	markdown, err := extract.ToMarkdown(strings.NewReader(blogPostHTML), ".post-content", false, nil)
+	if err != nil {
+		t.Fatalf("ToMarkdown() unexpected error: %v", err)
+	}
+
+	result := extract.ToPlainText(markdown, false)
+
+	for _, marker := range []string{"**", "### ", "> "} {
+		if strings.Contains(result, marker) {
+			t.Errorf("ToPlainText() result should not contain Markdown marker %q.\nResult: %s", marker, result)
+		}
+	}
+	for _, expected := range []string{"sifting flour", "Ingredients", "The secret is in the sifting!"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ToPlainText() result should contain %q.\nResult: %s", expected, result)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// span limits of the HTML table model, which also keep malformed spans from growing a table without bound
+const (
+	maxColspan = 1000
+	maxRowspan = 65534
+)
+
+// renderPipeTable renders rows as a pipe table, treating the first row as the header
+func renderPipeTable(rows [][]string) string {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	renderRow := func(cells []string) string {
+		parts := make([]string, columns)
+		for c := range parts {
+			if c < len(cells) {
+				parts[c] = strings.ReplaceAll(cells[c], "|", `\|`)
+			}
+		}
+		return "| " + strings.Join(parts, " | ") + " |"
+	}
+
+	lines := []string{renderRow(rows[0]), "|" + strings.Repeat(" --- |", columns)}
+	for _, row := range rows[1:] {
+		lines = append(lines, renderRow(row))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// tableRules returns converter rules that render HTML data tables as pipe tables. Cells are
+// converted like any other content and collected by node, then laid out on a grid once their
+// table is reached. Layout tables keep their cells as ordinary blocks.
+func tableRules() []md.Rule {
+	cells := make(map[*html.Node]string)
+
+	return []md.Rule{
+		{
+			Filter: []string{"td", "th"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				cells[selec.Get(0)] = content
+				return md.String("")
+			},
+		},
+		{
+			Filter: []string{"tr", "caption"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				return md.String("")
+			},
+		},
+		{
+			Filter: []string{"table"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				rows, headerRows := tableRows(selec)
+				caption := strings.TrimSpace(collapseSpaces(selec.ChildrenFiltered("caption").Text()))
+
+				if isLayoutTable(selec) {
+					blocks := []string{caption}
+					for _, row := range rows {
+						row.Children().Filter("td, th").Each(func(_ int, cell *goquery.Selection) {
+							blocks = append(blocks, strings.TrimSpace(cells[cell.Get(0)]))
+						})
+					}
+					return md.String("\n\n" + joinBlocks(blocks, "\n\n") + "\n\n")
+				}
+
+				grid, headerRows := tableGrid(rows, headerRows, cells)
+				if len(grid) == 0 {
+					return md.String("")
+				}
+
+				// pipe tables cannot nest, so a table in a cell of another is flattened into its text
+				if outer := selec.Parent().Closest("table"); outer.Length() > 0 && !isLayoutTable(outer) {
+					var texts []string
+					for _, row := range grid {
+						texts = append(texts, row...)
+					}
+					return md.String(" " + joinBlocks(texts, " ") + " ")
+				}
+
+				header := mergeHeader(grid[:headerRows])
+				table := renderPipeTable(append([][]string{header}, grid[headerRows:]...))
+				if caption != "" {
+					table = escapeMarkdown(caption) + "\n\n" + table
+				}
+				return md.String("\n\n" + table + "\n\n")
+			},
+		},
+	}
+}
+
+// isLayoutTable reports whether a table arranges page content rather than holding data:
+// it is marked as presentational or has a single column
+func isLayoutTable(table *goquery.Selection) bool {
+	if role, _ := table.Attr("role"); role == "presentation" || role == "none" {
+		return true
+	}
+	rows, _ := tableRows(table)
+	for _, row := range rows {
+		if row.Children().Filter("td, th").Length() > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// tableRows returns the rows of a table, excluding those of nested tables, with its header
+// rows first. Header rows are those of the thead or, without one, a first row of th cells.
+func tableRows(table *goquery.Selection) ([]*goquery.Selection, int) {
+	var header, body []*goquery.Selection
+	table.Find("tr").Each(func(_ int, row *goquery.Selection) {
+		if !row.Closest("table").IsSelection(table) {
+			return
+		}
+		if goquery.NodeName(row.Parent()) == "thead" {
+			header = append(header, row)
+		} else {
+			body = append(body, row)
+		}
+	})
+
+	if len(header) == 0 && len(body) > 0 &&
+		body[0].Children().Filter("th").Length() > 0 && body[0].Children().Not("th").Length() == 0 {
+		header, body = body[:1], body[1:]
+	}
+	return append(header, body...), len(header)
+}
+
+// tableGrid lays out rows as cell text. A cell spanning rows is repeated in each of them, so
+// that every row stands on its own; a cell spanning columns fills the first of them or, in
+// header rows, each of them, so that merged header rows name every column. Empty rows are
+// dropped, and the number of header rows left is returned with the grid.
+func tableGrid(rows []*goquery.Selection, headerRows int, cells map[*html.Node]string) ([][]string, int) {
+	// carried holds, by column, the text and remaining rows of a cell spanning rows
+	type carry struct {
+		text string
+		rows int
+	}
+	var carried []carry
+	var group *html.Node
+
+	headers := headerRows
+	grid := make([][]string, 0, len(rows))
+	for r, row := range rows {
+		isHeader := r < headers
+		// row spans end with their row group (thead, tbody, or tfoot)
+		if parent := row.Parent().Get(0); parent != group {
+			group, carried = parent, nil
+		}
+
+		var line []string
+		// take fills the next column with the cell carried into it, reporting false if it has none
+		take := func() bool {
+			c := len(line)
+			if c >= len(carried) || carried[c].rows == 0 {
+				return false
+			}
+			line = append(line, carried[c].text)
+			carried[c].rows--
+			return true
+		}
+
+		row.Children().Filter("td, th").Each(func(_ int, cell *goquery.Selection) {
+			// the converter escapes pipes in text, which renderPipeTable escapes itself
+			text := strings.ReplaceAll(strings.TrimSpace(collapseSpaces(cells[cell.Get(0)])), `\|`, "|")
+			colspan := spanAttr(cell, "colspan", maxColspan)
+			rowspan := spanAttr(cell, "rowspan", maxRowspan)
+
+			for c := range colspan {
+				for take() {
+				}
+				spanned := text
+				if c > 0 && !isHeader {
+					spanned = ""
+				}
+				line = append(line, spanned)
+				if rowspan > 1 {
+					for len(carried) < len(line) {
+						carried = append(carried, carry{})
+					}
+					carried[len(line)-1] = carry{text: spanned, rows: rowspan - 1}
+				}
+			}
+		})
+		// cells from rows above may continue past the last cell of this row
+		for c := len(line); c < len(carried); c++ {
+			if carried[c].rows > 0 {
+				for len(line) < c {
+					line = append(line, "")
+				}
+				take()
+			}
+		}
+
+		switch {
+		case len(line) > 0:
+			grid = append(grid, line)
+		case isHeader:
+			headerRows--
+		}
+	}
+
+	return grid, headerRows
+}
+
+// spanAttr reads a colspan or rowspan attribute, clamped to [1, limit]. A rowspan of 0 spans
+// the rest of its row group.
+func spanAttr(cell *goquery.Selection, name string, limit int) int {
+	value, ok := cell.Attr(name)
+	if !ok {
+		return 1
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil || n < 0 || (n == 0 && name != "rowspan"):
+		return 1
+	case n == 0:
+		return limit
+	}
+	return min(n, limit)
+}
+
+// mergeHeader combines header rows into one, joining the distinct names stacked in each
+// column, such as "Q1" above "Sales" into "Q1 Sales"
+func mergeHeader(rows [][]string) []string {
+	var merged []string
+	for _, row := range rows {
+		for c, text := range row {
+			for len(merged) <= c {
+				merged = append(merged, "")
+			}
+			if text == "" || text == merged[c] || strings.HasSuffix(merged[c], " "+text) {
+				continue
+			}
+			merged[c] = strings.TrimSpace(merged[c] + " " + text)
+		}
+	}
+	return merged
+}
+
+// joinBlocks joins the non-empty texts with sep
+func joinBlocks(texts []string, sep string) string {
+	var kept []string
+	for _, text := range texts {
+		if text != "" {
+			kept = append(kept, text)
+		}
+	}
+	return strings.Join(kept, sep)
+}
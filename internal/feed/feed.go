@@ -0,0 +1,385 @@
+// Package feed parses RSS and Atom feeds into entries that sift can read as individual sources.
+//
+// RSS 2.0, RSS 1.0 (RDF), and Atom 1.0 are supported. Entry links are resolved against the
+// feed's xml:base or URL, so each entry can be extracted with its own base URL.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultLimit is the default maximum number of entries read from a feed
+const DefaultLimit = 20
+
+// Feed is a parsed RSS or Atom feed
+type Feed struct {
+	Title   string
+	Link    string // website of the feed, if given
+	Entries []Entry
+}
+
+// Entry is a single item of a feed
+type Entry struct {
+	Title     string
+	Link      string    // absolute URL of the entry's article, if any
+	Published time.Time // publication (or, failing that, update) time; zero if unknown
+	Content   string    // full content as HTML, if the feed includes it
+	Summary   string    // summary or description as HTML, if any
+}
+
+// Options selects which entries of a feed are read
+type Options struct {
+	Limit int       // maximum number of entries, in feed order (0 means no limit)
+	Since time.Time // only entries published at or after this time (zero means no lower bound)
+	Until time.Time // only entries published before this time (zero means no upper bound)
+}
+
+// Filter returns the entries selected by opts, keeping feed order.
+// When a date bound is set, entries without a publication date are skipped.
+func Filter(entries []Entry, opts Options) []Entry {
+	var selected []Entry
+	for _, entry := range entries {
+		if opts.Limit > 0 && len(selected) >= opts.Limit {
+			break
+		}
+		if !opts.Since.IsZero() || !opts.Until.IsZero() {
+			if entry.Published.IsZero() {
+				continue
+			}
+			if !opts.Since.IsZero() && entry.Published.Before(opts.Since) {
+				continue
+			}
+			if !opts.Until.IsZero() && !entry.Published.Before(opts.Until) {
+				continue
+			}
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// rssDocument covers RSS 2.0 (<rss><channel><item>) and RSS 1.0 (<rdf:RDF><item>), whose items
+// are siblings of the channel
+type rssDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Titles []rssElement `xml:"title"`
+		Links  []rssElement `xml:"link"`
+		Items  []rssItem    `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Titles      []rssElement `xml:"title"`
+	Links       []rssElement `xml:"link"`
+	GUID        string       `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Date        string       `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string       `xml:"description"`
+	Encoded     string       `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+// rss1Namespace is the default namespace of RSS 1.0 (RDF) elements; RSS 2.0 elements have none
+const rss1Namespace = "http://purl.org/rss/1.0/"
+
+// rssElement is a text element whose namespace must be checked, since extension elements such as
+// <atom:link> and <media:title> share local names with RSS elements
+type rssElement struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// rssValue returns the text of the first element in the RSS namespace
+func rssValue(elements []rssElement) string {
+	for _, element := range elements {
+		if element.XMLName.Space == "" || element.XMLName.Space == rss1Namespace {
+			return strings.TrimSpace(element.Value)
+		}
+	}
+	return ""
+}
+
+type atomDocument struct {
+	Base    string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title   atomText    `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Base      string     `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Content   atomText   `xml:"content"`
+	Summary   atomText   `xml:"summary"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// atomText is an Atom text construct, whose type says whether it holds text, escaped HTML, or inline XHTML
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the construct as HTML
+func (t atomText) html() string {
+	switch t.Type {
+	case "xhtml":
+		return strings.TrimSpace(t.Inner)
+	case "html", "text/html":
+		return strings.TrimSpace(unescapeXML(t.Inner))
+	default:
+		return html.EscapeString(strings.TrimSpace(unescapeXML(t.Inner)))
+	}
+}
+
+// text returns the construct as plain text
+func (t atomText) text() string {
+	if t.Type == "text" || t.Type == "" {
+		return collapse(unescapeXML(t.Inner))
+	}
+	return collapse(stripTags(t.html()))
+}
+
+// Parse reads an RSS or Atom feed. Relative entry links are resolved against the feed's
+// xml:base attributes and then against feedURL, which may be nil for local files.
+func Parse(content io.Reader, feedURL *url.URL) (*Feed, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Local {
+	case "rss", "RDF":
+		return parseRSS(data, feedURL)
+	case "feed":
+		return parseAtom(data, feedURL)
+	default:
+		return nil, fmt.Errorf("not a feed: unexpected root element <%s>", root.Local)
+	}
+}
+
+// rootElement returns the name of the document element
+func rootElement(data []byte) (xml.Name, error) {
+	decoder := newDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("not a feed: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func parseRSS(data []byte, feedURL *url.URL) (*Feed, error) {
+	var doc rssDocument
+	if err := newDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+	}
+
+	feed := &Feed{
+		Title: collapse(rssValue(doc.Channel.Titles)),
+		Link:  resolve(feedURL, rssValue(doc.Channel.Links)),
+	}
+
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		link := rssValue(item.Links)
+		if link == "" && strings.HasPrefix(item.GUID, "http") {
+			link = strings.TrimSpace(item.GUID) // a GUID is often the permalink
+		}
+
+		date := item.PubDate
+		if strings.TrimSpace(date) == "" {
+			date = item.Date
+		}
+
+		feed.Entries = append(feed.Entries, Entry{
+			Title:     collapse(stripTags(rssValue(item.Titles))),
+			Link:      resolve(feedURL, link),
+			Published: parseDate(date),
+			Content:   strings.TrimSpace(item.Encoded),
+			Summary:   strings.TrimSpace(item.Description),
+		})
+	}
+
+	return feed, nil
+}
+
+func parseAtom(data []byte, feedURL *url.URL) (*Feed, error) {
+	var doc atomDocument
+	if err := newDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+	}
+
+	feedBase := rebase(feedURL, doc.Base)
+	feed := &Feed{
+		Title: doc.Title.text(),
+		Link:  resolve(feedBase, alternateLink(doc.Links)),
+	}
+
+	for _, entry := range doc.Entries {
+		date := entry.Published
+		if strings.TrimSpace(date) == "" {
+			date = entry.Updated
+		}
+
+		feed.Entries = append(feed.Entries, Entry{
+			Title:     entry.Title.text(),
+			Link:      resolve(rebase(feedBase, entry.Base), alternateLink(entry.Links)),
+			Published: parseDate(date),
+			Content:   entry.Content.html(),
+			Summary:   entry.Summary.html(),
+		})
+	}
+
+	return feed, nil
+}
+
+// alternateLink returns the href of the rel="alternate" link (the default relation), preferring HTML
+func alternateLink(links []atomLink) string {
+	var found string
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return strings.TrimSpace(link.Href)
+		}
+		if found == "" {
+			found = strings.TrimSpace(link.Href)
+		}
+	}
+	return found
+}
+
+// rebase applies an xml:base attribute to a base URL
+func rebase(base *url.URL, xmlBase string) *url.URL {
+	xmlBase = strings.TrimSpace(xmlBase)
+	if xmlBase == "" {
+		return base
+	}
+	ref, err := url.Parse(xmlBase)
+	if err != nil {
+		return base
+	}
+	if base == nil {
+		return ref
+	}
+	return base.ResolveReference(ref)
+}
+
+// resolve resolves a link against base, returning it unchanged if either cannot be used
+func resolve(base *url.URL, link string) string {
+	if link == "" || base == nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// dateLayouts are the publication date formats seen in feeds: RFC 822 variants for RSS, RFC 3339 for Atom and Dublin Core
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses a feed date, returning the zero time if it is missing or unrecognized
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// newDecoder returns a lenient XML decoder, since feeds in the wild often contain HTML entities
+func newDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		// content is transcoded to UTF-8 when fetched, whatever the XML declaration says
+		return input, nil
+	}
+	return decoder
+}
+
+// unescapeXML decodes character references in raw inner XML, including CDATA sections
+func unescapeXML(inner string) string {
+	var b strings.Builder
+	decoder := newDecoder([]byte("<x>" + inner + "</x>"))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if data, ok := token.(xml.CharData); ok {
+			b.Write(data)
+		}
+	}
+	return b.String()
+}
+
+// stripTags removes HTML tags from a short string such as a title
+func stripTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return html.UnescapeString(s)
+	}
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+// collapse trims text and replaces runs of whitespace with single spaces
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
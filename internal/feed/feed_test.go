@@ -0,0 +1,196 @@
+package feed_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chriscorrea/sift/internal/feed"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Baking Notes</title>
+  <link>https://example.com/</link>
+  <atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+  <item>
+    <title>Carrot Cake &amp; Icing</title>
+    <link>/posts/carrot-cake</link>
+    <pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate>
+    <description>A short summary.</description>
+    <content:encoded><![CDATA[<p>Sift the <a href="flour">flour</a> twice.</p>]]></content:encoded>
+  </item>
+  <item>
+    <title>Sourdough</title>
+    <guid isPermaLink="true">https://example.com/posts/sourdough</guid>
+    <pubDate>Mon, 1 Jan 2024 08:30:00 GMT</pubDate>
+    <description>&lt;p&gt;Feed the starter.&lt;/p&gt;</description>
+  </item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://blog.example.org/">
+  <title type="html">Field &lt;em&gt;Notes&lt;/em&gt;</title>
+  <link rel="self" href="/atom.xml"/>
+  <link href="/"/>
+  <entry xml:base="2024/">
+    <title>Spring Planting</title>
+    <link rel="alternate" type="text/html" href="spring-planting.html"/>
+    <link rel="enclosure" href="seeds.mp3"/>
+    <published>2024-03-20T09:00:00Z</published>
+    <updated>2024-03-21T09:00:00Z</updated>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Sow the <b>peas</b>.</p></div></content>
+  </entry>
+  <entry>
+    <title type="text">Compost &amp; Soil</title>
+    <link href="compost"/>
+    <updated>2024-02-01T12:00:00+01:00</updated>
+    <summary type="html">&lt;p&gt;Turn it weekly.&lt;/p&gt;</summary>
+  </entry>
+</feed>`
+
+const rdfFeed = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.net/"><title>Old News</title><link>https://example.net/</link></channel>
+  <item rdf:about="https://example.net/1">
+    <title>First Post</title>
+    <link>https://example.net/1</link>
+    <dc:date>2003-12-13T18:30:02Z</dc:date>
+    <description>Hello.</description>
+  </item>
+</rdf:RDF>`
+
+func TestParse(t *testing.T) {
+	feedURL, _ := url.Parse("https://example.com/feed.xml")
+
+	tests := []struct {
+		name          string
+		content       string
+		feedURL       *url.URL
+		expectTitle   string
+		expectEntries []feed.Entry
+	}{
+		{
+			name:        "RSS 2.0",
+			content:     rssFeed,
+			feedURL:     feedURL,
+			expectTitle: "Baking Notes",
+			expectEntries: []feed.Entry{
+				{
+					Title:     "Carrot Cake & Icing",
+					Link:      "https://example.com/posts/carrot-cake",
+					Published: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+					Content:   `<p>Sift the <a href="flour">flour</a> twice.</p>`,
+					Summary:   "A short summary.",
+				},
+				{
+					Title:     "Sourdough",
+					Link:      "https://example.com/posts/sourdough",
+					Published: time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC),
+					Summary:   "<p>Feed the starter.</p>",
+				},
+			},
+		},
+		{
+			name:        "Atom with xml:base",
+			content:     atomFeed,
+			expectTitle: "Field Notes",
+			expectEntries: []feed.Entry{
+				{
+					Title:     "Spring Planting",
+					Link:      "https://blog.example.org/2024/spring-planting.html",
+					Published: time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC),
+					Content:   `<div xmlns="http://www.w3.org/1999/xhtml"><p>Sow the <b>peas</b>.</p></div>`,
+				},
+				{
+					Title:     "Compost & Soil",
+					Link:      "https://blog.example.org/compost",
+					Published: time.Date(2024, 2, 1, 11, 0, 0, 0, time.UTC),
+					Summary:   "<p>Turn it weekly.</p>",
+				},
+			},
+		},
+		{
+			name:        "RSS 1.0",
+			content:     rdfFeed,
+			expectTitle: "Old News",
+			expectEntries: []feed.Entry{
+				{
+					Title:     "First Post",
+					Link:      "https://example.net/1",
+					Published: time.Date(2003, 12, 13, 18, 30, 2, 0, time.UTC),
+					Summary:   "Hello.",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := feed.Parse(strings.NewReader(tt.content), tt.feedURL)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if parsed.Title != tt.expectTitle {
+				t.Errorf("Title = %q, want %q", parsed.Title, tt.expectTitle)
+			}
+			if len(parsed.Entries) != len(tt.expectEntries) {
+				t.Fatalf("got %d entries, want %d: %+v", len(parsed.Entries), len(tt.expectEntries), parsed.Entries)
+			}
+			for i, entry := range parsed.Entries {
+				expected := tt.expectEntries[i]
+				if !entry.Published.Equal(expected.Published) {
+					t.Errorf("entry %d Published = %v, want %v", i, entry.Published, expected.Published)
+				}
+				entry.Published, expected.Published = time.Time{}, time.Time{}
+				if entry != expected {
+					t.Errorf("entry %d = %+v, want %+v", i, entry, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_NotAFeed(t *testing.T) {
+	if _, err := feed.Parse(strings.NewReader(`<html><body>Hi</body></html>`), nil); err == nil {
+		t.Error("Parse() expected error for HTML content")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	entries := []feed.Entry{
+		{Title: "fifth", Published: day(5)},
+		{Title: "undated"},
+		{Title: "fourth", Published: day(4)},
+		{Title: "second", Published: day(2)},
+		{Title: "first", Published: day(1)},
+	}
+
+	tests := []struct {
+		name     string
+		opts     feed.Options
+		expected []string
+	}{
+		{"no options", feed.Options{}, []string{"fifth", "undated", "fourth", "second", "first"}},
+		{"limit", feed.Options{Limit: 2}, []string{"fifth", "undated"}},
+		{"since skips undated", feed.Options{Since: day(2)}, []string{"fifth", "fourth", "second"}},
+		{"until is exclusive", feed.Options{Until: day(4)}, []string{"second", "first"}},
+		{"range with limit", feed.Options{Since: day(2), Until: day(5), Limit: 1}, []string{"fourth"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var titles []string
+			for _, entry := range feed.Filter(entries, tt.opts) {
+				titles = append(titles, entry.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Filter() = %v, want %v", titles, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ArchiveSeparator separates an archive path from a member path in sources such as "bundle.zip!docs/intro.md"
+const ArchiveSeparator = "!"
+
+// archiveExtensions are the file extensions read as archives; compressed tarballs are decompressed first
+var archiveExtensions = []string{".zip", ".tar", ".tgz", ".tar.gz", ".tar.zst", ".tzst", ".tar.bz2", ".tbz2"}
+
+// isArchive reports whether a file name has an archive extension
+func isArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitArchiveSource splits an "archive!member" source at the first separator that follows an archive name
+func splitArchiveSource(source string) (archive, member string, ok bool) {
+	for i := 0; i < len(source); i++ {
+		next := strings.Index(source[i:], ArchiveSeparator)
+		if next < 0 {
+			return "", "", false
+		}
+		i += next
+		if isArchive(source[:i]) {
+			return source[:i], source[i+len(ArchiveSeparator):], true
+		}
+	}
+	return "", "", false
+}
+
+// cleanMember normalizes a member path, so that "./docs/a.md" and "/docs/a.md" are both "docs/a.md"
+func cleanMember(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// archiveMembers lists the regular files of a zip or tar archive, in archive order
+func archiveMembers(archive string) ([]string, error) {
+	var members []string
+	err := walkArchive(archive, func(name string, _ int64, _ func() (io.ReadCloser, error)) error {
+		members = append(members, name)
+		return nil
+	})
+	return members, err
+}
+
+// SelectArchiveMembers records the "archive!member" sources among sources, as expanded by
+// ExpandSources, so that indexing a tar archive copies only these members to its spool file.
+// It must be called before the members are read; members that were not selected are still
+// read, by scanning the archive again for each.
+func (c *Client) SelectArchiveMembers(sources []string) {
+	c.archives.mu.Lock()
+	defer c.archives.mu.Unlock()
+
+	if c.archives.selected == nil {
+		c.archives.selected = make(map[string]map[string]bool)
+	}
+	for _, source := range sources {
+		archive, member, ok := splitArchiveSource(source)
+		if !ok || fileExists(source) {
+			continue
+		}
+		if c.archives.selected[archive] == nil {
+			c.archives.selected[archive] = make(map[string]bool)
+		}
+		c.archives.selected[archive][cleanMember(member)] = true
+	}
+}
+
+// openArchiveMember opens a member of a zip or tar archive, rejecting members whose recorded size
+// exceeds the client's limit. Compressed members are decompressed like files.
+func (c *Client) openArchiveMember(archive, member string) (io.ReadCloser, error) {
+	member = cleanMember(member)
+	source := archive + ArchiveSeparator + member
+
+	index, err := c.archives.index(archive, c.opts.MaxBytes, c.opts.MaxTotalBytes)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := index.members[member]
+	if !ok {
+		return nil, fmt.Errorf("archive %q has no member %q", archive, member)
+	}
+	if entry.size > c.opts.MaxBytes {
+		return nil, &SizeLimitError{Source: source, Limit: c.opts.MaxBytes, Size: entry.size}
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	var content io.ReadCloser
+	if entry.open != nil {
+		content, err = entry.open()
+	} else {
+		// a tar member that was not selected is read from the archive on its own
+		content, err = openTarMember(archive, member)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive member %q: %w", source, err)
+	}
+	return decompress(content, source)
+}
+
+// archiveCache indexes each archive the first time one of its members is opened, so that an
+// archive expanded into many sources is read once rather than once per member
+type archiveCache struct {
+	mu       sync.Mutex
+	indexes  map[string]*archiveIndex
+	selected map[string]map[string]bool // members to spool, by archive
+}
+
+// archiveIndex locates the members of an archive. Zip members are read from the archive, which is
+// kept open; the selected members of tar archives, which cannot be read out of order once
+// compressed, are copied to a temporary spool file as the archive is read.
+type archiveIndex struct {
+	once    sync.Once
+	members map[string]archiveEntry
+	closer  io.Closer // zip reader or spool file, nil until indexed
+	spool   string    // path of the spool file, empty if nothing was spooled
+	err     error
+}
+
+// archiveEntry is an indexed archive member
+type archiveEntry struct {
+	size int64                         // uncompressed size recorded in the archive
+	open func() (io.ReadCloser, error) // nil for tar members that were not spooled
+	err  error                         // why a selected tar member was not spooled, such as the total limit
+}
+
+// index returns the index of an archive, reading the archive if it has not been indexed yet
+func (ac *archiveCache) index(archive string, maxBytes, maxTotalBytes int64) (*archiveIndex, error) {
+	ac.mu.Lock()
+	if ac.indexes == nil {
+		ac.indexes = make(map[string]*archiveIndex)
+	}
+	index, ok := ac.indexes[archive]
+	if !ok {
+		index = &archiveIndex{}
+		ac.indexes[archive] = index
+	}
+	selected := ac.selected[archive]
+	ac.mu.Unlock()
+
+	// other members of the same archive wait for the first to finish indexing it
+	index.once.Do(func() {
+		if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+			index.err = index.readZip(archive)
+		} else {
+			index.err = index.readTar(archive, selected, maxBytes, maxTotalBytes)
+		}
+	})
+	return index, index.err
+}
+
+// close releases the archives and removes the spool files of all indexes
+func (ac *archiveCache) close() error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	var first error
+	for _, index := range ac.indexes {
+		if index.closer == nil {
+			continue
+		}
+		if err := index.closer.Close(); err != nil && first == nil {
+			first = err
+		}
+		if index.spool != "" {
+			if err := os.Remove(index.spool); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	ac.indexes = nil
+	return first
+}
+
+// readZip indexes a zip archive from its central directory, keeping the archive open
+func (index *archiveIndex) readZip(archive string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %q: %w", archive, err)
+	}
+	index.closer = reader
+
+	index.members = make(map[string]archiveEntry)
+	for _, file := range reader.File {
+		name := cleanMember(file.Name)
+		if _, seen := index.members[name]; seen || !file.Mode().IsRegular() {
+			continue
+		}
+		index.members[name] = archiveEntry{size: int64(file.UncompressedSize64), open: file.Open}
+	}
+	return nil
+}
+
+// readTar indexes a tar archive in one pass, copying each selected member within maxBytes to a
+// spool file that members are then read from by offset. The spool file holds at most
+// maxTotalBytes (0 for no limit), as the members read from it count toward that limit anyway.
+func (index *archiveIndex) readTar(archive string, selected map[string]bool, maxBytes, maxTotalBytes int64) error {
+	index.members = make(map[string]archiveEntry)
+	var spool *os.File
+	var offset int64
+	return walkArchive(archive, func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		if _, seen := index.members[name]; seen {
+			return nil
+		}
+		if !selected[name] || size > maxBytes {
+			index.members[name] = archiveEntry{size: size}
+			return nil
+		}
+		if maxTotalBytes > 0 && offset+size > maxTotalBytes {
+			source := archive + ArchiveSeparator + name
+			index.members[name] = archiveEntry{size: size, err: &SizeLimitError{Source: source, Limit: maxTotalBytes, Total: true}}
+			return nil
+		}
+
+		if spool == nil {
+			var err error
+			if spool, err = os.CreateTemp("", "sift-archive-*"); err != nil {
+				return fmt.Errorf("failed to index archive %q: %w", archive, err)
+			}
+			index.closer, index.spool = spool, spool.Name()
+		}
+
+		reader, err := open()
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(spool, reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to index archive %q: %w", archive, err)
+		}
+
+		section := io.NewSectionReader(spool, offset, written)
+		index.members[name] = archiveEntry{size: size, open: func() (io.ReadCloser, error) {
+			// a section reads the spool file at its own offsets, so members can be read concurrently
+			return io.NopCloser(io.NewSectionReader(section, 0, section.Size())), nil
+		}}
+		offset += written
+		return nil
+	})
+}
+
+// openTarMember opens a single member of a tar archive, reading the archive up to it
+func openTarMember(archive, member string) (io.ReadCloser, error) {
+	reader, stream, err := openTar(archive)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			stream.Close()
+			return nil, fmt.Errorf("archive %q has no member %q", archive, member)
+		}
+		if err != nil {
+			stream.Close()
+			return nil, fmt.Errorf("failed to read archive %q: %w", archive, err)
+		}
+		if header.Typeflag == tar.TypeReg && cleanMember(header.Name) == member {
+			return struct {
+				io.Reader
+				io.Closer
+			}{reader, stream}, nil
+		}
+	}
+}
+
+// walkArchive calls visit for each regular file of a zip or tar archive, in archive order.
+// open is only valid during the call.
+func walkArchive(archive string, visit func(name string, size int64, open func() (io.ReadCloser, error)) error) error {
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return walkZip(archive, visit)
+	}
+	return walkTar(archive, visit)
+}
+
+func walkZip(archive string, visit func(string, int64, func() (io.ReadCloser, error)) error) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %q: %w", archive, err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		if err := visit(cleanMember(file.Name), int64(file.UncompressedSize64), file.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(archive string, visit func(string, int64, func() (io.ReadCloser, error)) error) error {
+	reader, stream, err := openTar(archive)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %q: %w", archive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		open := func() (io.ReadCloser, error) {
+			return io.NopCloser(reader), nil
+		}
+		if err := visit(cleanMember(header.Name), header.Size, open); err != nil {
+			return err
+		}
+	}
+}
+
+// openTar opens a tar archive for reading; closing the returned stream closes the archive
+func openTar(archive string) (*tar.Reader, io.Closer, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive %q: %w", archive, err)
+	}
+	// compressed tarballs (.tar.gz, .tar.zst, .tar.bz2) are detected by their magic bytes
+	stream, err := decompress(file, archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tar.NewReader(stream), stream, nil
+}
@@ -0,0 +1,355 @@
+package fetch_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// archiveFiles are the members of the test archives, in archive order
+var archiveFiles = []struct {
+	name    string
+	content string
+}{
+	{"README.md", "# Bundle"},
+	{"docs/intro.html", "<p>intro</p>"},
+	{"./docs/guide.md", "# Guide"},
+	{"docs/drafts/todo.txt", "todo"},
+	{"docs/logo.png", "png"},
+	{".hidden/notes.md", "hidden"},
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if _, err := w.Create("docs/"); err != nil {
+		t.Fatalf("failed to add directory: %v", err)
+	}
+	for _, file := range archiveFiles {
+		f, err := w.Create(file.name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", file.name, err)
+		}
+		io.WriteString(f, file.content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	if err := w.WriteHeader(&tar.Header{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatalf("failed to add directory: %v", err)
+	}
+	for _, file := range archiveFiles {
+		header := &tar.Header{Name: file.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(file.content))}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatalf("failed to add %s: %v", file.name, err)
+		}
+		io.WriteString(w, file.content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to write gzip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestExpandSources_Archives(t *testing.T) {
+	root := t.TempDir()
+	bundle := filepath.Join(root, "bundle.zip")
+	export := filepath.Join(root, "export.tar.gz")
+	writeZip(t, bundle)
+	writeTarGz(t, export)
+
+	members := func(archive string, names ...string) []string {
+		var out []string
+		for _, name := range names {
+			out = append(out, archive+"!"+name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		sources  []string
+		opts     fetch.ExpandOptions
+		expected []string
+	}{
+		{
+			name:     "zip expands to documents",
+			sources:  []string{bundle},
+			expected: members(bundle, "README.md", "docs/intro.html", "docs/guide.md", "docs/drafts/todo.txt"),
+		},
+		{
+			name:     "compressed tarball expands to documents",
+			sources:  []string{export},
+			expected: members(export, "README.md", "docs/intro.html", "docs/guide.md", "docs/drafts/todo.txt"),
+		},
+		{
+			name:     "member glob",
+			sources:  []string{bundle + "!docs/**/*.md"},
+			expected: members(bundle, "docs/guide.md"),
+		},
+		{
+			name:     "member glob matches any extension",
+			sources:  []string{export + "!docs/*"},
+			expected: members(export, "docs/intro.html", "docs/guide.md", "docs/logo.png"),
+		},
+		{
+			name:     "include and exclude patterns",
+			sources:  []string{bundle},
+			opts:     fetch.ExpandOptions{Include: []string{"*.md", "*.txt"}, Exclude: []string{"drafts/"}},
+			expected: members(bundle, "README.md", "docs/guide.md"),
+		},
+		{
+			name:     "max depth",
+			sources:  []string{export},
+			opts:     fetch.ExpandOptions{MaxDepth: 1},
+			expected: members(export, "README.md"),
+		},
+		{
+			name:     "member source passes through",
+			sources:  []string{bundle + "!docs/intro.html"},
+			expected: members(bundle, "docs/intro.html"),
+		},
+		{
+			name:     "glob matching no members passes through",
+			sources:  []string{bundle + "!*.pdf"},
+			expected: []string{bundle + "!*.pdf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetch.ExpandSources(tt.sources, tt.opts)
+			if err != nil {
+				t.Fatalf("ExpandSources() error = %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("ExpandSources() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetContent_ArchiveMembers(t *testing.T) {
+	root := t.TempDir()
+	bundle := filepath.Join(root, "bundle.zip")
+	export := filepath.Join(root, "export.tar.gz")
+	writeZip(t, bundle)
+	writeTarGz(t, export)
+
+	tests := []struct {
+		name        string
+		source      string
+		expected    string
+		expectError string
+	}{
+		{"zip member", bundle + "!docs/intro.html", "<p>intro</p>", ""},
+		{"zip member with ./ prefix", bundle + "!docs/guide.md", "# Guide", ""},
+		{"tarball member", export + "!docs/drafts/todo.txt", "todo", ""},
+		{"tarball member with ./ prefix", export + "!./docs/guide.md", "# Guide", ""},
+		{"missing member", bundle + "!docs/missing.md", "", "has no member"},
+		{"missing tarball member", export + "!docs/missing.md", "", "has no member"},
+		{"archive without matching documents", bundle, "", "contains no matching documents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := fetch.GetContent(context.Background(), tt.source, fetch.Options{})
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("GetContent() error = %v, want it to contain %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetContent() error = %v", err)
+			}
+			defer content.Close()
+
+			data, err := io.ReadAll(content)
+			if err != nil {
+				t.Fatalf("failed to read content: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("content = %q, want %q", data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClient_ArchiveIndexedOnce(t *testing.T) {
+	// spool files go to the temporary directory, which should be empty once the client is closed
+	spoolDir := t.TempDir()
+	t.Setenv("TMPDIR", spoolDir)
+
+	root := t.TempDir()
+	bundle := filepath.Join(root, "bundle.zip")
+	export := filepath.Join(root, "export.tar.gz")
+	writeZip(t, bundle)
+	writeTarGz(t, export)
+
+	client, err := fetch.NewClient(fetch.Options{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	var sources []string
+	for _, archive := range []string{bundle, export} {
+		for _, file := range archiveFiles {
+			sources = append(sources, archive+"!"+file.name)
+		}
+	}
+	client.SelectArchiveMembers(sources)
+
+	read := func(source string) (string, error) {
+		content, err := client.GetContent(context.Background(), source)
+		if err != nil {
+			return "", err
+		}
+		defer content.Close()
+		data, err := io.ReadAll(content)
+		return string(data), err
+	}
+
+	for _, archive := range []string{bundle, export} {
+		if _, err := read(archive + "!README.md"); err != nil {
+			t.Fatalf("GetContent() error = %v", err)
+		}
+		// later members are read from the index, not by scanning the archive again
+		if err := os.Remove(archive); err != nil {
+			t.Fatalf("failed to remove %s: %v", archive, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, archive := range []string{bundle, export} {
+		for _, file := range archiveFiles[1:] {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data, err := read(archive + "!" + file.name)
+				if err != nil {
+					t.Errorf("GetContent(%q) error = %v", file.name, err)
+				} else if data != file.content {
+					t.Errorf("GetContent(%q) = %q, want %q", file.name, data, file.content)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(spoolDir); len(entries) > 0 {
+		t.Errorf("temporary directory holds %d files after Close(), want none", len(entries))
+	}
+}
+
+func TestClient_ArchiveSpool(t *testing.T) {
+	root := t.TempDir()
+	export := filepath.Join(root, "export.tar.gz")
+	writeTarGz(t, export)
+
+	tests := []struct {
+		name      string
+		opts      fetch.Options
+		selected  []string
+		spoolSize int64
+		member    string // member read after the first selected one
+		expected  string
+		expectErr string
+	}{
+		{
+			name:      "only selected members",
+			selected:  []string{"README.md", "docs/guide.md"},
+			spoolSize: int64(len("# Bundle") + len("# Guide")),
+			member:    "docs/intro.html", // not selected, so read from the archive directly
+			expected:  "<p>intro</p>",
+		},
+		{
+			name:      "within the total limit",
+			opts:      fetch.Options{MaxTotalBytes: 10},
+			selected:  []string{"README.md", "docs/drafts/todo.txt"},
+			spoolSize: int64(len("# Bundle")),
+			member:    "docs/drafts/todo.txt",
+			expectErr: "10-byte total limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spoolDir := t.TempDir()
+			t.Setenv("TMPDIR", spoolDir)
+
+			client, err := fetch.NewClient(tt.opts)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			defer client.Close()
+
+			var sources []string
+			for _, member := range tt.selected {
+				sources = append(sources, export+"!"+member)
+			}
+			client.SelectArchiveMembers(sources)
+
+			content, err := client.GetContent(context.Background(), sources[0])
+			if err != nil {
+				t.Fatalf("GetContent() error = %v", err)
+			}
+			content.Close()
+
+			entries, _ := os.ReadDir(spoolDir)
+			if len(entries) != 1 {
+				t.Fatalf("temporary directory holds %d files, want the spool file", len(entries))
+			}
+			info, err := entries[0].Info()
+			if err != nil {
+				t.Fatalf("failed to stat spool file: %v", err)
+			}
+			if info.Size() != tt.spoolSize {
+				t.Errorf("spool file size = %d, want %d", info.Size(), tt.spoolSize)
+			}
+
+			content, err = client.GetContent(context.Background(), export+"!"+tt.member)
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Fatalf("GetContent(%q) error = %v, want it to contain %q", tt.member, err, tt.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetContent(%q) error = %v", tt.member, err)
+			}
+			defer content.Close()
+			if data, _ := io.ReadAll(content); string(data) != tt.expected {
+				t.Errorf("GetContent(%q) = %q, want %q", tt.member, data, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultCacheMaxAge is how long cached responses are served without revalidation. By default
+// every cached response is revalidated, so the cache never serves a page older than the server's.
+const DefaultCacheMaxAge time.Duration = 0
+
+// DefaultCacheDir returns the default on-disk HTTP cache location under the user cache directory
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "sift", "http"), nil
+}
+
+// cacheEntry is the metadata stored alongside a cached response body
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	StoredAt     time.Time `json:"stored_at"` // time the entry was stored or last revalidated
+}
+
+// diskCache stores HTTP response bodies on disk, keyed by URL and the custom request headers.
+// Each entry is a pair of files named by the SHA-256 of the key: <key>.json (metadata) and <key>.body.
+// Files are written to temporary names and renamed into place, so readers never see partial entries.
+type diskCache struct {
+	dir     string
+	maxAge  time.Duration
+	headers string // custom request headers, which may carry credentials, as part of every key
+}
+
+// newDiskCache creates the cache directory if needed. Responses to requests with custom headers
+// are cached apart from those without, so that a response fetched with credentials (such as an
+// Authorization header) is never served to a request without them.
+func newDiskCache(dir string, maxAge time.Duration, headers http.Header) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %q: %w", dir, err)
+	}
+
+	var key strings.Builder
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return strings.Compare(http.CanonicalHeaderKey(a), http.CanonicalHeaderKey(b))
+	})
+	for _, name := range names {
+		fmt.Fprintf(&key, "\n%s: %s", http.CanonicalHeaderKey(name), strings.Join(headers[name], ", "))
+	}
+
+	return &diskCache{dir: dir, maxAge: maxAge, headers: key.String()}, nil
+}
+
+// paths returns the metadata and body file paths for a URL
+func (dc *diskCache) paths(url string) (meta, body string) {
+	sum := sha256.Sum256([]byte(url + dc.headers))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(dc.dir, key+".json"), filepath.Join(dc.dir, key+".body")
+}
+
+// lookup returns the cache entry for a URL, or nil if there is none
+func (dc *diskCache) lookup(url string) *cacheEntry {
+	metaPath, bodyPath := dc.paths(url)
+
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		slog.Debug("Ignoring unreadable cache entry", "url", url, "error", err)
+		return nil
+	}
+
+	// metadata without a body is unusable
+	if _, err := os.Stat(bodyPath); err != nil {
+		return nil
+	}
+
+	return &entry
+}
+
+// isFresh reports whether an entry can be served without revalidation
+func (dc *diskCache) isFresh(entry *cacheEntry, now time.Time) bool {
+	return dc.maxAge > 0 && now.Sub(entry.StoredAt) < dc.maxAge
+}
+
+// open returns a reader for a cached body
+func (dc *diskCache) open(entry *cacheEntry) (io.ReadCloser, error) {
+	_, bodyPath := dc.paths(entry.URL)
+	return os.Open(bodyPath)
+}
+
+// conditionalHeaders returns revalidation headers for an entry (nil entries yield none)
+func conditionalHeaders(entry *cacheEntry) http.Header {
+	header := http.Header{}
+	if entry == nil {
+		return header
+	}
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+	return header
+}
+
+// touch marks an entry as revalidated now
+func (dc *diskCache) touch(entry *cacheEntry) {
+	entry.StoredAt = time.Now()
+	if err := dc.writeMeta(entry); err != nil {
+		slog.Debug("Failed to update cache entry", "url", entry.URL, "error", err)
+	}
+}
+
+// writeMeta atomically writes an entry's metadata file
+func (dc *diskCache) writeMeta(entry *cacheEntry) error {
+	metaPath, _ := dc.paths(entry.URL)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dc.dir, "meta-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), metaPath)
+}
+
+// isCacheable reports whether a response may be stored: responses marked no-store are not, nor
+// are those marked private, which are meant for a single user (as logged in by cookies, say)
+func isCacheable(resp *http.Response) bool {
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "private":
+			return false
+		}
+	}
+	return true
+}
+
+// store wraps a response body so that it is written to the cache as it is read.
+// The entry is committed only if the body is read to the end without errors.
+func (dc *diskCache) store(url string, resp *http.Response, body io.ReadCloser) io.ReadCloser {
+	tmp, err := os.CreateTemp(dc.dir, "body-*.tmp")
+	if err != nil {
+		slog.Debug("Failed to create cache file", "url", url, "error", err)
+		return body
+	}
+
+	return &cacheWriter{
+		ReadCloser: body,
+		cache:      dc,
+		tmp:        tmp,
+		entry: cacheEntry{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			ContentType:  resp.Header.Get("Content-Type"),
+		},
+	}
+}
+
+// cacheWriter tees a response body into a temporary cache file
+type cacheWriter struct {
+	io.ReadCloser
+	cache    *diskCache
+	tmp      *os.File
+	entry    cacheEntry
+	complete bool // body was read to EOF
+	failed   bool // a read or write error occurred
+}
+
+func (cw *cacheWriter) Read(p []byte) (int, error) {
+	n, err := cw.ReadCloser.Read(p)
+	if n > 0 && !cw.failed {
+		if _, werr := cw.tmp.Write(p[:n]); werr != nil {
+			cw.failed = true
+		}
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		cw.complete = true
+	case err != nil:
+		cw.failed = true
+	}
+	return n, err
+}
+
+// Close commits the cache entry if the whole body was read, then closes the response body
+func (cw *cacheWriter) Close() error {
+	err := cw.ReadCloser.Close()
+
+	tmpName := cw.tmp.Name()
+	if closeErr := cw.tmp.Close(); closeErr != nil {
+		cw.failed = true
+	}
+	if !cw.complete || cw.failed {
+		os.Remove(tmpName)
+		return err
+	}
+
+	_, bodyPath := cw.cache.paths(cw.entry.URL)
+	if renameErr := os.Rename(tmpName, bodyPath); renameErr != nil {
+		os.Remove(tmpName)
+		slog.Debug("Failed to commit cache body", "url", cw.entry.URL, "error", renameErr)
+		return err
+	}
+
+	cw.entry.StoredAt = time.Now()
+	if metaErr := cw.cache.writeMeta(&cw.entry); metaErr != nil {
+		slog.Debug("Failed to commit cache metadata", "url", cw.entry.URL, "error", metaErr)
+	}
+
+	return err
+}
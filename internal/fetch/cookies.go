@@ -0,0 +1,80 @@
+package fetch
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in Netscape cookie files exported by curl and browsers
+const httpOnlyPrefix = "#HttpOnly_"
+
+// loadCookieFile reads a Netscape-format cookie file into a cookie jar.
+// Each non-comment line holds seven tab-separated fields:
+// domain, include subdomains, path, secure, expiry (unix seconds), name, value.
+func loadCookieFile(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookie file %q line %d: expected 7 tab-separated fields, got %d", path, lineNumber, len(fields))
+		}
+
+		domain := fields[0]
+		includeSubdomains := strings.EqualFold(fields[1], "TRUE")
+		secure := strings.EqualFold(fields[3], "TRUE")
+
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if includeSubdomains {
+			cookie.Domain = domain
+		}
+		if expiry, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: "/"}, []*http.Cookie{cookie})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie file %q: %w", path, err)
+	}
+
+	return jar, nil
+}
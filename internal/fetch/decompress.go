@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionMagic identifies compressed content by its leading bytes
+var compressionMagic = []struct {
+	name  string
+	magic []byte
+	valid func(head []byte) bool // checks the bytes after the magic, nil if the magic is enough
+}{
+	{"gzip", []byte{0x1f, 0x8b}, nil},
+	{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}, nil},
+	{"bzip2", []byte("BZh"), isBzip2Header},
+}
+
+// bzip2 streams open with a block, or end the stream right away if empty, each marked by a
+// 48-bit magic number
+var (
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// isBzip2Header reports whether head continues a "BZh" signature as bzip2 does, with a block size
+// level from 1 to 9 and the magic of a block or stream end, so that text starting with "BZh" is
+// not mistaken for bzip2
+func isBzip2Header(head []byte) bool {
+	if len(head) < 10 || head[3] < '1' || head[3] > '9' {
+		return false
+	}
+	return bytes.Equal(head[4:10], bzip2BlockMagic) || bytes.Equal(head[4:10], bzip2EndMagic)
+}
+
+// compressedExtensions are the file extensions of the compression formats decompressed on the fly
+var compressedExtensions = []string{".gz", ".zst", ".bz2"}
+
+// trimCompressedExtension removes a compression extension from a file name, so that "page.html.gz"
+// is treated as "page.html"
+func trimCompressedExtension(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	for _, compressedExt := range compressedExtensions {
+		if ext == compressedExt {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// decompressor reads decompressed content and closes both the decoder and the underlying file
+type decompressor struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decompressor) Close() error {
+	var first error
+	for _, closer := range d.closers {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// decompress detects gzip, zstd, and bzip2 content by its magic bytes and decompresses it on the fly.
+// Other content is returned unchanged. Size limits apply to the decompressed stream (see Client.GetContent),
+// which guards against decompression bombs.
+func decompress(file io.ReadCloser, source string) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(file)
+	head, _ := buffered.Peek(10) // short files cannot be compressed
+
+	for _, format := range compressionMagic {
+		if !bytes.HasPrefix(head, format.magic) || (format.valid != nil && !format.valid(head)) {
+			continue
+		}
+
+		var reader io.Reader
+		closers := []io.Closer{file}
+		switch format.name {
+		case "gzip":
+			gz, err := gzip.NewReader(buffered)
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to decompress %q: %w", source, err)
+			}
+			reader, closers = gz, append(closers, gz)
+		case "zstd":
+			zr, err := zstd.NewReader(buffered, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to decompress %q: %w", source, err)
+			}
+			readCloser := zr.IOReadCloser()
+			reader, closers = readCloser, append(closers, readCloser)
+		case "bzip2":
+			reader = bzip2.NewReader(buffered)
+		}
+
+		slog.Debug("Decompressing file", "source", source, "compression", format.name)
+		return &decompressor{Reader: reader, closers: closers}, nil
+	}
+
+	return &readCloser{Reader: buffered, Closer: file}, nil
+}
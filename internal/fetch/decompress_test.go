@@ -0,0 +1,139 @@
+package fetch_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+	"github.com/klauspost/compress/zstd"
+)
+
+// bzip2Notes is "Plain notes compressed with bzip2.\n" compressed with bzip2, which the standard library cannot write
+var bzip2Notes = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x62, 0x68,
+	0xb7, 0x5b, 0x00, 0x00, 0x03, 0xdb, 0x80, 0x00, 0x10, 0x40, 0x01, 0x10,
+	0x00, 0x40, 0x00, 0x3e, 0x67, 0xdc, 0x90, 0x20, 0x00, 0x31, 0x46, 0x8c,
+	0x81, 0xa3, 0x4c, 0x8d, 0x0a, 0x00, 0xd0, 0x34, 0x69, 0x9a, 0x91, 0xc7,
+	0xb9, 0x9b, 0x25, 0xb9, 0x77, 0x19, 0x2a, 0xc0, 0xc0, 0xe8, 0x6c, 0x32,
+	0x48, 0x74, 0xce, 0x05, 0x42, 0x1f, 0x17, 0x72, 0x45, 0x38, 0x50, 0x90,
+	0x62, 0x68, 0xb7, 0x5b,
+}
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("failed to gzip content: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to gzip content: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, content []byte) []byte {
+	t.Helper()
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("failed to create zstd encoder: %v", err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(content, nil)
+}
+
+func TestGetContentDecompression(t *testing.T) {
+	page := []byte("<html><body><p>Archived page</p></body></html>")
+
+	tests := []struct {
+		name        string
+		file        string
+		content     []byte
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "gzip",
+			file:     "page.html.gz",
+			content:  gzipBytes(t, page),
+			expected: string(page),
+		},
+		{
+			name:     "zstd",
+			file:     "app.log.zst",
+			content:  zstdBytes(t, []byte("line one\nline two\n")),
+			expected: "line one\nline two\n",
+		},
+		{
+			name:     "bzip2",
+			file:     "notes.txt.bz2",
+			content:  bzip2Notes,
+			expected: "Plain notes compressed with bzip2.\n",
+		},
+		{
+			name:     "detected by content, not extension",
+			file:     "page.html",
+			content:  gzipBytes(t, page),
+			expected: string(page),
+		},
+		{
+			name:     "uncompressed file is unchanged",
+			file:     "notes.gz",
+			content:  []byte("not actually compressed"),
+			expected: "not actually compressed",
+		},
+		{
+			name:     "text starting like bzip2 is unchanged",
+			file:     "notes.md",
+			content:  []byte("BZh9 is the bzip2 signature at its highest block size level."),
+			expected: "BZh9 is the bzip2 signature at its highest block size level.",
+		},
+		{
+			name:        "decompressed size is limited",
+			file:        "bomb.txt.gz",
+			content:     gzipBytes(t, make([]byte, 1024*1024)),
+			expectError: "exceeds the 65536-byte per-source limit",
+		},
+		{
+			name:        "corrupt gzip",
+			file:        "corrupt.gz",
+			content:     []byte{0x1f, 0x8b, 0x00, 0x00},
+			expectError: "failed to decompress",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			content, err := fetch.GetContent(context.Background(), path, fetch.Options{MaxBytes: 64 * 1024})
+			var data []byte
+			if err == nil {
+				data, err = io.ReadAll(content)
+				content.Close()
+			}
+
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("content = %q, want %q", data, tt.expected)
+			}
+		})
+	}
+}
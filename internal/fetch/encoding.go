@@ -0,0 +1,156 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogs/chardet"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffLen is how much content is examined to decide whether it is text and in which encoding
+const sniffLen = 4096
+
+// minDetectConfidence is the chardet confidence (1-100) below which a guess is not trusted
+const minDetectConfidence = 30
+
+// declaredCharset matches an encoding declared in an HTML <meta> tag or an XML declaration
+var declaredCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)|^\s*<\?xml[^>]+encoding\s*=\s*["']([a-z0-9_:.\-]+)`)
+
+// boms are the byte order marks recognized at the start of content
+var boms = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// LookupEncoding returns the encoding for a WHATWG label such as "shift_jis", "windows-1252",
+// or "latin1". Returns an error for unknown labels.
+func LookupEncoding(label string) (encoding.Encoding, error) {
+	enc, _ := charset.Lookup(strings.TrimSpace(label))
+	if enc == nil {
+		return nil, fmt.Errorf("unknown character encoding %q", label)
+	}
+	return enc, nil
+}
+
+// decodeText transcodes text content to UTF-8, leaving binary content (PDFs, archives, images)
+// untouched. The encoding is overridden if set; otherwise it is detected from a byte order mark,
+// the charset parameter of contentType, a <meta charset> or XML declaration, and finally by
+// sniffing the content. A UTF-8 byte order mark is removed.
+func decodeText(content io.ReadCloser, contentType string, override encoding.Encoding, source string) io.ReadCloser {
+	buffered := bufio.NewReaderSize(content, sniffLen)
+	// a read error surfaces again on the next Read
+	head, _ := buffered.Peek(sniffLen)
+
+	if !isText(head, contentType) {
+		return &readCloser{Reader: buffered, Closer: content}
+	}
+
+	enc, name := override, "override"
+	if enc == nil {
+		enc, name = detectEncoding(head, contentType)
+	}
+	slog.Debug("Detected character encoding", "source", source, "encoding", name)
+
+	// a byte order mark takes precedence over any other declaration, as in the WHATWG decode algorithm
+	decoder := unicode.BOMOverride(enc.NewDecoder())
+	if enc == unicode.UTF8 {
+		decoder = unicode.BOMOverride(transform.Nop)
+	}
+	return &readCloser{Reader: transform.NewReader(buffered, decoder), Closer: content}
+}
+
+// readCloser combines a reader with the Close method of the content it reads from
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// isText reports whether content is text, by its media type or, for files, stdin, and generic
+// media types, by sniffing its first bytes
+func isText(head []byte, contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "/xml"), strings.HasSuffix(mediaType, "+xml"):
+		// EPUB and OOXML are zipped XML, but report their own media types
+		return true
+	case mediaType == "application/json", mediaType == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// detectEncoding returns the encoding of text content and its name, for logging
+func detectEncoding(head []byte, contentType string) (encoding.Encoding, string) {
+	for _, b := range boms {
+		if bytes.HasPrefix(head, b.bom) {
+			enc, _ := charset.Lookup(b.name)
+			return enc, b.name
+		}
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if enc, name := charset.Lookup(params["charset"]); enc != nil {
+			return enc, name
+		}
+	}
+
+	if match := declaredCharset.FindSubmatch(head); match != nil {
+		label := string(match[1]) + string(match[2])
+		if enc, name := charset.Lookup(label); enc != nil {
+			// a document that declares UTF-16 but is readable as ASCII is really UTF-8
+			if strings.HasPrefix(name, "utf-16") {
+				return unicode.UTF8, "utf-8"
+			}
+			return enc, name
+		}
+	}
+
+	if validUTF8Prefix(head) {
+		return unicode.UTF8, "utf-8"
+	}
+
+	if result, err := chardet.NewTextDetector().DetectBest(head); err == nil && result.Confidence >= minDetectConfidence {
+		if enc, name := charset.Lookup(result.Charset); enc != nil {
+			return enc, name
+		}
+	}
+
+	// the most common encoding of legacy Western text, and the WHATWG default
+	return charmap.Windows1252, "windows-1252"
+}
+
+// validUTF8Prefix reports whether head is valid UTF-8, ignoring a rune cut off at its end
+func validUTF8Prefix(head []byte) bool {
+	for i := len(head) - 1; i >= 0 && i > len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				head = head[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(head)
+}
@@ -0,0 +1,153 @@
+package fetch_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestGetContentEncoding(t *testing.T) {
+	japaneseText := "吾輩は猫である。名前はまだ無い。どこで生れたかとんと見当がつかぬ。何でも薄暗いじめじめした所でニャーニャー泣いていた事だけは記憶している。"
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().String(japaneseText)
+	if err != nil {
+		t.Fatalf("failed to encode Shift_JIS: %v", err)
+	}
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String("naïve café")
+	if err != nil {
+		t.Fatalf("failed to encode UTF-16: %v", err)
+	}
+	pdf := "%PDF-1.7\n\xe2\xe3\xcf\xd3\n1 0 obj\n"
+
+	tests := []struct {
+		name        string
+		content     string
+		contentType string // served over HTTP when set, otherwise read from a file
+		encoding    string
+		expected    string
+	}{
+		{
+			name:     "UTF-8 is unchanged",
+			content:  "naïve café",
+			expected: "naïve café",
+		},
+		{
+			name:     "UTF-8 byte order mark is removed",
+			content:  "\xef\xbb\xbfnaïve café",
+			expected: "naïve café",
+		},
+		{
+			name:     "UTF-16 byte order mark",
+			content:  utf16,
+			expected: "naïve café",
+		},
+		{
+			name:        "HTTP charset",
+			content:     "na\xefve caf\xe9",
+			contentType: "text/plain; charset=iso-8859-1",
+			expected:    "naïve café",
+		},
+		{
+			name:        "HTTP charset overrides meta charset",
+			content:     `<meta charset="shift_jis"><p>caf` + "\xe9</p>",
+			contentType: "text/html; charset=windows-1252",
+			expected:    `<meta charset="shift_jis"><p>café</p>`,
+		},
+		{
+			name:        "meta charset",
+			content:     `<html><head><meta charset="windows-1252"></head><body>caf` + "\xe9 \x93quoted\x94</body></html>",
+			contentType: "text/html",
+			expected:    `<html><head><meta charset="windows-1252"></head><body>café “quoted”</body></html>`,
+		},
+		{
+			name:     "meta http-equiv charset in a file",
+			content:  `<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"></head><body>` + shiftJIS + `</body></html>`,
+			expected: `<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"></head><body>` + japaneseText + `</body></html>`,
+		},
+		{
+			name:        "XML declaration",
+			content:     `<?xml version="1.0" encoding="ISO-8859-1"?><rss><title>caf` + "\xe9</title></rss>",
+			contentType: "application/rss+xml",
+			expected:    `<?xml version="1.0" encoding="ISO-8859-1"?><rss><title>café</title></rss>`,
+		},
+		{
+			name:     "sniffed Shift_JIS",
+			content:  shiftJIS,
+			expected: japaneseText,
+		},
+		{
+			name:     "sniffed Windows-1252",
+			content:  "The caf\xe9 served cr\xe8me br\xfbl\xe9e \x96 na\xefvely priced at \x80" + "5.",
+			expected: "The café served crème brûlée – naïvely priced at €5.",
+		},
+		{
+			name:     "override",
+			content:  shiftJIS,
+			encoding: "shift_jis",
+			expected: japaneseText,
+		},
+		{
+			name:     "override does not apply to binary content",
+			content:  pdf,
+			encoding: "shift_jis",
+			expected: pdf,
+		},
+		{
+			name:        "binary content is unchanged",
+			content:     pdf,
+			contentType: "application/pdf",
+			expected:    pdf,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), "source")
+			if tt.contentType != "" {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/robots.txt" {
+						http.NotFound(w, r)
+						return
+					}
+					w.Header().Set("Content-Type", tt.contentType)
+					io.WriteString(w, tt.content)
+				}))
+				defer server.Close()
+				source = server.URL + "/source"
+			} else if err := os.WriteFile(source, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write source: %v", err)
+			}
+
+			client, err := fetch.NewClient(fetch.Options{Encoding: tt.encoding})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			content, err := client.GetContent(context.Background(), source)
+			if err != nil {
+				t.Fatalf("GetContent() error = %v", err)
+			}
+			defer content.Close()
+
+			data, err := io.ReadAll(content)
+			if err != nil {
+				t.Fatalf("failed to read content: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("content = %q, want %q", data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewClientUnknownEncoding(t *testing.T) {
+	if _, err := fetch.NewClient(fetch.Options{Encoding: "klingon"}); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+}
@@ -0,0 +1,308 @@
+package fetch
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxFiles is the default limit on files expanded from directory and glob sources
+const DefaultMaxFiles = 1000
+
+// DocumentExtensions lists the file extensions picked up when a directory is expanded without include patterns
+var DocumentExtensions = []string{".html", ".htm", ".xhtml", ".md", ".markdown", ".txt", ".pdf", ".docx", ".odt", ".epub"}
+
+// ExpandOptions configures how directory and glob sources are expanded into files.
+// The zero value expands directories recursively, honoring .gitignore files and skipping symlinks.
+type ExpandOptions struct {
+	Include        []string // glob patterns that files must match (default: files with DocumentExtensions)
+	Exclude        []string // glob patterns for files and directories to skip
+	MaxDepth       int      // directory levels to descend, where 1 means only top-level files (0 for unlimited)
+	MaxFiles       int      // max files expanded across all sources (0 uses DefaultMaxFiles)
+	FollowSymlinks bool     // follow symlinked files and directories (cycles are skipped)
+	NoGitignore    bool     // include files ignored by .gitignore
+}
+
+// expander accumulates files from directory and glob sources
+type expander struct {
+	opts     ExpandOptions
+	include  []globPattern
+	exclude  []globPattern
+	maxFiles int
+	files    []string        // files expanded from the current source
+	matched  int             // files matched by the current source, including duplicates
+	seen     map[string]bool // files expanded from all sources
+}
+
+// ExpandSources expands directory and glob sources into individual file sources.
+// Directories are walked recursively; glob patterns support "*", "?", "[...]" and "**" (any number of directories).
+// Include and exclude patterns without a slash match file names at any depth; patterns with a slash match
+// paths relative to the directory (or the static prefix of the glob).
+// Zip and tar archives (including compressed tarballs) expand into "archive!member" sources, filtered like directories;
+// "archive!pattern" sources select members with a glob pattern.
+// Hidden files and directories are skipped. URLs, "-", and plain files are passed through unchanged, as are globs that match nothing,
+// so that they fail with the usual per-source warning. Files are returned in lexical order within each source.
+//
+// Returns an error if expansion exceeds the file limit or a directory source (or the static prefix
+// of a glob) cannot be read; directories below it that cannot be read are skipped.
+func ExpandSources(sources []string, opts ExpandOptions) ([]string, error) {
+	e := &expander{
+		opts:     opts,
+		maxFiles: opts.MaxFiles,
+		seen:     make(map[string]bool),
+	}
+	if e.maxFiles <= 0 {
+		e.maxFiles = DefaultMaxFiles
+	}
+	for _, pattern := range opts.Include {
+		e.include = append(e.include, parseGlobPattern(pattern, false))
+	}
+	for _, pattern := range opts.Exclude {
+		e.exclude = append(e.exclude, parseGlobPattern(pattern, false))
+	}
+
+	var expanded []string
+	for _, source := range sources {
+		if source == "-" || strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			expanded = append(expanded, source)
+			continue
+		}
+
+		info, err := os.Stat(source)
+		archive, member, inArchive := splitArchiveSource(source)
+		switch {
+		case err == nil && info.Mode().IsRegular() && isArchive(source):
+			if err := e.expandArchive(source, nil); err != nil {
+				return nil, err
+			}
+			if e.matched == 0 {
+				e.files = append(e.files, source)
+			}
+		case err != nil && inArchive && hasGlobMeta(member):
+			pattern := parseGlobPattern("/"+member, false)
+			if err := e.expandArchive(archive, &pattern); err != nil {
+				return nil, err
+			}
+			if e.matched == 0 {
+				e.files = append(e.files, source)
+			}
+		case err == nil && info.IsDir():
+			if err := e.expandRoot(source, nil); err != nil {
+				return nil, err
+			}
+		case err != nil && hasGlobMeta(source):
+			root, pattern := splitGlob(source)
+			if rootInfo, err := os.Stat(root); err != nil || !rootInfo.IsDir() {
+				e.files = append(e.files, source)
+				break
+			}
+			if err := e.expandRoot(root, &pattern); err != nil {
+				return nil, err
+			}
+			if e.matched == 0 {
+				e.files = append(e.files, source)
+			}
+		default:
+			e.files = append(e.files, source)
+		}
+
+		expanded = append(expanded, e.files...)
+		e.files = e.files[:0]
+		e.matched = 0
+	}
+
+	return expanded, nil
+}
+
+// expandArchive adds the matching members of a zip or tar archive as "archive!member" sources;
+// pattern is nil for plain archive sources
+func (e *expander) expandArchive(archive string, pattern *globPattern) error {
+	members, err := archiveMembers(archive)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if e.skippedMember(member) || !e.included(member, pattern) {
+			continue
+		}
+		if err := e.add(archive + ArchiveSeparator + member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skippedMember reports whether an archive member is hidden, excluded, or deeper than the depth limit,
+// as files in a directory walk would be
+func (e *expander) skippedMember(member string) bool {
+	segments := strings.Split(member, "/")
+	if e.opts.MaxDepth > 0 && len(segments) > e.opts.MaxDepth {
+		return true
+	}
+	for i, segment := range segments {
+		isDir := i < len(segments)-1
+		if strings.HasPrefix(segment, ".") || matchAny(e.exclude, strings.Join(segments[:i+1], "/"), isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGlob splits a glob source into its static directory prefix and an anchored pattern for the rest
+func splitGlob(source string) (string, globPattern) {
+	segments := strings.Split(filepath.ToSlash(source), "/")
+
+	static := 0
+	for static < len(segments)-1 && !hasGlobMeta(segments[static]) {
+		static++
+	}
+
+	root := strings.Join(segments[:static], "/")
+	switch {
+	case root == "" && strings.HasPrefix(source, "/"):
+		root = "/"
+	case root == "":
+		root = "."
+	}
+
+	return filepath.FromSlash(root), parseGlobPattern("/"+strings.Join(segments[static:], "/"), false)
+}
+
+// expandRoot walks a directory, adding matching files; pattern is nil for plain directory sources
+func (e *expander) expandRoot(root string, pattern *globPattern) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory %q: %w", root, err)
+	}
+
+	var ignores ignoreStack
+	if !e.opts.NoGitignore {
+		ignores = ancestorIgnores(absRoot)
+	}
+
+	visited := make(map[string]bool)
+	if e.opts.FollowSymlinks {
+		if real, err := filepath.EvalSymlinks(absRoot); err == nil {
+			visited[real] = true
+		}
+	}
+
+	return e.walk(root, absRoot, "", 0, ignores, pattern, visited)
+}
+
+// walk adds matching files below dir; rel is dir's slash-separated path relative to the root
+func (e *expander) walk(dir, absDir, rel string, depth int, ignores ignoreStack, pattern *globPattern, visited map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// below the root, an unreadable directory is skipped rather than failing the whole source
+		if rel != "" {
+			slog.Warn("Skipping unreadable directory", "dir", dir, "error", err)
+			return nil
+		}
+		return fmt.Errorf("failed to read directory %q: %w", dir, err)
+	}
+
+	if !e.opts.NoGitignore {
+		ignores = ignores.with(absDir)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		entryPath := filepath.Join(dir, name)
+		entryAbs := filepath.Join(absDir, name)
+		entryRel := path.Join(rel, name)
+
+		isDir := entry.IsDir()
+		isRegular := entry.Type().IsRegular()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if !e.opts.FollowSymlinks {
+				continue
+			}
+			info, err := os.Stat(entryPath)
+			if err != nil {
+				continue // broken link
+			}
+			isDir, isRegular = info.IsDir(), info.Mode().IsRegular()
+		}
+
+		// hidden files and directories (including .git) are skipped, as shells do for globs
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if ignores.ignored(entryAbs, isDir) || matchAny(e.exclude, entryRel, isDir) {
+			continue
+		}
+
+		if isDir {
+			if e.opts.MaxDepth > 0 && depth+1 >= e.opts.MaxDepth {
+				continue
+			}
+			// a glob only descends into directories its remaining segments can match
+			if pattern != nil && !pattern.matchesBelow(entryRel) {
+				continue
+			}
+			if e.opts.FollowSymlinks {
+				real, err := filepath.EvalSymlinks(entryAbs)
+				if err != nil || visited[real] {
+					continue // unresolvable or already walked (symlink cycle)
+				}
+				visited[real] = true
+			}
+			if err := e.walk(entryPath, entryAbs, entryRel, depth+1, ignores, pattern, visited); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isRegular || !e.included(entryRel, pattern) {
+			continue
+		}
+		if err := e.add(entryPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// included reports whether a file matches the glob pattern (if any) and the include patterns
+func (e *expander) included(rel string, pattern *globPattern) bool {
+	if pattern != nil && !pattern.match(rel, false) {
+		return false
+	}
+	if len(e.include) > 0 {
+		return matchAny(e.include, rel, false)
+	}
+	// an explicit glob selects files regardless of extension
+	if pattern != nil {
+		return true
+	}
+
+	// compressed documents such as "page.html.gz" are included too
+	ext := strings.ToLower(path.Ext(trimCompressedExtension(rel)))
+	for _, documentExt := range DocumentExtensions {
+		if ext == documentExt {
+			return true
+		}
+	}
+	return false
+}
+
+// add records a file, enforcing the file limit and skipping duplicates
+func (e *expander) add(file string) error {
+	e.matched++
+	if e.seen[file] {
+		return nil
+	}
+	if len(e.seen) >= e.maxFiles {
+		return fmt.Errorf("sources expand to more than %d files", e.maxFiles)
+	}
+	e.seen[file] = true
+	e.files = append(e.files, file)
+	return nil
+}
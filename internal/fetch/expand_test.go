@@ -0,0 +1,218 @@
+package fetch_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+// writeTree creates files (with placeholder content) below root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestExpandSources(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".git/HEAD":              "ref: refs/heads/main",
+		".gitignore":             "build/\n*.tmp.md\n!keep.tmp.md\n",
+		"index.html":             "<p>index</p>",
+		"image.png":              "png",
+		"notes/cake.md":          "# cake",
+		"notes/keep.tmp.md":      "kept",
+		"notes/scratch.tmp.md":   "ignored",
+		"notes/drafts/icing.md":  "# icing",
+		"notes/drafts/deep/a.md": "# deep",
+		"build/out.html":         "<p>generated</p>",
+		"archive/old.html.gz":    "gzip",
+		"archive/dump.gz":        "gzip",
+	})
+
+	rel := func(paths ...string) []string {
+		var out []string
+		for _, p := range paths {
+			out = append(out, filepath.Join(root, filepath.FromSlash(p)))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name        string
+		sources     []string
+		opts        fetch.ExpandOptions
+		expected    []string
+		expectError string
+	}{
+		{
+			name:     "directory expands to documents, honoring gitignore",
+			sources:  []string{root},
+			expected: rel("archive/old.html.gz", "index.html", "notes/cake.md", "notes/drafts/deep/a.md", "notes/drafts/icing.md", "notes/keep.tmp.md"),
+		},
+		{
+			name:     "gitignore can be disabled",
+			sources:  []string{root},
+			opts:     fetch.ExpandOptions{NoGitignore: true, Include: []string{"*.html"}},
+			expected: rel("build/out.html", "index.html"),
+		},
+		{
+			name:     "recursive glob",
+			sources:  []string{filepath.Join(root, "notes", "**", "*.md")},
+			expected: rel("notes/cake.md", "notes/drafts/deep/a.md", "notes/drafts/icing.md", "notes/keep.tmp.md"),
+		},
+		{
+			name:     "single-level glob matches any extension",
+			sources:  []string{filepath.Join(root, "*")},
+			expected: rel("image.png", "index.html"),
+		},
+		{
+			name:     "exclude prunes directories",
+			sources:  []string{filepath.Join(root, "notes")},
+			opts:     fetch.ExpandOptions{Exclude: []string{"drafts/"}},
+			expected: rel("notes/cake.md", "notes/keep.tmp.md"),
+		},
+		{
+			name:     "anchored exclude pattern",
+			sources:  []string{filepath.Join(root, "notes")},
+			opts:     fetch.ExpandOptions{Exclude: []string{"drafts/deep/**"}},
+			expected: rel("notes/cake.md", "notes/drafts/icing.md", "notes/keep.tmp.md"),
+		},
+		{
+			name:     "max depth",
+			sources:  []string{filepath.Join(root, "notes")},
+			opts:     fetch.ExpandOptions{MaxDepth: 2},
+			expected: rel("notes/cake.md", "notes/drafts/icing.md", "notes/keep.tmp.md"),
+		},
+		{
+			name:        "max files",
+			sources:     []string{root},
+			opts:        fetch.ExpandOptions{MaxFiles: 2},
+			expectError: "more than 2 files",
+		},
+		{
+			name:     "urls, stdin, files, and unmatched globs pass through",
+			sources:  []string{"https://example.com", "-", filepath.Join(root, "image.png"), filepath.Join(root, "*.pdf")},
+			expected: []string{"https://example.com", "-", filepath.Join(root, "image.png"), filepath.Join(root, "*.pdf")},
+		},
+		{
+			name:     "overlapping sources are deduplicated",
+			sources:  []string{filepath.Join(root, "notes", "drafts"), filepath.Join(root, "notes", "drafts", "*.md")},
+			expected: rel("notes/drafts/deep/a.md", "notes/drafts/icing.md"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetch.ExpandSources(tt.sources, tt.opts)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("ExpandSources() error = %v, want error containing %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandSources() error = %v", err)
+			}
+			if strings.Join(result, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("ExpandSources() =\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestExpandSources_Symlinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"docs/cake.md": "# cake"})
+	if err := os.Symlink(filepath.Join(root, "docs"), filepath.Join(root, "docs", "loop")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "docs", "cake.md"), filepath.Join(root, "docs", "link.md")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	source := filepath.Join(root, "docs")
+
+	result, err := fetch.ExpandSources([]string{source}, fetch.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("ExpandSources() error = %v", err)
+	}
+	if len(result) != 1 || result[0] != filepath.Join(source, "cake.md") {
+		t.Errorf("without following symlinks got %v, want only cake.md", result)
+	}
+
+	result, err = fetch.ExpandSources([]string{source}, fetch.ExpandOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("ExpandSources() error = %v", err)
+	}
+	expected := []string{filepath.Join(source, "cake.md"), filepath.Join(source, "link.md")}
+	if strings.Join(result, ",") != strings.Join(expected, ",") {
+		t.Errorf("following symlinks got %v, want %v (the directory cycle must not be walked)", result, expected)
+	}
+}
+
+func TestExpandSources_UnreadableDirectories(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions do not apply to root")
+	}
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"docs/cake.md":           "# cake",
+		"docs/private/secret.md": "# secret",
+		"docs/recipes/bread.md":  "# bread",
+	})
+	private := filepath.Join(root, "docs", "private")
+	if err := os.Chmod(private, 0); err != nil {
+		t.Fatalf("failed to make directory unreadable: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(private, 0o755) })
+
+	tests := []struct {
+		name     string
+		source   string
+		expected []string
+	}{
+		{
+			name:     "directory walk skips unreadable directories",
+			source:   filepath.Join(root, "docs"),
+			expected: []string{filepath.Join(root, "docs", "cake.md"), filepath.Join(root, "docs", "recipes", "bread.md")},
+		},
+		{
+			name:     "single-level glob does not descend into directories",
+			source:   filepath.Join(root, "docs", "*.md"),
+			expected: []string{filepath.Join(root, "docs", "cake.md")},
+		},
+		{
+			name:     "glob descends only into directories it can match",
+			source:   filepath.Join(root, "docs", "rec*", "*.md"),
+			expected: []string{filepath.Join(root, "docs", "recipes", "bread.md")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetch.ExpandSources([]string{tt.source}, fetch.ExpandOptions{})
+			if err != nil {
+				t.Fatalf("ExpandSources() error = %v", err)
+			}
+			if strings.Join(result, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("ExpandSources() =\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+
+	if _, err := fetch.ExpandSources([]string{private}, fetch.ExpandOptions{}); err == nil {
+		t.Error("ExpandSources() of an unreadable directory source succeeded, want an error")
+	}
+}
@@ -4,135 +4,326 @@ package fetch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/text/encoding"
 )
 
-// File size limits to prevent memory overload
-// TODO: make this configurable via command-line flags OR eliminate w/ streaming
+// Default HTTP settings, used when Options leaves them unset
 const (
-	MaxFileSizeBytes = 50 * 1024 * 1024  // 50MB limit for files
-	MaxHTTPSizeBytes = 100 * 1024 * 1024 // 100MB limit for HTTP content (may not have Content-Length)
+	DefaultHTTPRequestTimeout = 30 * time.Second
+	DefaultUserAgent          = "sift/0.1"
+	DefaultMaxAttempts        = 3
+	DefaultRetryBaseDelay     = 500 * time.Millisecond
 )
 
-// HTTP client timeout configuration; currently set to reasonable defaults
-// TODO: make this configurable via command-line flags
-const HTTPRequestTimeout = 30 * time.Second
+// Options configures how content is fetched from URLs.
+// The zero value is usable; unset fields fall back to the defaults above.
+type Options struct {
+	Timeout    time.Duration // overall time limit for a single HTTP request
+	UserAgent  string        // User-Agent header sent with every request
+	Headers    http.Header   // additional headers sent with every request
+	CookieFile string        // optional Netscape-format cookie file (as exported by curl or browsers)
 
-// specific timeout thresholds (based on HTTPRequestTimeout)
-var (
-	HTTPDialTimeout           = HTTPRequestTimeout / 6 // ~17%, max time to wait for network connection
-	HTTPTLSTimeout            = HTTPRequestTimeout / 6 // ~17%, max time to wait for TLS handshake
-	HTTPResponseHeaderTimeout = HTTPRequestTimeout / 2 // 50%, max time for response headers (usually the longest phase)
-)
+	// retry behavior for transient failures (429, 5xx, connection resets, timeouts)
+	MaxAttempts    int           // total attempts per URL, including the first (1 disables retries)
+	RetryBaseDelay time.Duration // initial backoff delay, doubled after each attempt
+	RetryDeadline  time.Duration // overall time limit across all attempts and waits (0 for none)
+
+	// on-disk response cache
+	CacheDir    string        // directory for cached responses (empty disables caching, as does a cookie file)
+	CacheMaxAge time.Duration // serve cached responses without revalidation for this long (0 always revalidates)
+
+	// politeness toward each host
+	IgnoreRobots    bool          // fetch URLs even when robots.txt disallows them
+	HostDelay       time.Duration // minimum time between requests to the same host (robots.txt Crawl-delay may raise it)
+	HostConcurrency int           // maximum simultaneous requests to the same host
+
+	// character encoding of text content, as a WHATWG label such as "shift_jis" (empty detects it per source)
+	Encoding string
 
-// limitedReadCloser wraps an io.ReadCloser to enforce size limits
-type limitedReadCloser struct {
+	// size limits, which guard against memory overload (and decompression bombs)
+	MaxBytes      int64 // maximum bytes read from a single source (0 uses DefaultMaxBytes)
+	MaxTotalBytes int64 // maximum bytes read across all sources fetched by a Client (0 for no limit)
+}
+
+// ErrDisallowed is returned for URLs that robots.txt does not allow the client to fetch
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// Content is fetched content along with what is known about its type.
+// It must be closed after reading.
+type Content struct {
 	io.ReadCloser
-	N      int64  // max bytes remaining
-	source string // for error messages
+	ContentType string // media type reported by the server (e.g. "text/html; charset=utf-8"); empty for files and stdin
+}
+
+// Client fetches content from files, URLs, and standard input using a fixed set of Options.
+// A Client is safe for concurrent use across multiple goroutines, and should be closed once
+// the content it returned has been read.
+type Client struct {
+	opts       Options
+	httpClient *http.Client
+	cache      *diskCache // nil when caching is disabled
+	robots     robotsCache
+	limiter    *hostLimiter
+	encoding   encoding.Encoding // nil when encodings are detected
+	total      *byteBudget       // nil when there is no total limit
+	archives   archiveCache      // indexes of the archives members were read from
 }
 
-func (l *limitedReadCloser) Read(p []byte) (n int, err error) {
-	if l.N <= 0 {
-		return 0, fmt.Errorf("content from %q exceeds size limit", l.source)
+// NewClient creates a Client for the given options.
+// Returns an error if the cookie file cannot be loaded or the encoding is unknown.
+func NewClient(opts Options) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHTTPRequestTimeout
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = DefaultRetryBaseDelay
+	}
+	if opts.HostConcurrency <= 0 {
+		opts.HostConcurrency = DefaultHostConcurrency
 	}
-	if int64(len(p)) > l.N {
-		p = p[0:l.N]
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
 	}
-	n, err = l.ReadCloser.Read(p)
-	l.N -= int64(n)
-	return
+
+	// phase timeouts are derived from the overall request timeout
+	httpClient := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			// honor HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout: opts.Timeout / 6, // ~17%, max time to wait for network connection
+			}).DialContext,
+			TLSHandshakeTimeout:   opts.Timeout / 6, // ~17%, max time to wait for TLS handshake
+			ResponseHeaderTimeout: opts.Timeout / 2, // 50%, max time for response headers (usually the longest phase)
+			// disable keep-alives to avoid connection reuse issues
+			DisableKeepAlives: true,
+		},
+	}
+
+	if opts.CookieFile != "" {
+		jar, err := loadCookieFile(opts.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Jar = jar
+	}
+
+	client := &Client{opts: opts, httpClient: httpClient, limiter: newHostLimiter(opts.HostConcurrency)}
+
+	if opts.MaxTotalBytes > 0 {
+		client.total = &byteBudget{limit: opts.MaxTotalBytes}
+	}
+
+	if opts.Encoding != "" {
+		enc, err := LookupEncoding(opts.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		client.encoding = enc
+	}
+
+	// responses fetched with cookies may be personal to the account they log in to, and the
+	// cookies sent vary by URL and over time, so they are not cached
+	if opts.CacheDir != "" && opts.CookieFile == "" {
+		cache, err := newDiskCache(opts.CacheDir, opts.CacheMaxAge, opts.Headers)
+		if err != nil {
+			return nil, err
+		}
+		client.cache = cache
+	}
+
+	return client, nil
+}
+
+// Close releases the archives read by the client, after which content it returned from archive
+// members can no longer be read
+func (c *Client) Close() error {
+	return c.archives.close()
 }
 
-// httpClient is a shared HTTP client with appropriate timeouts to prevent indefinite hangs.
-// this should be safe for concurrent use across multiple goroutines.
-var httpClient = &http.Client{
-	Timeout: HTTPRequestTimeout,
-	Transport: &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout: HTTPDialTimeout,
-		}).Dial,
-		TLSHandshakeTimeout:   HTTPTLSTimeout,
-		ResponseHeaderTimeout: HTTPResponseHeaderTimeout,
-		// disable keep-alives to avoid connection reuse issues
-		DisableKeepAlives: true,
-	},
+// GetContent retrieves content from a source using a Client configured with opts, which is closed
+// along with the content. See Client.GetContent for the supported source types.
+func GetContent(ctx context.Context, source string, opts Options) (*Content, error) {
+	client, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	content, err := client.GetContent(ctx, source)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	content.ReadCloser = &decompressor{Reader: content.ReadCloser, closers: []io.Closer{content.ReadCloser, client}}
+	return content, nil
 }
 
-// GetContent retrieves content from various source types and returns an io.ReadCloser.
+// GetContent retrieves content from various source types and returns it as a Content reader.
 // It supports three types of sources:
 //   - "-" reads from standard input
 //   - URLs starting with "http://" or "https://" are fetched via HTTP
+//   - "archive!member" reads a member of a zip or tar archive
 //   - everything else is treated as a local file path
 //
+// Reading more than Options.MaxBytes from a source, or Options.MaxTotalBytes across sources,
+// fails with a *SizeLimitError. Text content is transcoded to UTF-8 (see decodeText); binary
+// content is returned as is.
 // ctx allows for cancellation and timeout control of fetch operations.
-func GetContent(ctx context.Context, source string) (io.ReadCloser, error) {
+func (c *Client) GetContent(ctx context.Context, source string) (*Content, error) {
+	var content *Content
 	switch {
 	case source == "-":
-		// Wrap stdin with size limit to prevent memory overload
-		// This is useful for piping content directly into the program
-		return &limitedReadCloser{
-			ReadCloser: os.Stdin,
-			N:          MaxFileSizeBytes,
-			source:     "stdin",
-		}, nil
+		// reading stdin is useful for piping content directly into the program
+		content = &Content{ReadCloser: os.Stdin}
 	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
-		return fetchURL(ctx, source)
+		var err error
+		content, err = c.fetchURL(ctx, source)
+		if err != nil {
+			return nil, err
+		}
 	default:
-		return fetchFile(ctx, source)
+		var file io.ReadCloser
+		var err error
+		if archive, member, ok := splitArchiveSource(source); ok && !fileExists(source) {
+			file, err = c.openArchiveMember(archive, member)
+		} else {
+			file, err = fetchFile(ctx, source, c.opts.MaxBytes)
+		}
+		if err != nil {
+			return nil, err
+		}
+		content = &Content{ReadCloser: file}
 	}
+
+	// every source is held to the same limits, applied after decompression
+	content.ReadCloser = c.limit(content.ReadCloser, source)
+	content.ReadCloser = decodeText(content.ReadCloser, content.ContentType, c.encoding, source)
+	return content, nil
 }
 
-// fetchURL retrieves content from an HTTP or HTTPS URL using a client with timeout configuration
+// fetchURL retrieves content from an HTTP or HTTPS URL using the client's configured headers and timeouts,
+// retrying transient failures with backoff. When caching is enabled, fresh entries are served from disk
+// and stale entries are revalidated with If-None-Match/If-Modified-Since.
+// Unless robots.txt is ignored, URLs it disallows fail with ErrDisallowed; requests to each host are
+// spaced and bounded by the client's per-host limits.
 // ctx allows for cancellation and timeout control of HTTP requests.
-func fetchURL(ctx context.Context, url string) (io.ReadCloser, error) {
-	// create request with User-Agent and context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request for URL %q: %w", url, err)
+func (c *Client) fetchURL(ctx context.Context, url string) (*Content, error) {
+	var cached *cacheEntry
+	if c.cache != nil {
+		cached = c.cache.lookup(url)
+		if cached != nil && c.cache.isFresh(cached, time.Now()) {
+			if body, err := c.cache.open(cached); err == nil {
+				slog.Debug("Serving URL from cache", "url", url, "storedAt", cached.StoredAt)
+				return &Content{
+					ReadCloser:  body,
+					ContentType: cached.ContentType,
+				}, nil
+			}
+		}
 	}
-	req.Header.Set("User-Agent", "sift/0.1")
 
-	// execute request using shared client
-	resp, err := httpClient.Do(req)
+	robots := c.Robots(ctx, url)
+	if !robots.Allowed(url) {
+		return nil, fmt.Errorf("cannot fetch URL %q: %w", url, ErrDisallowed)
+	}
+
+	// the retry deadline bounds all attempts, backoff waits, and reading the final body
+	cancel := context.CancelFunc(func() {})
+	if c.opts.RetryDeadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.opts.RetryDeadline)
+	}
+
+	release, err := c.limiter.acquire(ctx, hostOf(url), max(c.opts.HostDelay, min(robots.CrawlDelay(), maxCrawlDelay)))
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to fetch URL %q: %w", url, err)
 	}
+	resp, err := c.doWithRetry(ctx, url, conditionalHeaders(cached))
+	release()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// unchanged since it was cached
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		cancel()
+		if body, err := c.cache.open(cached); err == nil {
+			slog.Debug("Revalidated cached URL", "url", url)
+			c.cache.touch(cached)
+			return &Content{
+				ReadCloser:  body,
+				ContentType: cached.ContentType,
+			}, nil
+		}
+		return nil, fmt.Errorf("cached content for URL %q is no longer readable", url)
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
+		cancel()
 		return nil, fmt.Errorf("HTTP request failed for URL %q: status %d %s", url, resp.StatusCode, resp.Status)
 	}
 
 	// check content-length header if present to prevent memory overload (and exhaustion attacks)
 	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
-		if size, err := strconv.ParseInt(contentLength, 10, 64); err == nil {
-			if size > MaxHTTPSizeBytes {
-				resp.Body.Close()
-				return nil, fmt.Errorf("HTTP content too large (%d bytes > %d bytes limit)",
-					size, MaxHTTPSizeBytes)
-			}
+		if size, err := strconv.ParseInt(contentLength, 10, 64); err == nil && size > c.opts.MaxBytes {
+			resp.Body.Close()
+			cancel()
+			return nil, &SizeLimitError{Source: url, Limit: c.opts.MaxBytes, Size: size}
 		}
 	}
 
-	// For HTTP content without Content-Length, use limitedReadCloser to prevent memory overload
-	return &limitedReadCloser{
-		ReadCloser: resp.Body,
-		N:          MaxHTTPSizeBytes,
-		source:     url,
-	}, nil
+	var body io.ReadCloser = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	if c.cache != nil && isCacheable(resp) {
+		body = c.cache.store(url, resp, body)
+	}
+
+	// content without a Content-Length is limited as it is read
+	return &Content{ReadCloser: body, ContentType: resp.Header.Get("Content-Type")}, nil
 }
 
-// fetchFile opens a local file for reading with better error messages
+// newRequest creates a GET request carrying the client's user agent, custom headers, and any extra
+// per-request headers (such as cache revalidation headers)
+func (c *Client) newRequest(ctx context.Context, url string, extra http.Header) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for URL %q: %w", url, err)
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+	// custom headers take precedence, including an explicit User-Agent
+	for name, values := range c.opts.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	for name, values := range extra {
+		req.Header[name] = values
+	}
+
+	return req, nil
+}
+
+// fetchFile opens a local file for reading with better error messages, decompressing gzip, zstd,
+// and bzip2 files on the fly
 // ctx is accepted for API consistency but not actually used for local file operations
-func fetchFile(ctx context.Context, path string) (io.ReadCloser, error) {
+func fetchFile(ctx context.Context, path string, maxBytes int64) (io.ReadCloser, error) {
 	// check if file exists and get size
 	fileInfo, err := os.Stat(path)
 	if os.IsNotExist(err) {
@@ -142,10 +333,14 @@ func fetchFile(ctx context.Context, path string) (io.ReadCloser, error) {
 		return nil, fmt.Errorf("failed to access file %q: %w", path, err)
 	}
 
+	// archives are read member by member, as expanded by ExpandSources
+	if isArchive(path) {
+		return nil, fmt.Errorf("archive %q contains no matching documents", path)
+	}
+
 	// check file size before opening to prevent memory overload
-	if fileInfo.Size() > MaxFileSizeBytes {
-		return nil, fmt.Errorf("file %q is too large (%d bytes > %d bytes limit)",
-			path, fileInfo.Size(), MaxFileSizeBytes)
+	if fileInfo.Size() > maxBytes {
+		return nil, &SizeLimitError{Source: path, Limit: maxBytes, Size: fileInfo.Size()}
 	}
 
 	file, err := os.Open(path)
@@ -153,5 +348,12 @@ func fetchFile(ctx context.Context, path string) (io.ReadCloser, error) {
 		return nil, fmt.Errorf("failed to open file %q: %w", path, err)
 	}
 
-	return file, nil
+	// compressed files are read decompressed
+	return decompress(file, path)
+}
+
+// fileExists reports whether a file or directory exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
@@ -6,8 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/chriscorrea/sift/internal/fetch"
 )
@@ -118,7 +121,7 @@ func TestGetContent(t *testing.T) {
 
 			// skip stdin test for actual reading since it's hard to mock
 			if source == "-" {
-				reader, err := fetch.GetContent(context.Background(), source)
+				reader, err := fetch.GetContent(context.Background(), source, fetch.Options{})
 				if err != nil {
 					t.Fatalf("GetContent() error = %v, expected no error for stdin", err)
 				}
@@ -130,7 +133,7 @@ func TestGetContent(t *testing.T) {
 				return
 			}
 
-			reader, err := fetch.GetContent(context.Background(), source)
+			reader, err := fetch.GetContent(context.Background(), source, fetch.Options{})
 
 			if tt.expectError {
 				if err == nil {
@@ -194,7 +197,7 @@ func TestGetContentSourceTypes(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// we just test that the function routes to the correct branch
 			// by checking the type of error or success for known patterns
-			_, err := fetch.GetContent(context.Background(), tt.source)
+			_, err := fetch.GetContent(context.Background(), tt.source, fetch.Options{})
 
 			switch tt.expectType {
 			case "stdin":
@@ -222,3 +225,463 @@ func TestGetContentSourceTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestClientOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        func(t *testing.T) fetch.Options
+		handler     http.HandlerFunc
+		expectError bool
+		expectData  string
+	}{
+		{
+			name: "default user agent",
+			opts: func(t *testing.T) fetch.Options { return fetch.Options{} },
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.UserAgent()))
+			},
+			expectData: fetch.DefaultUserAgent,
+		},
+		{
+			name: "custom user agent",
+			opts: func(t *testing.T) fetch.Options { return fetch.Options{UserAgent: "flour-sifter/2.0"} },
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.UserAgent()))
+			},
+			expectData: "flour-sifter/2.0",
+		},
+		{
+			name: "custom headers",
+			opts: func(t *testing.T) fetch.Options {
+				headers := http.Header{}
+				headers.Add("Authorization", "Bearer carrot")
+				headers.Add("X-Recipe", "cake")
+				return fetch.Options{Headers: headers}
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("X-Recipe")))
+			},
+			expectData: "Bearer carrot|cake",
+		},
+		{
+			name: "request timeout",
+			opts: func(t *testing.T) fetch.Options { return fetch.Options{Timeout: 50 * time.Millisecond} },
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte("too slow"))
+			},
+			expectError: true,
+		},
+		{
+			name: "cookie file",
+			opts: func(t *testing.T) fetch.Options {
+				path := filepath.Join(t.TempDir(), "cookies.txt")
+				content := "# Netscape HTTP Cookie File\n" +
+					"127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tsifted\n" +
+					"#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t0\tflavor\tcarrot\n"
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatalf("Failed to write cookie file: %v", err)
+				}
+				return fetch.Options{CookieFile: path}
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				session, _ := r.Cookie("session")
+				flavor, _ := r.Cookie("flavor")
+				if session == nil || flavor == nil {
+					_, _ = w.Write([]byte("missing cookies"))
+					return
+				}
+				_, _ = w.Write([]byte(session.Value + "|" + flavor.Value))
+			},
+			expectData: "sifted|carrot",
+		},
+		{
+			name: "missing cookie file",
+			opts: func(t *testing.T) fetch.Options {
+				return fetch.Options{CookieFile: filepath.Join(t.TempDir(), "missing.txt")}
+			},
+			handler:     func(w http.ResponseWriter, r *http.Request) {},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			reader, err := fetch.GetContent(context.Background(), server.URL, tt.opts(t))
+			if tt.expectError {
+				if err == nil {
+					reader.Close()
+					t.Errorf("GetContent() expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetContent() error = %v, expected no error", err)
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to read from reader: %v", err)
+			}
+			if string(data) != tt.expectData {
+				t.Errorf("GetContent() data = %q, expected %q", string(data), tt.expectData)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           fetch.Options
+		respond        func(w http.ResponseWriter, attempt int)
+		expectError    string
+		expectData     string
+		expectAttempts int32
+		minElapsed     time.Duration
+	}{
+		{
+			name: "recovers from transient 503",
+			opts: fetch.Options{RetryBaseDelay: time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				if attempt < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write([]byte("fresh carrot cake"))
+			},
+			expectData:     "fresh carrot cake",
+			expectAttempts: 3,
+		},
+		{
+			name: "gives up after max attempts",
+			opts: fetch.Options{MaxAttempts: 2, RetryBaseDelay: time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expectError:    "gave up after 2 attempts",
+			expectAttempts: 2,
+		},
+		{
+			name: "does not retry client errors",
+			opts: fetch.Options{RetryBaseDelay: time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			expectError:    "status 404",
+			expectAttempts: 1,
+		},
+		{
+			name: "recovers from connection reset",
+			opts: fetch.Options{RetryBaseDelay: time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				if attempt == 1 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err == nil {
+						conn.Close()
+					}
+					return
+				}
+				_, _ = w.Write([]byte("sifted on retry"))
+			},
+			expectData:     "sifted on retry",
+			expectAttempts: 2,
+		},
+		{
+			name: "honors Retry-After seconds",
+			opts: fetch.Options{RetryBaseDelay: time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				if attempt == 1 {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte("worth the wait"))
+			},
+			expectData:     "worth the wait",
+			expectAttempts: 2,
+			minElapsed:     time.Second,
+		},
+		{
+			name: "Retry-After beyond deadline fails fast",
+			opts: fetch.Options{RetryBaseDelay: time.Millisecond, RetryDeadline: 500 * time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			expectError:    "retry deadline exceeded",
+			expectAttempts: 1,
+		},
+		{
+			name: "Retry-After beyond retry delay limit fails fast",
+			opts: fetch.Options{RetryBaseDelay: time.Millisecond},
+			respond: func(w http.ResponseWriter, attempt int) {
+				w.Header().Set("Retry-After", "86400")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectError:    "server asked to retry after 24h0m0s",
+			expectAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					http.NotFound(w, r)
+					return
+				}
+				tt.respond(w, int(attempts.Add(1)))
+			}))
+			defer server.Close()
+
+			start := time.Now()
+			reader, err := fetch.GetContent(context.Background(), server.URL, tt.opts)
+			elapsed := time.Since(start)
+
+			if got := attempts.Load(); got != tt.expectAttempts {
+				t.Errorf("server saw %d attempts, expected %d", got, tt.expectAttempts)
+			}
+
+			if tt.expectError != "" {
+				if err == nil {
+					reader.Close()
+					t.Fatalf("GetContent() expected error containing %q but got none", tt.expectError)
+				}
+				if !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("GetContent() error = %v, expected it to contain %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetContent() error = %v, expected no error", err)
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Failed to read from reader: %v", err)
+			}
+			if string(data) != tt.expectData {
+				t.Errorf("GetContent() data = %q, expected %q", string(data), tt.expectData)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("GetContent() returned after %v, expected to wait at least %v", elapsed, tt.minElapsed)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxAge       time.Duration
+		disable      bool
+		respond      func(w http.ResponseWriter, r *http.Request)
+		expectStatus []int // status served for each of two fetches (0 when no request reaches the server)
+	}{
+		{
+			name:   "revalidates with ETag",
+			maxAge: 0,
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				_, _ = w.Write([]byte("carrot cake"))
+			},
+			expectStatus: []int{http.StatusOK, http.StatusNotModified},
+		},
+		{
+			name:   "revalidates with Last-Modified",
+			maxAge: 0,
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+				_, _ = w.Write([]byte("carrot cake"))
+			},
+			expectStatus: []int{http.StatusOK, http.StatusNotModified},
+		},
+		{
+			name:   "serves fresh entries without a request",
+			maxAge: time.Hour,
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				_, _ = w.Write([]byte("carrot cake"))
+			},
+			expectStatus: []int{http.StatusOK, 0},
+		},
+		{
+			name:   "skips no-store responses",
+			maxAge: time.Hour,
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "no-store")
+				_, _ = w.Write([]byte("carrot cake"))
+			},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:   "skips private responses",
+			maxAge: time.Hour,
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "private, max-age=60")
+				_, _ = w.Write([]byte("carrot cake"))
+			},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:    "disabled cache always fetches",
+			maxAge:  time.Hour,
+			disable: true,
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-None-Match") != "" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				_, _ = w.Write([]byte("carrot cake"))
+			},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statuses []int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					http.NotFound(w, r)
+					return
+				}
+				rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+				tt.respond(rec, r)
+				statuses = append(statuses, rec.status)
+			}))
+			defer server.Close()
+
+			opts := fetch.Options{CacheDir: t.TempDir(), CacheMaxAge: tt.maxAge}
+			if tt.disable {
+				opts.CacheDir = ""
+			}
+			client, err := fetch.NewClient(opts)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			for i := range tt.expectStatus {
+				reader, err := client.GetContent(context.Background(), server.URL)
+				if err != nil {
+					t.Fatalf("fetch %d: GetContent() error = %v", i+1, err)
+				}
+				data, err := io.ReadAll(reader)
+				reader.Close()
+				if err != nil {
+					t.Fatalf("fetch %d: failed to read content: %v", i+1, err)
+				}
+				if string(data) != "carrot cake" {
+					t.Errorf("fetch %d: content = %q, want %q", i+1, string(data), "carrot cake")
+				}
+			}
+
+			var expected []int
+			for _, status := range tt.expectStatus {
+				if status != 0 {
+					expected = append(expected, status)
+				}
+			}
+			if len(statuses) != len(expected) {
+				t.Fatalf("server statuses = %v, want %v", statuses, expected)
+			}
+			for i := range expected {
+				if statuses[i] != expected[i] {
+					t.Errorf("server statuses = %v, want %v", statuses, expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+// statusRecorder records the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func TestCache_Credentials(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+			_, _ = w.Write([]byte("members only"))
+			return
+		}
+		_, _ = w.Write([]byte("public"))
+	}))
+	defer server.Close()
+
+	cookies := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(cookies, []byte("127.0.0.1\tFALSE\t/\tFALSE\t0\tsession\tsifted\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write cookie file: %v", err)
+	}
+
+	cacheDir := t.TempDir()
+	fetches := []struct {
+		name       string
+		opts       fetch.Options
+		expectData string
+		cached     bool // served from the cache without a request
+	}{
+		{name: "authorized", opts: fetch.Options{Headers: http.Header{"Authorization": {"Bearer token"}}}, expectData: "members only"},
+		{name: "authorized again", opts: fetch.Options{Headers: http.Header{"authorization": {"Bearer token"}}}, expectData: "members only", cached: true},
+		{name: "anonymous", expectData: "public"},
+		{name: "cookies", opts: fetch.Options{CookieFile: cookies}, expectData: "members only"},
+		{name: "anonymous again", expectData: "public", cached: true},
+		{name: "cookies again", opts: fetch.Options{CookieFile: cookies}, expectData: "members only"},
+	}
+
+	for _, f := range fetches {
+		f.opts.CacheDir, f.opts.CacheMaxAge = cacheDir, time.Hour
+		client, err := fetch.NewClient(f.opts)
+		if err != nil {
+			t.Fatalf("%s: NewClient() error = %v", f.name, err)
+		}
+
+		before := requests.Load()
+		reader, err := client.GetContent(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("%s: GetContent() error = %v", f.name, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: failed to read content: %v", f.name, err)
+		}
+
+		if string(data) != f.expectData {
+			t.Errorf("%s: content = %q, want %q", f.name, data, f.expectData)
+		}
+		if cached := requests.Load() == before; cached != f.cached {
+			t.Errorf("%s: served from cache = %v, want %v", f.name, cached, f.cached)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package fetch
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// globPattern matches slash-separated relative paths using gitignore-style rules:
+//   - "*", "?" and "[...]" match within a single path segment
+//   - "**" as a whole segment matches zero or more segments
+//   - patterns without a slash match the final segment (base name) at any depth
+//   - a leading slash anchors the pattern to the root; a trailing slash matches directories only
+type globPattern struct {
+	segments []string
+	negate   bool // gitignore "!" rules re-include previously ignored paths
+	dirOnly  bool
+}
+
+// parseGlobPattern compiles a pattern; negation is only recognized when allowNegate is set
+func parseGlobPattern(pattern string, allowNegate bool) globPattern {
+	var g globPattern
+
+	if allowNegate && strings.HasPrefix(pattern, "!") {
+		g.negate = true
+		pattern = pattern[1:]
+	}
+	pattern = filepath.ToSlash(pattern)
+
+	if strings.HasSuffix(pattern, "/") {
+		g.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	// unanchored patterns match at any depth
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	g.segments = strings.Split(pattern, "/")
+	return g
+}
+
+// match reports whether rel (a slash-separated path relative to the pattern root) matches
+func (g globPattern) match(rel string, isDir bool) bool {
+	if g.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(g.segments, strings.Split(rel, "/"))
+}
+
+// matchesBelow reports whether paths below the directory dir (a slash-separated path relative
+// to the pattern root) can match, so that directories the pattern cannot reach are not walked
+func (g globPattern) matchesBelow(dir string) bool {
+	pattern, segments := g.segments, strings.Split(dir, "/")
+	for ; len(segments) > 0; pattern, segments = pattern[1:], segments[1:] {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+	}
+	return len(pattern) > 0
+}
+
+// matchSegments matches path segments against pattern segments, expanding "**"
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// collapse runs of "**" and try every possible split point
+			rest := pattern[1:]
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(rest, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
+
+// matchAny reports whether rel matches any of the patterns
+func matchAny(patterns []globPattern, rel string, isDir bool) bool {
+	for _, p := range patterns {
+		if p.match(rel, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGlobMeta reports whether s contains glob metacharacters
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// gitignore holds the rules of a single .gitignore file, relative to its directory
+type gitignore struct {
+	dir   string // absolute directory containing the .gitignore file
+	rules []globPattern
+}
+
+// loadGitignore reads dir/.gitignore, returning nil if there is none
+func loadGitignore(dir string) *gitignore {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	ignore := &gitignore{dir: dir}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// a leading backslash escapes "#" and "!"
+		if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			ignore.rules = append(ignore.rules, parseGlobPattern(line[1:], false))
+			continue
+		}
+		ignore.rules = append(ignore.rules, parseGlobPattern(line, true))
+	}
+
+	if len(ignore.rules) == 0 {
+		return nil
+	}
+	return ignore
+}
+
+// ignoreStack is the set of .gitignore files that apply to a directory, outermost first
+type ignoreStack []*gitignore
+
+// ignored reports whether an absolute path is ignored; deeper files and later rules take precedence
+func (s ignoreStack) ignored(absPath string, isDir bool) bool {
+	ignored := false
+	for _, ignore := range s {
+		rel, err := filepath.Rel(ignore.dir, absPath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, rule := range ignore.rules {
+			if rule.match(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// with returns a stack extended by dir's .gitignore, if it has one
+func (s ignoreStack) with(dir string) ignoreStack {
+	ignore := loadGitignore(dir)
+	if ignore == nil {
+		return s
+	}
+	return append(s[:len(s):len(s)], ignore)
+}
+
+// ancestorIgnores collects .gitignore files from the enclosing git work tree above dir, outermost first.
+// Returns nil when dir is not inside a git work tree or is the work tree root itself.
+func ancestorIgnores(dir string) ignoreStack {
+	if isWorkTreeRoot(dir) {
+		return nil
+	}
+
+	var parents []string
+	for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+		parents = append(parents, current)
+		if isWorkTreeRoot(current) {
+			break
+		}
+		if filepath.Dir(current) == current {
+			return nil // reached the filesystem root without finding a work tree
+		}
+	}
+
+	var stack ignoreStack
+	for i := len(parents) - 1; i >= 0; i-- {
+		stack = stack.with(parents[i])
+	}
+	return stack
+}
+
+// isWorkTreeRoot reports whether dir contains a .git directory or file
+func isWorkTreeRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
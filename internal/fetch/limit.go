@@ -0,0 +1,80 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// DefaultMaxBytes is the default limit on bytes read from a single source
+const DefaultMaxBytes = 100 * 1024 * 1024 // 100MB
+
+// SizeLimitError is returned when a source, or all sources together, exceed a size limit
+type SizeLimitError struct {
+	Source string // source that exceeded the limit
+	Limit  int64  // limit in bytes
+	Size   int64  // known size of the source in bytes, or 0 if it was exceeded while reading
+	Total  bool   // whether the limit is on all sources together rather than a single source
+}
+
+func (e *SizeLimitError) Error() string {
+	scope := "per-source"
+	if e.Total {
+		scope = "total"
+	}
+	if e.Size > 0 {
+		return fmt.Sprintf("%q is too large (%d bytes > %d-byte %s limit)", e.Source, e.Size, e.Limit, scope)
+	}
+	return fmt.Sprintf("content from %q exceeds the %d-byte %s limit", e.Source, e.Limit, scope)
+}
+
+// byteBudget counts bytes read across all sources of a Client
+type byteBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// limitedReadCloser wraps an io.ReadCloser to enforce size limits
+type limitedReadCloser struct {
+	io.ReadCloser
+	N      int64       // max bytes remaining
+	limit  int64       // per-source limit, for errors
+	source string      // for error messages
+	total  *byteBudget // shared limit across sources; nil for none
+}
+
+// limit wraps content with the client's per-source and total size limits
+func (c *Client) limit(content io.ReadCloser, source string) io.ReadCloser {
+	return &limitedReadCloser{ReadCloser: content, N: c.opts.MaxBytes, limit: c.opts.MaxBytes, source: source, total: c.total}
+}
+
+func (l *limitedReadCloser) Read(p []byte) (n int, err error) {
+	allowed, total := l.N, false
+	if l.total != nil {
+		if remaining := l.total.limit - l.total.used.Load(); remaining < allowed {
+			allowed, total = remaining, true
+		}
+	}
+
+	if allowed <= 0 {
+		// content that ends exactly at the limit is not over it
+		var probe [1]byte
+		if n, err := l.ReadCloser.Read(probe[:]); n == 0 && err != nil {
+			return 0, err
+		}
+		if total {
+			return 0, &SizeLimitError{Source: l.source, Limit: l.total.limit, Total: true}
+		}
+		return 0, &SizeLimitError{Source: l.source, Limit: l.limit}
+	}
+
+	if int64(len(p)) > allowed {
+		p = p[0:allowed]
+	}
+	n, err = l.ReadCloser.Read(p)
+	l.N -= int64(n)
+	if l.total != nil {
+		l.total.used.Add(int64(n))
+	}
+	return
+}
@@ -0,0 +1,102 @@
+package fetch_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+func TestSizeLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/sized":
+			io.WriteString(w, strings.Repeat("a", 200))
+		case "/streamed":
+			// flushing before writing everything omits the Content-Length header
+			w.Write([]byte(strings.Repeat("a", 50)))
+			w.(http.Flusher).Flush()
+			io.WriteString(w, strings.Repeat("a", 150))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFile := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("a", size)), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	small := writeFile("small.txt", 60)
+	exact := writeFile("exact.txt", 100)
+	large := writeFile("large.txt", 200)
+
+	tests := []struct {
+		name        string
+		opts        fetch.Options
+		sources     []string
+		expectTotal bool   // whether the last source exceeds the total limit
+		expectError string // error for the last source; all others must succeed
+	}{
+		{"file within limit", fetch.Options{MaxBytes: 100}, []string{small}, false, ""},
+		{"file exactly at limit", fetch.Options{MaxBytes: 100}, []string{exact}, false, ""},
+		{"file over limit", fetch.Options{MaxBytes: 100}, []string{large}, false, "is too large (200 bytes > 100-byte per-source limit)"},
+		{"HTTP Content-Length over limit", fetch.Options{MaxBytes: 100}, []string{server.URL + "/sized"}, false, "is too large"},
+		{"streamed HTTP over limit", fetch.Options{MaxBytes: 100}, []string{server.URL + "/streamed"}, false, "exceeds the 100-byte per-source limit"},
+		{"default limit", fetch.Options{}, []string{large, server.URL + "/streamed"}, false, ""},
+		{"total limit across sources", fetch.Options{MaxTotalBytes: 150}, []string{small, small, small}, true, "exceeds the 150-byte total limit"},
+		{"sources within total limit", fetch.Options{MaxTotalBytes: 160}, []string{exact, small}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := fetch.NewClient(tt.opts)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			for i, source := range tt.sources {
+				err := readAll(client, source)
+				if i < len(tt.sources)-1 || tt.expectError == "" {
+					if err != nil {
+						t.Fatalf("source %d: unexpected error: %v", i, err)
+					}
+					continue
+				}
+
+				var limitErr *fetch.SizeLimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("error = %v, want a *SizeLimitError", err)
+				}
+				if limitErr.Total != tt.expectTotal {
+					t.Errorf("Total = %v, want %v", limitErr.Total, tt.expectTotal)
+				}
+				if !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("error = %q, want it to contain %q", err, tt.expectError)
+				}
+			}
+		})
+	}
+}
+
+// readAll fetches and reads a source in full
+func readAll(client *fetch.Client, source string) error {
+	content, err := client.GetContent(context.Background(), source)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	_, err = io.ReadAll(content)
+	return err
+}
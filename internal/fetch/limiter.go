@@ -0,0 +1,85 @@
+package fetch
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHostConcurrency is the default number of simultaneous requests to a single host
+const DefaultHostConcurrency = 2
+
+// maxCrawlDelay caps the robots.txt Crawl-delay honored between requests to a host
+const maxCrawlDelay = 30 * time.Second
+
+// hostLimiter spaces out and bounds concurrent requests to each host
+type hostLimiter struct {
+	concurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+// hostSlot is the politeness state of a single host
+type hostSlot struct {
+	active chan struct{} // semaphore of in-flight requests
+	next   time.Time     // earliest start time of the next request
+}
+
+func newHostLimiter(concurrency int) *hostLimiter {
+	return &hostLimiter{concurrency: concurrency, hosts: make(map[string]*hostSlot)}
+}
+
+// acquire waits until a request to host may start: fewer than the concurrency limit are in flight
+// and at least delay has passed since the previous request started. The returned function must be
+// called once the request has completed.
+func (l *hostLimiter) acquire(ctx context.Context, host string, delay time.Duration) (func(), error) {
+	l.mu.Lock()
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{active: make(chan struct{}, l.concurrency)}
+		l.hosts[host] = slot
+	}
+	l.mu.Unlock()
+
+	select {
+	case slot.active <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slot.active }
+
+	// reserve the next start time, so that concurrent requests queue up behind each other
+	l.mu.Lock()
+	now := time.Now()
+	start := now
+	if slot.next.After(now) {
+		start = slot.next
+	}
+	slot.next = start.Add(delay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return release, nil
+}
+
+// hostOf returns the lowercase host (with port) of a URL, or the URL itself if it cannot be parsed
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
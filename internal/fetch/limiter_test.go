@@ -0,0 +1,175 @@
+package fetch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+func TestHostDelay(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	const delay = 100 * time.Millisecond
+	client, err := fetch.NewClient(fetch.Options{HostDelay: delay, HostConcurrency: 4})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := client.GetContent(context.Background(), fmt.Sprintf("%s/page/%d", server.URL, i))
+			if err != nil {
+				t.Errorf("GetContent() error = %v", err)
+				return
+			}
+			content.Close()
+		}()
+	}
+	wg.Wait()
+
+	if len(starts) != 3 {
+		t.Fatalf("got %d requests, want 3", len(starts))
+	}
+	for i := 1; i < len(starts); i++ {
+		// allow for timer granularity
+		if gap := starts[i].Sub(starts[i-1]); gap < delay-10*time.Millisecond {
+			t.Errorf("gap between requests %d and %d = %v, want at least %v", i-1, i, gap, delay)
+		}
+	}
+}
+
+func TestHostConcurrency(t *testing.T) {
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		concurrency int
+		expected    int32
+	}{
+		{"default", 0, fetch.DefaultHostConcurrency},
+		{"single", 1, 1},
+		{"wider", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peak.Store(0)
+			client, err := fetch.NewClient(fetch.Options{HostConcurrency: tt.concurrency})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			var wg sync.WaitGroup
+			for i := range 6 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					content, err := client.GetContent(context.Background(), fmt.Sprintf("%s/page/%d", server.URL, i))
+					if err != nil {
+						t.Errorf("GetContent() error = %v", err)
+						return
+					}
+					content.Close()
+				}()
+			}
+			wg.Wait()
+
+			if got := peak.Load(); got != tt.expected {
+				t.Errorf("peak concurrent requests = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRobotsDisallowed(t *testing.T) {
+	var pageRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+			return
+		}
+		pageRequests.Add(1)
+		fmt.Fprint(w, "secret")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		ignoreRobots bool
+		path         string
+		expectErr    bool
+	}{
+		{"disallowed", false, "/private/page", true},
+		{"allowed", false, "/public/page", false},
+		{"ignored", true, "/private/page", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageRequests.Store(0)
+			client, err := fetch.NewClient(fetch.Options{IgnoreRobots: tt.ignoreRobots})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			content, err := client.GetContent(context.Background(), server.URL+tt.path)
+			if tt.expectErr {
+				if !errors.Is(err, fetch.ErrDisallowed) {
+					t.Fatalf("GetContent() error = %v, want ErrDisallowed", err)
+				}
+				if got := pageRequests.Load(); got != 0 {
+					t.Errorf("disallowed page requested %d times", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetContent() error = %v", err)
+			}
+			defer content.Close()
+			if body, _ := io.ReadAll(content); string(body) != "secret" {
+				t.Errorf("content = %q, want %q", body, "secret")
+			}
+		})
+	}
+}
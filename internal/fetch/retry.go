@@ -0,0 +1,151 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// maxRetryDelay caps the computed backoff between attempts and the longest Retry-After wait honored
+const maxRetryDelay = 30 * time.Second
+
+// cancelOnClose releases a request context once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// doWithRetry performs a GET request, retrying transient failures with exponential backoff and jitter.
+// Retry-After headers on 429 and 503 responses take precedence over the computed backoff; a request
+// asking for a longer wait than maxRetryDelay fails instead of waiting.
+// Non-retryable responses (including non-200 statuses such as 304 or 404) are returned to the caller as-is.
+func (c *Client) doWithRetry(ctx context.Context, url string, extra http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, url, extra)
+		if err != nil {
+			return nil, err
+		}
+
+		var wait time.Duration
+		resp, err := c.httpClient.Do(req)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("failed to fetch URL %q: %w", url, err)
+			if ctx.Err() != nil || !isRetryableError(err) {
+				return nil, err
+			}
+		case isRetryableStatus(resp.StatusCode):
+			wait = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			// drain so the failed response doesn't linger
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+			err = fmt.Errorf("HTTP request failed for URL %q: status %d %s", url, resp.StatusCode, resp.Status)
+		default:
+			return resp, nil
+		}
+
+		if attempt >= c.opts.MaxAttempts {
+			if attempt > 1 {
+				return nil, fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+			return nil, err
+		}
+
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+
+		// don't start a wait that the deadline would cut short
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, fmt.Errorf("%w (retry deadline exceeded after %d attempts)", err, attempt)
+		}
+		if wait > maxRetryDelay {
+			return nil, fmt.Errorf("%w (server asked to retry after %v, longer than the %v limit)", err, wait, maxRetryDelay)
+		}
+
+		slog.Debug("Retrying HTTP request", "url", url, "attempt", attempt, "wait", wait, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (retry interrupted: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt: exponential growth from the base delay,
+// capped at maxRetryDelay, with "equal jitter" (half fixed, half random) to spread out retries
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.opts.RetryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// isRetryableStatus reports whether an HTTP status indicates a transient server-side condition
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableError reports whether a request error is likely transient:
+// connection resets, connections closed mid-response, and timeouts
+func isRetryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter interprets a Retry-After header given either as delay seconds or an HTTP date.
+// Returns 0 if the header is absent, invalid, or already in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,251 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRobotsBytes limits how much of a robots.txt file is read, as in RFC 9309
+const maxRobotsBytes = 500 * 1024
+
+// Robots holds the robots.txt rules of a host that apply to the client's user agent.
+// A nil *Robots allows everything.
+type Robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+// robotsRule is an Allow or Disallow line
+type robotsRule struct {
+	allow   bool
+	pattern string // path pattern, where * matches any sequence and a trailing $ anchors the end
+}
+
+// robotsGroup is a set of rules for one or more user agents
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// Allowed reports whether rawURL may be fetched. The longest matching rule wins, with Allow
+// winning ties; URLs no rule matches are allowed.
+func (r *Robots) Allowed(rawURL string) bool {
+	if r == nil {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if len(rule.pattern) < longest || !matchRobotsPattern(rule.pattern, target) {
+			continue
+		}
+		if len(rule.pattern) > longest || rule.allow {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay requested for the client's user agent, or 0 if none
+func (r *Robots) CrawlDelay() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.crawlDelay
+}
+
+// Sitemaps returns the sitemap URLs listed in robots.txt
+func (r *Robots) Sitemaps() []string {
+	if r == nil {
+		return nil
+	}
+	return r.sitemaps
+}
+
+// matchRobotsPattern reports whether a robots.txt path pattern matches the start of target
+func matchRobotsPattern(pattern, target string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(target, parts[0]) {
+		return false
+	}
+	rest := target[len(parts[0]):]
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// parseRobots parses a robots.txt file, keeping the rules of the group that best matches userAgent
+// (falling back to the "*" group). Unknown lines are ignored.
+func parseRobots(content io.Reader, userAgent string) *Robots {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	var sitemaps []string
+	inAgents := false // consecutive User-agent lines share a group
+
+	scanner := bufio.NewScanner(io.LimitReader(content, maxRobotsBytes))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents || current == nil {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			// an empty Disallow allows everything, so it adds no rule
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 && current != nil {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		}
+		inAgents = false
+	}
+
+	robots := &Robots{sitemaps: sitemaps}
+	if group := matchRobotsGroup(groups, userAgent); group != nil {
+		robots.rules = group.rules
+		robots.crawlDelay = group.crawlDelay
+	}
+	return robots
+}
+
+// matchRobotsGroup returns the group naming the product token of userAgent, or else the "*" group
+func matchRobotsGroup(groups []*robotsGroup, userAgent string) *robotsGroup {
+	token, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(userAgent)), "/")
+	token = strings.TrimSpace(token)
+
+	var fallback *robotsGroup
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			if agent == "*" {
+				if fallback == nil {
+					fallback = group
+				}
+			} else if token != "" && strings.HasPrefix(token, agent) {
+				return group
+			}
+		}
+	}
+	return fallback
+}
+
+// robotsCache remembers each host's robots.txt for the lifetime of a Client
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsEntry is a host's robots.txt, loaded once even when requested concurrently
+type robotsEntry struct {
+	once   sync.Once
+	robots *Robots
+}
+
+// Robots returns the robots.txt rules that apply to the client for the host of rawURL,
+// fetching them on first use and caching them per host. Missing, unreadable, or unreachable
+// robots.txt files allow everything. Returns nil (allowing everything) for non-HTTP sources
+// and when the client ignores robots.txt.
+func (c *Client) Robots(ctx context.Context, rawURL string) *Robots {
+	if c.opts.IgnoreRobots {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	c.robots.mu.Lock()
+	if c.robots.hosts == nil {
+		c.robots.hosts = make(map[string]*robotsEntry)
+	}
+	entry, ok := c.robots.hosts[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		c.robots.hosts[origin] = entry
+	}
+	c.robots.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.robots = c.fetchRobots(ctx, origin+"/robots.txt")
+	})
+	return entry.robots
+}
+
+// fetchRobots retrieves and parses a robots.txt file. It is requested once, without retries,
+// so that an unhealthy host does not hold up fetching for the length of a retry schedule.
+func (c *Client) fetchRobots(ctx context.Context, robotsURL string) *Robots {
+	release, err := c.limiter.acquire(ctx, hostOf(robotsURL), c.opts.HostDelay)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	req, err := c.newRequest(ctx, robotsURL, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Debug("Ignoring unavailable robots.txt", "url", robotsURL, "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Debug("No robots.txt rules", "url", robotsURL, "status", resp.StatusCode)
+		return nil
+	}
+
+	return parseRobots(resp.Body, c.opts.UserAgent)
+}
@@ -0,0 +1,108 @@
+package fetch_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chriscorrea/sift/internal/fetch"
+)
+
+const robotsTxt = `# example robots.txt
+User-agent: *
+Disallow: /private/
+Allow: /private/press
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: OtherBot
+User-agent: sift
+Disallow: /drafts
+Allow: /drafts/public
+Disallow: /search?
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestClientRobots(t *testing.T) {
+	var robotsRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			robotsRequests.Add(1)
+			fmt.Fprint(w, robotsTxt)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		userAgent   string
+		path        string
+		expected    bool
+		expectDelay time.Duration
+	}{
+		{"named group disallows prefix", "sift/0.1", "/drafts/plan.html", false, 500 * time.Millisecond},
+		{"longer allow wins", "sift/0.1", "/drafts/public/post", true, 500 * time.Millisecond},
+		{"query rule", "sift/0.1", "/search?q=cake", false, 500 * time.Millisecond},
+		{"named group ignores * group", "sift/0.1", "/private/notes", true, 500 * time.Millisecond},
+		{"fallback group", "curl/8.0", "/private/notes", false, 2 * time.Second},
+		{"fallback allow exception", "curl/8.0", "/private/press/2024", true, 2 * time.Second},
+		{"anchored wildcard", "curl/8.0", "/files/report.pdf", false, 2 * time.Second},
+		{"anchored wildcard does not match longer path", "curl/8.0", "/files/report.pdf.html", true, 2 * time.Second},
+		{"unmatched path allowed", "curl/8.0", "/", true, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := fetch.NewClient(fetch.Options{UserAgent: tt.userAgent})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			robots := client.Robots(context.Background(), server.URL+tt.path)
+			if got := robots.Allowed(server.URL + tt.path); got != tt.expected {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+			if got := robots.CrawlDelay(); got != tt.expectDelay {
+				t.Errorf("CrawlDelay() = %v, want %v", got, tt.expectDelay)
+			}
+			if sitemaps := robots.Sitemaps(); len(sitemaps) != 1 || sitemaps[0] != "https://example.com/sitemap.xml" {
+				t.Errorf("Sitemaps() = %v", sitemaps)
+			}
+		})
+	}
+
+	t.Run("cached per host", func(t *testing.T) {
+		robotsRequests.Store(0)
+		client, err := fetch.NewClient(fetch.Options{})
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		for _, path := range []string{"/a", "/b", "/c"} {
+			client.Robots(context.Background(), server.URL+path)
+		}
+		if got := robotsRequests.Load(); got != 1 {
+			t.Errorf("robots.txt fetched %d times, want 1", got)
+		}
+	})
+
+	t.Run("missing robots.txt allows everything", func(t *testing.T) {
+		missing := httptest.NewServer(http.NotFoundHandler())
+		defer missing.Close()
+
+		client, err := fetch.NewClient(fetch.Options{})
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		if !client.Robots(context.Background(), missing.URL+"/private/").Allowed(missing.URL + "/private/") {
+			t.Error("expected URL to be allowed without robots.txt")
+		}
+	})
+}
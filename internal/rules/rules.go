@@ -0,0 +1,174 @@
+// Package rules reads per-site extraction rules, so that pages from different sites can be
+// extracted with their own selector, exclusions, and input format without extra flags.
+//
+// A rules file is YAML (or JSON, which YAML also accepts) listing rules in order of precedence:
+//
+//	rules:
+//	  - match: example.com/blog
+//	    selector: article
+//	    exclude: [".ad", "nav"]
+//	  - match: docs.example.org
+//	    include_all: true
+//	    input_format: html
+//
+// A source URL uses the first rule whose pattern matches it.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chriscorrea/sift/internal/extract"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule holds the extraction settings for the sources matching a pattern
+type Rule struct {
+	// Match is a host, optionally followed by a path, such as "example.com/blog". The host also
+	// matches its subdomains, and the path matches itself and everything below it.
+	Match       string
+	Selector    string         // CSS selector for content extraction
+	Exclude     []string       // CSS selectors of elements removed before extraction
+	IncludeAll  bool           // skip readability and classification filtering
+	InputFormat extract.Format // input format override (FormatAuto detects it)
+
+	host string // lowercase host of Match
+	path string // path of Match without a trailing slash, empty for the whole host
+}
+
+// Set is an ordered list of rules
+type Set struct {
+	rules []Rule
+}
+
+// file is the layout of a rules file
+type file struct {
+	Rules []struct {
+		Match       string   `yaml:"match"`
+		Selector    string   `yaml:"selector"`
+		Exclude     []string `yaml:"exclude"`
+		IncludeAll  bool     `yaml:"include_all"`
+		InputFormat string   `yaml:"input_format"`
+	} `yaml:"rules"`
+}
+
+// DefaultPath returns the default rules file location under the user config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "sift", "rules.yaml"), nil
+}
+
+// Load reads a rules file
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	set, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %q: %w", path, err)
+	}
+	return set, nil
+}
+
+// Parse reads rules in YAML or JSON, rejecting unknown fields, invalid selectors, and unknown
+// input formats
+func Parse(r io.Reader) (*Set, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var parsed file
+	if err := decoder.Decode(&parsed); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	set := &Set{rules: make([]Rule, 0, len(parsed.Rules))}
+	for i, entry := range parsed.Rules {
+		host, path, err := parsePattern(entry.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		selectors := entry.Exclude
+		if entry.Selector != "" {
+			selectors = append([]string{entry.Selector}, selectors...)
+		}
+		if err := extract.CheckSelectors(selectors); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		format := extract.FormatAuto
+		if entry.InputFormat != "" {
+			if format, err = extract.ParseFormat(entry.InputFormat); err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+		}
+
+		set.rules = append(set.rules, Rule{
+			Match:       entry.Match,
+			Selector:    entry.Selector,
+			Exclude:     entry.Exclude,
+			IncludeAll:  entry.IncludeAll,
+			InputFormat: format,
+			host:        host,
+			path:        path,
+		})
+	}
+
+	return set, nil
+}
+
+// parsePattern splits a match pattern into its lowercase host and its path
+func parsePattern(pattern string) (host, path string, err error) {
+	// a scheme is accepted, but the pattern matches both http and https
+	trimmed := strings.TrimSpace(pattern)
+	if _, rest, found := strings.Cut(trimmed, "://"); found {
+		trimmed = rest
+	}
+
+	host, path, _ = strings.Cut(trimmed, "/")
+	host = strings.ToLower(host)
+	if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+		host = withoutPort
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("match pattern %q has no host", pattern)
+	}
+
+	path = strings.TrimSuffix("/"+path, "/")
+	return host, path, nil
+}
+
+// Match returns the first rule matching a source URL. Files and standard input match no rule.
+func (s *Set) Match(source string) (Rule, bool) {
+	if s == nil || (!strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://")) {
+		return Rule{}, false
+	}
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return Rule{}, false
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	for _, rule := range s.rules {
+		if host != rule.host && !strings.HasSuffix(host, "."+rule.host) {
+			continue
+		}
+		if rule.path != "" && parsed.Path != rule.path && !strings.HasPrefix(parsed.Path, rule.path+"/") {
+			continue
+		}
+		return rule, true
+	}
+	return Rule{}, false
+}
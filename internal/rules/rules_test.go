@@ -0,0 +1,127 @@
+package rules_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chriscorrea/sift/internal/extract"
+	"github.com/chriscorrea/sift/internal/rules"
+)
+
+const rulesYAML = `
+rules:
+  - match: example.com/blog
+    selector: article
+    exclude: [".ad", "nav"]
+  - match: https://Docs.Example.org/
+    include_all: true
+    input_format: html
+  - match: example.com
+    exclude:
+      - .comments
+  - match: localhost:8080/wiki
+    selector: "#content"
+`
+
+func TestSetMatch(t *testing.T) {
+	set, err := rules.Parse(strings.NewReader(rulesYAML))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		source    string
+		wantMatch string // match pattern of the expected rule, empty for none
+	}{
+		{"path prefix", "https://example.com/blog/2024/proofing", "example.com/blog"},
+		{"path itself", "http://example.com/blog", "example.com/blog"},
+		{"subdomain with path", "https://www.example.com/blog/", "example.com/blog"},
+		{"path prefix is segment-aware", "https://example.com/blogroll", "example.com"},
+		{"host only", "https://example.com/", "example.com"},
+		{"case-insensitive host", "https://DOCS.example.org/guide", "https://Docs.Example.org/"},
+		{"port is ignored", "http://localhost:3000/wiki/Main_Page", "localhost:8080/wiki"},
+		{"other host", "https://example.net/blog", ""},
+		{"suffix without a dot", "https://notexample.com/blog", ""},
+		{"file path", "example.com/blog/post.html", ""},
+		{"standard input", "-", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := set.Match(tt.source)
+			if ok != (tt.wantMatch != "") {
+				t.Fatalf("Match(%q) ok = %v, want %v", tt.source, ok, tt.wantMatch != "")
+			}
+			if rule.Match != tt.wantMatch {
+				t.Errorf("Match(%q) = rule %q, want %q", tt.source, rule.Match, tt.wantMatch)
+			}
+		})
+	}
+
+	rule, _ := set.Match("https://docs.example.org/")
+	if !rule.IncludeAll || rule.InputFormat != extract.FormatHTML {
+		t.Errorf("rule settings = %+v, want include-all and HTML input", rule)
+	}
+	rule, _ = set.Match("https://example.com/blog/post")
+	if rule.Selector != "article" || strings.Join(rule.Exclude, ",") != ".ad,nav" {
+		t.Errorf("rule settings = %+v, want the article selector and two exclusions", rule)
+	}
+
+	var none *rules.Set
+	if _, ok := none.Match("https://example.com/"); ok {
+		t.Error("Match() on a nil set found a rule")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rules   int
+		wantErr string
+	}{
+		{"json", `{"rules": [{"match": "example.com", "selector": "main", "exclude": [".ad"]}]}`, 1, ""},
+		{"empty file", "", 0, ""},
+		{"unknown field", "rules:\n  - match: example.com\n    selecter: main\n", 0, "selecter"},
+		{"invalid selector", "rules:\n  - match: example.com\n    exclude: [\"div[class\"]\n", 0, "rule 1"},
+		{"unknown input format", "rules:\n  - match: example.com\n    input_format: rtf\n", 0, "rtf"},
+		{"missing host", "rules:\n  - match: example.com\n  - selector: main\n", 0, "rule 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rules.Parse(strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(rulesYAML), 0o644); err != nil {
+		t.Fatalf("failed to write rules file: %v", err)
+	}
+
+	set, err := rules.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := set.Match("https://example.com/"); !ok {
+		t.Error("Load() rules match no source")
+	}
+
+	if _, err := rules.Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() of a missing file expected an error")
+	}
+}